@@ -17,8 +17,10 @@ The flags are:
 		Give the current version of TunaQuest and then exit.
 
 	-w, --world FILE
-		Use the provided TQW resource file for the world. Defaults to the file
-		"world.tqw" in the current working directory.
+		Use the provided TQW resource file for the world. This can be a data
+		file, a manifest file, or a zip or gzipped tar resource pack containing
+		a manifest at its root. Defaults to the file "world.tqw" in the current
+		working directory.
 
 	-d, --direct
 	    Force reading directly from the console as opposed to using GNU readline
@@ -27,11 +29,69 @@ The flags are:
 
 	-c, --command COMMANDS
 		Immediately run the given command(s) at start. Can be multiple commands
-		separated by the ";" character.
+		separated by the ";" character, a period, or "THEN", the same as
+		commands typed on one line.
+
+	-s, --save-dir DIR
+		Write saved games to and read them from the given directory. Defaults to
+		the directory that the world file is in.
+
+	-u, --undo-depth TURNS
+		Allow up to TURNS turns to be taken back with the "UNDO" command.
+		Defaults to 50. Give a negative number to disable "UNDO" and "REDO".
+
+	-r, --seed SEED
+		Seed the random choices made in the game, such as where wandering NPCs
+		go, with the given integer. This overrides any seed given in the world
+		or in a walkthrough run with --test. If no seed is given anywhere, a
+		different one is used every time.
+
+	-t, --test WALKTHROUGH
+		Instead of starting an interactive session, play through the commands
+		in the given walkthrough file and check that the results of each are as
+		the walkthrough expects, then exit. The world named in the walkthrough
+		is used unless one is given with --world. The result of every step is
+		printed, and the exit code is non-zero if any step fails.
+
+	-l, --lint
+		Instead of starting an interactive session, check the world for likely
+		mistakes and print a warning for each one, then exit. This finds rooms
+		that cannot be reached from the start, 'if' conditions that can never be
+		true, flags that are read but never set or set but never read, dialog
+		that refers to lines that do not exist or can never be reached, and
+		on_use actions whose 'with' refers to nothing in the world. The exit
+		code is non-zero if there are any warnings.
+
+	-m, --map FORMAT
+		Instead of starting an interactive session, print a map of the rooms in
+		the world and the exits between them, then exit. FORMAT is "dot" for a
+		Graphviz DOT graph, "mermaid" for a Mermaid flowchart, or "json" for a
+		JSON list of nodes and edges. Each exit is labeled with its aliases and
+		the 'if' it requires, if any.
+
+	--map-routes
+		Include NPCs in the map printed with --map, along with the rooms they
+		start in, the paths of those that patrol, and the rooms that those that
+		wander are allowed in or forbidden from.
+
+	--dialog FORMAT
+		Instead of starting an interactive session, print a flowchart of the
+		dialog tree of every NPC in the world, then exit. FORMAT is the same as
+		for --map. Lines, choices, pauses, and ends are each drawn differently,
+		and choices are labeled with their text as it would be shown at the
+		start of the game.
+
+	--dialog-npc LABEL
+		Only print the dialog tree of the NPC with the given label with
+		--dialog.
 
 Once a session has started, the user input will be parsed for TunaQuest
 commands. For an explanation of the commands, type "HELP" once in a session. To
-exit the interpreter, type "QUIT".
+exit the interpreter, type "QUIT". Several commands can be given on one line
+by separating them with periods, semicolons, or "THEN", and the last command
+can be run again with "AGAIN". The game can be saved at any time with
+"SAVE", optionally followed by a name for the save, and restored later with
+"LOAD".
 */
 package main
 
@@ -41,7 +101,11 @@ import (
 	"strings"
 
 	"github.com/dekarrin/tunaq"
+	"github.com/dekarrin/tunaq/internal/lint"
+	"github.com/dekarrin/tunaq/internal/tqw"
 	"github.com/dekarrin/tunaq/internal/version"
+	"github.com/dekarrin/tunaq/internal/walkthrough"
+	"github.com/dekarrin/tunaq/internal/worldmap"
 	"github.com/spf13/pflag"
 )
 
@@ -57,14 +121,31 @@ const (
 	// ExitInitError indicates an unsuccessful program execution due to an issue
 	// initializing the engine.
 	ExitInitError
+
+	// ExitTestFailure indicates that a walkthrough was run and at least one of
+	// its steps failed.
+	ExitTestFailure
+
+	// ExitLintWarnings indicates that a world was checked with --lint and at
+	// least one problem was found.
+	ExitLintWarnings
 )
 
 var (
 	returnCode   int     = ExitSuccess
 	flagVersion  *bool   = pflag.BoolP("version", "v", false, "Gives the version info")
-	worldFile    *string = pflag.StringP("world", "w", "world.tqw", "The TQW world data or manifest file that contains the definition of the world")
+	worldFile    *string = pflag.StringP("world", "w", "world.tqw", "The TQW world data file, manifest file, or resource pack that contains the definition of the world")
 	forceDirect  *bool   = pflag.BoolP("direct", "d", false, "Force reading directly from stdin instead of going through GNU readline where possible")
 	startCommand *string = pflag.StringP("command", "c", "", "Execute the given player commands immediately at start and leave the interpreter open")
+	saveDir      *string = pflag.StringP("save-dir", "s", "", "The directory to write saved games to and read them from; defaults to the directory the world file is in")
+	seed         *int64  = pflag.Int64P("seed", "r", 0, "Seed the random choices made in the game with the given value instead of the one given by the world")
+	testFile     *string = pflag.StringP("test", "t", "", "Run the given walkthrough file against the world and report the results instead of starting an interactive session")
+	lintWorld    *bool   = pflag.BoolP("lint", "l", false, "Check the world for likely mistakes and report them instead of starting an interactive session")
+	mapFormat    *string = pflag.StringP("map", "m", "", "Print a map of the world in the given format (\"dot\", \"mermaid\", or \"json\") instead of starting an interactive session")
+	mapRoutes    *bool   = pflag.Bool("map-routes", false, "Include NPCs and their movement in the map printed with --map")
+	dialogFormat *string = pflag.String("dialog", "", "Print a flowchart of NPC dialog trees in the given format (\"dot\", \"mermaid\", or \"json\") instead of starting an interactive session")
+	dialogNPC    *string = pflag.String("dialog-npc", "", "Only print the dialog tree of the NPC with the given label with --dialog")
+	undoDepth    *int    = pflag.IntP("undo-depth", "u", tunaq.DefaultUndoDepth, "The number of turns that can be taken back with UNDO; give a negative number to disable it")
 )
 
 func main() {
@@ -85,12 +166,35 @@ func main() {
 		return
 	}
 
-	var startCommands []string
-	if *startCommand != "" {
-		startCommands = strings.Split(*startCommand, ";")
+	if *testFile != "" {
+		returnCode = runWalkthrough(*testFile)
+		return
+	}
+
+	if *lintWorld {
+		returnCode = runLint(*worldFile)
+		return
+	}
+
+	if *mapFormat != "" {
+		returnCode = runMap(*worldFile, *mapFormat)
+		return
+	}
+
+	if *dialogFormat != "" {
+		returnCode = runDialog(*worldFile, *dialogFormat, *dialogNPC)
+		return
+	}
+
+	cfg := tunaq.Config{
+		Seed:        seedOverride(),
+		WorldFile:   *worldFile,
+		SaveDir:     *saveDir,
+		UndoDepth:   *undoDepth,
+		ForceDirect: *forceDirect,
 	}
 
-	gameEng, initErr := tunaq.New(os.Stdin, os.Stdout, *worldFile, *forceDirect)
+	gameEng, initErr := tunaq.New(os.Stdin, os.Stdout, cfg)
 	if initErr != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %s\n", initErr.Error())
 		returnCode = ExitInitError
@@ -98,10 +202,143 @@ func main() {
 	}
 	defer gameEng.Close()
 
-	err := gameEng.RunUntilQuit(startCommands)
+	err := gameEng.RunUntilQuit(*startCommand)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
 		returnCode = ExitGameError
 		return
 	}
 }
+
+// runWalkthrough runs the walkthrough at the given path, prints the results of
+// it to stdout, and returns the code that the program should exit with.
+func runWalkthrough(path string) int {
+	wt, err := walkthrough.Load(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: load walkthrough: %s\n", err.Error())
+		return ExitInitError
+	}
+
+	if s := seedOverride(); s != nil {
+		wt.Seed = s
+	}
+
+	worldPath := *worldFile
+	if wt.World != "" && !pflag.CommandLine.Changed("world") {
+		worldPath = wt.World
+	}
+
+	world, err := tqw.LoadResourceBundle(worldPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		return ExitInitError
+	}
+
+	results, err := walkthrough.Run(wt, world)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		return ExitGameError
+	}
+
+	var failed int
+	for i, res := range results {
+		if res.Passed() {
+			fmt.Printf("PASS %3d: %s\n", i+1, res.Command)
+			continue
+		}
+
+		failed++
+		fmt.Printf("FAIL %3d: %s\n", i+1, res.Command)
+		for _, f := range res.Failures {
+			fmt.Printf("          - %s\n", f)
+		}
+		fmt.Printf("          output:\n")
+		for _, line := range strings.Split(strings.TrimSpace(res.Output), "\n") {
+			fmt.Printf("            | %s\n", line)
+		}
+	}
+
+	fmt.Printf("\n%d steps, %d passed, %d failed\n", len(results), len(results)-failed, failed)
+
+	if failed > 0 {
+		return ExitTestFailure
+	}
+	return ExitSuccess
+}
+
+// runLint checks the world at the given path for problems, prints each one to
+// stdout, and returns the code that the program should exit with.
+func runLint(path string) int {
+	world, err := tqw.LoadResourceBundle(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		return ExitInitError
+	}
+
+	warnings := lint.Check(world)
+	for _, w := range warnings {
+		fmt.Printf("%s\n", w)
+	}
+
+	if len(warnings) > 0 {
+		fmt.Printf("\n%d problem(s) found\n", len(warnings))
+		return ExitLintWarnings
+	}
+	fmt.Printf("No problems found\n")
+	return ExitSuccess
+}
+
+// runMap prints a map of the world at the given path in the given format to
+// stdout and returns the code that the program should exit with.
+func runMap(path string, format string) int {
+	f, ok := worldmap.FormatsByString[strings.ToUpper(format)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: map format must be \"dot\", \"mermaid\", or \"json\", not %q\n", format)
+		return ExitInitError
+	}
+
+	world, err := tqw.LoadResourceBundle(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		return ExitInitError
+	}
+
+	fmt.Print(worldmap.Draw(world, f, worldmap.Options{Routes: *mapRoutes}))
+	return ExitSuccess
+}
+
+// runDialog prints flowcharts of the dialog trees of NPCs in the world at the
+// given path in the given format to stdout and returns the code that the
+// program should exit with. If npcLabel is not empty, only the dialog tree of
+// that NPC is printed.
+func runDialog(path string, format string, npcLabel string) int {
+	f, ok := worldmap.FormatsByString[strings.ToUpper(format)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "ERROR: dialog format must be \"dot\", \"mermaid\", or \"json\", not %q\n", format)
+		return ExitInitError
+	}
+
+	world, err := tqw.LoadResourceBundle(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		return ExitInitError
+	}
+
+	chart, err := worldmap.DrawDialog(world, npcLabel, f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		return ExitInitError
+	}
+
+	fmt.Print(chart)
+	return ExitSuccess
+}
+
+// seedOverride gives the seed given with the --seed flag, or nil if the flag
+// was not given.
+func seedOverride() *int64 {
+	if !pflag.CommandLine.Changed("seed") {
+		return nil
+	}
+	return seed
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -24,17 +25,92 @@ type Engine struct {
 	state   *game.State
 	term    *terminalDevice
 	running bool
+
+	// vocab is the words that commands are parsed with. It comes from the
+	// loaded world.
+	vocab *command.Vocabulary
+
+	// worldFile is the path to the TQW resource file the world is loaded from.
+	worldFile string
+
+	// fingerprint is the fingerprint of the loaded world, used to check that
+	// saved games are for it.
+	fingerprint string
+
+	// saveDir is the directory that saved games are written to and read from.
+	saveDir string
+
+	// history is the turns that can be taken back with UNDO and redone with
+	// REDO.
+	history turnHistory
+
+	// lastCmd is the last command that was run, which AGAIN runs again.
+	lastCmd command.Command
+
+	// seed is the seed to give every new game. If nil, the seed given by the
+	// world is used, if it gives one.
+	seed *int64
 }
 
 const consoleOutputWidth = 80
 
+// DefaultUndoDepth is the maximum number of turns that can be taken back if
+// no other value is set in a Config.
+const DefaultUndoDepth = 50
+
+// Config is a configuration for an Engine. It contains all parameters that can
+// be used to configure how the Engine runs a game.
+type Config struct {
+	// WorldFile is the path to the TQW resource file that contains the
+	// definition of the world. If not set, it defaults to "world.tqw".
+	WorldFile string
+
+	// SaveDir is the directory that saved games are written to and read from.
+	// If not set, it defaults to the directory that WorldFile is in.
+	SaveDir string
+
+	// Seed is the seed for the random source of the game. If nil, the seed
+	// given in the world is used, or one based on the current time if the
+	// world does not give one.
+	Seed *int64
+
+	// UndoDepth is the maximum number of turns that can be taken back with
+	// UNDO. If not set, it defaults to DefaultUndoDepth. If set to a negative
+	// number, UNDO and REDO are disabled.
+	UndoDepth int
+
+	// ForceDirect forces reading directly from the input stream instead of
+	// going through GNU readline routines, even if the engine is attached to
+	// stdin and stdout.
+	ForceDirect bool
+}
+
+// FillDefaults returns a new Config identical to cfg but with unset values set
+// to their defaults.
+func (cfg Config) FillDefaults() Config {
+	newCFG := cfg
+
+	if newCFG.WorldFile == "" {
+		newCFG.WorldFile = "world.tqw"
+	}
+	if newCFG.SaveDir == "" {
+		newCFG.SaveDir = filepath.Dir(newCFG.WorldFile)
+	}
+	if newCFG.UndoDepth == 0 {
+		newCFG.UndoDepth = DefaultUndoDepth
+	}
+
+	return newCFG
+}
+
 // New creates a new engine ready to operate on the given input and output
 // streams. It will immediately open a buffered reader on the input stream and a
-// buffered writer on the output stream.
+// buffered writer on the output stream. Any values not set in cfg are given
+// their defaults.
 //
 // If nil is given for the input stream, a bufio.Reader is opened on stdin. If
 // nil is given for the output stream, a bufio.Writer is opened on stdout.
-func New(inputStream io.Reader, outputStream io.Writer, worldFilePath string, forceDirectInput bool) (*Engine, error) {
+func New(inputStream io.Reader, outputStream io.Writer, cfg Config) (*Engine, error) {
 	if inputStream == nil {
 		inputStream = os.Stdin
 	}
@@ -42,20 +118,18 @@ func New(inputStream io.Reader, outputStream io.Writer, worldFilePath string, fo
 		outputStream = os.Stdout
 	}
 
-	// load world file
-	worldData, err := tqw.LoadResourceBundle(worldFilePath)
-	if err != nil {
-		return nil, err
-	}
+	cfg = cfg.FillDefaults()
+
+	var err error
 
 	// terminal for IO output.
 	term := &terminalDevice{
 		width:       consoleOutputWidth,
-		forceDirect: forceDirectInput,
+		forceDirect: cfg.ForceDirect,
 		out:         bufio.NewWriter(outputStream),
 	}
 
-	term.useReadline = !forceDirectInput && inputStream == os.Stdin && outputStream == os.Stdout
+	term.useReadline = !cfg.ForceDirect && inputStream == os.Stdin && outputStream == os.Stdout
 
 	if term.useReadline {
 		term.in, err = input.NewInteractiveReader()
@@ -68,17 +142,49 @@ func New(inputStream io.Reader, outputStream io.Writer, worldFilePath string, fo
 
 	// create engine
 	eng := &Engine{
-		term:    term,
-		running: false,
+		term:      term,
+		running:   false,
+		worldFile: cfg.WorldFile,
+		saveDir:   cfg.SaveDir,
+		history:   turnHistory{depth: cfg.UndoDepth},
+		seed:      cfg.Seed,
+	}
+
+	if err := eng.startNewGame(); err != nil {
+		return nil, err
+	}
+
+	return eng, nil
+}
+
+// startNewGame loads the world from the world file and starts a new game in
+// it, replacing the current one if there is one.
+func (eng *Engine) startNewGame() error {
+	worldData, err := tqw.LoadResourceBundle(eng.worldFile)
+	if err != nil {
+		return err
 	}
 
 	state, err := game.New(worldData.Rooms, worldData.Start, worldData.Flags, eng.term)
 	if err != nil {
-		return nil, fmt.Errorf("initializing game engine: %w", err)
+		return fmt.Errorf("initializing game engine: %w", err)
+	}
+	state.SetEvents(worldData.Events)
+	if err := state.SetVerbs(worldData.Verbs); err != nil {
+		return fmt.Errorf("initializing game engine: %w", err)
 	}
-	eng.state = state
 
-	return eng, nil
+	if eng.seed != nil {
+		state.SetSeed(*eng.seed)
+	} else if worldData.Seed != nil {
+		state.SetSeed(*worldData.Seed)
+	}
+
+	eng.state = state
+	eng.vocab = worldData.Vocabulary
+	eng.fingerprint = worldData.Fingerprint
+	eng.history.clear()
+	return nil
 }
 
 // Close closes all resources associated with the Engine, including any
@@ -102,8 +208,9 @@ func (eng *Engine) Close() error {
 // RunUntilQuit begins reading commands from the streams and applying them to
 // the game until the QUIT command is received.
 //
-// startCommands, if non nil, is commands to run as soon as it starts.
-func (eng *Engine) RunUntilQuit(startCommands []string) error {
+// startCommands, if not empty, is a line of commands to run as soon as it
+// starts, separated the same way as commands typed on one line.
+func (eng *Engine) RunUntilQuit(startCommands string) error {
 	introMsg := "Welcome to TunaQuest Engine\n"
 	if eng.term.forceDirect {
 		introMsg += "(direct input mode)\n"
@@ -125,40 +232,27 @@ func (eng *Engine) RunUntilQuit(startCommands []string) error {
 		eng.running = false
 	}()
 
-	startCmdIdx := 0
-
 	for eng.running {
-		var cmd command.Command
+		var cmds []command.Command
 		var err error
 
-		if startCmdIdx+1 <= len(startCommands) {
-			cmd, err = command.Parse(startCommands[startCmdIdx])
-			if err != nil {
-				consoleMessage := tqerrors.GameMessage(err)
-				consoleMessage = rosed.Edit(consoleMessage).Wrap(consoleOutputWidth).String()
-				if _, err := eng.term.out.WriteString("\n" + consoleMessage + "\n\n"); err != nil {
-					return fmt.Errorf("could not write output: %w", err)
-				}
-				if err := eng.term.out.Flush(); err != nil {
-					return fmt.Errorf("could not flush output: %w", err)
-				}
-			}
-			startCmdIdx++
+		if startCommands != "" {
+			cmds, err = eng.vocab.ParseAll(startCommands)
+			startCommands = ""
 		} else {
-			cmd, err = command.Get(eng.term.in, eng.term.out)
+			cmds, err = command.Get(eng.term.in, eng.term.out, eng.vocab)
 			if err != nil {
 				return fmt.Errorf("get user command: %w", err)
 			}
 		}
 
-		// special check: actual game will not use the QUIT command, only a
-		// runner can do that. so check if that's what we got
-		if cmd.Verb == "QUIT" {
-			eng.running = false
-			break
+		if err == nil {
+			cmds, err = command.ResolveAgain(cmds, eng.lastCmd)
+		}
+		if err == nil {
+			err = eng.executeCommands(cmds)
 		}
 
-		err = eng.state.Advance(cmd)
 		if err != nil {
 			consoleMessage := tqerrors.GameMessage(err)
 			consoleMessage = rosed.Edit(consoleMessage).Wrap(consoleOutputWidth).String()
@@ -181,6 +275,36 @@ func (eng *Engine) RunUntilQuit(startCommands []string) error {
 	return nil
 }
 
+// executeCommands executes each of cmds in order, stopping at the first one
+// that fails or at QUIT. The error of the one that failed is returned.
+func (eng *Engine) executeCommands(cmds []command.Command) error {
+	for _, cmd := range cmds {
+		eng.lastCmd = cmd
+
+		// special check: actual game will not use the QUIT command or any of
+		// the commands for managing saved games or the turn history, only a
+		// runner can do that. so check if that's what we got
+		var err error
+		switch cmd.Verb {
+		case "QUIT":
+			eng.running = false
+			return nil
+		case "SAVE", "LOAD", "SAVES", "RESTART":
+			err = eng.executeSaveCommand(cmd)
+		case "UNDO", "REDO":
+			err = eng.executeHistoryCommand(cmd)
+		default:
+			err = eng.advanceRecorded(cmd)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 type terminalDevice struct {
 	width       int
 	out         *bufio.Writer
@@ -2,6 +2,8 @@
 // commands from input sources.
 package command
 
+import "github.com/dekarrin/tunaq/internal/tqerrors"
+
 // Command is a valid command received from a game input source.
 type Command struct {
 
@@ -21,4 +23,96 @@ type Command struct {
 	// "TALK TO MAN", the recipient would be "CUP" and "MAN" respectively. For
 	// MOVE commands, this can also be a direction.
 	Recipient string
+
+	// Instruments is every instrument when more than one is given, for
+	// instance in "USE KEY WITH LOCK AND CHAIN", it would be "LOCK" and
+	// "CHAIN". Instrument is always the first of them. It is nil if only one
+	// instrument was given.
+	Instruments []string
+
+	// Recipients is every recipient when more than one is given, for instance
+	// in "GET CUP AND PLATE", it would be "CUP" and "PLATE". Recipient is
+	// always the first of them. It is nil if only one recipient was given.
+	Recipients []string
+
+	// All is whether the recipient was given as everything that it could be,
+	// for instance in "GET ALL". When it is set, Recipient is empty.
+	All bool
+
+	// Except is the recipients that were left out of All, for instance in "GET
+	// ALL EXCEPT CUP", it would be "CUP". It is only set along with All.
+	Except []string
+
+	// VerbAssumed is whether Verb was not typed but was assumed from a
+	// misspelling of it, for instance "TAKE" from "TKAE CUP".
+	VerbAssumed bool
+}
+
+// ResolveAgain returns cmds with every AGAIN command replaced by the command
+// before it. last is the command before the first one in cmds, which has an
+// empty Verb if there wasn't one. If an AGAIN has no command before it, a
+// non-nil error is returned.
+func ResolveAgain(cmds []Command, last Command) ([]Command, error) {
+	resolved := make([]Command, len(cmds))
+	for i, cmd := range cmds {
+		if cmd.Verb == "AGAIN" {
+			if last.Verb == "" {
+				return nil, tqerrors.Interpreterf("There's nothing to do again")
+			}
+			cmd = last
+		}
+		resolved[i] = cmd
+		last = cmd
+	}
+	return resolved, nil
+}
+
+// ArgUse is whether a part of a command must, may, or must not be given.
+type ArgUse int
+
+const (
+	// ArgNone is a part of a command that must not be given.
+	ArgNone ArgUse = iota
+
+	// ArgOptional is a part of a command that may be given.
+	ArgOptional
+
+	// ArgRequired is a part of a command that must be given.
+	ArgRequired
+)
+
+// ArgUsesByString maps the names of ArgUses as they are written in world files
+// to the ArgUse.
+var ArgUsesByString = map[string]ArgUse{
+	"NONE":     ArgNone,
+	"OPTIONAL": ArgOptional,
+	"REQUIRED": ArgRequired,
+}
+
+// VerbDef is the definition of a verb that is not built in, such as one that a
+// world adds. Commands that use it are parsed into a Command with the verb's
+// Name as the Verb and with a Recipient and Instrument, as in "PUSH BOX WITH
+// STICK".
+type VerbDef struct {
+	// Name is the canonical name of the verb. It is upper case.
+	Name string
+
+	// Aliases is other things that can be typed instead of Name to use the
+	// verb, such as "SHOVE" for "PUSH". They are upper case and can be more
+	// than one word long, but must be the first words in a command.
+	Aliases []string
+
+	// Recipient is whether the thing receiving the action is given right after
+	// the verb, as "BOX" is in "PUSH BOX".
+	Recipient ArgUse
+
+	// Preposition is the word that comes before the instrument, as "WITH" does
+	// in "PUSH BOX WITH STICK". It must be one of the default reserved words,
+	// and not a word that a Vocabulary has stand in for one. If it is empty,
+	// the verb does not take an instrument.
+	Preposition string
+
+	// Instrument is whether the thing that the action is done with is given
+	// after Preposition. It is not used if Preposition is empty.
+	Instrument ArgUse
 }
@@ -31,60 +31,49 @@ type Reader interface {
 	Close() error
 }
 
-// Get obtains a single command from input by reading from the provided Reader.
-// It reads a line of input and attempts to parse it as a valid command,
-// returning that command if it is successful. If it is not, error output is
-// printed to the ostream and the input is read until a valid command is
-// encountered.
+// Get obtains the commands on a single line of input by reading from the
+// provided Reader. It reads a line of input and attempts to parse every command
+// in it with the words in vocab, returning the commands if it is successful. If
+// it is not, error output is printed to the ostream and the input is read until
+// a line with only valid commands is encountered. See Vocabulary.ParseAll for
+// how commands on the same line are separated.
 //
-// Note that this function does not check if the command is executable, only
-// that a Command can be parsed from the user input.
+// Note that this function does not check if the commands are executable, only
+// that Commands can be parsed from the user input.
 //
 // TODO: abstract this and the entire command parsing structure to new package,
 // cmd.
-func Get(cmdStream Reader, ostream *bufio.Writer) (Command, error) {
-	var cmd Command
-	gotValidCommand := false
+func Get(cmdStream Reader, ostream *bufio.Writer, vocab *Vocabulary) ([]Command, error) {
+	var cmds []Command
 
 	if _, err := ostream.WriteString("Enter command\n"); err != nil {
-		return cmd, fmt.Errorf("could not write output: %w", err)
+		return nil, fmt.Errorf("could not write output: %w", err)
 	}
 	if err := ostream.Flush(); err != nil {
-		return cmd, fmt.Errorf("could not flush output: %w", err)
+		return nil, fmt.Errorf("could not flush output: %w", err)
 	}
 
-	for !gotValidCommand {
+	for len(cmds) < 1 {
 		// IO to get input:
 		input, err := cmdStream.ReadCommand()
 		if err != nil {
-			return cmd, fmt.Errorf("could not get input: %w", err)
+			return nil, fmt.Errorf("could not get input: %w", err)
 		}
 
 		// now attempt to parse the input
-		cmd, err = Parse(input)
+		cmds, err = vocab.ParseAll(input)
 		if err != nil {
 			consoleMessage := tqerrors.GameMessage(err)
 			errMsg := fmt.Sprintf("%v\nTry HELP for valid commands\n", consoleMessage)
 			// IO to report error and prompt user to try again
 			if _, err := ostream.WriteString(errMsg); err != nil {
-				return cmd, fmt.Errorf("could not write output: %w", err)
+				return nil, fmt.Errorf("could not write output: %w", err)
 			}
 			if err := ostream.Flush(); err != nil {
-				return cmd, fmt.Errorf("could not flush output: %w", err)
+				return nil, fmt.Errorf("could not flush output: %w", err)
 			}
-		} else if cmd.Verb != "" {
-			gotValidCommand = true
 		}
 	}
 
-	return cmd, nil
-}
-
-// Parse parses a command from the given text. If it cannot, a non-nil
-// error is returned. ostream is a stream to write error messages to.
-//
-// If an empty string or a string composed only of whitespace is passed in, nil
-// error is returned and a zero value for Command will be returned.
-func Parse(input string) (Command, error) {
-	return parseCommand(input)
+	return cmds, nil
 }
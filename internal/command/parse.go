@@ -1,84 +1,20 @@
 package command
 
 import (
+	"sort"
 	"strings"
+	"unicode"
 
 	"github.com/dekarrin/tunaq/internal/tqerrors"
+	"github.com/dekarrin/tunaq/internal/util"
 )
 
-var (
-	// ReservedWords is the list of all token sequences that a symbol
-	// cannot have anywhere or it will cause issues in parsing.
-	ReservedWords = []string{
-		"TO",
-		"THROUGH",
-		"INTO",
-		"FROM",
-		"ON",
-		"IN",
-		"WITH",
-		"AT",
-	}
-)
-
-var (
-	// VerbAliases maps shorthand verbs (which must be the first words in a
-	// command) to their canonical forms. They are all uppercase.
-	VerbAliases map[string]string = map[string]string{
-		"NORTH":    "GO NORTH",
-		"SOUTH":    "GO SOUTH",
-		"EAST":     "GO EAST",
-		"WEST":     "GO WEST",
-		"UP":       "GO UP",
-		"DOWN":     "GO DOWN",
-		"MOVE":     "GO",
-		"BYE":      "QUIT",
-		"SPEAK":    "TALK",
-		"COMBINE":  "USE",
-		"PUT":      "DROP",
-		"PUT DOWN": "DROP",
-		"GET":      "TAKE",
-		"PICK":     "TAKE",
-		"PICK UP":  "TAKE",
-		"DESCRIBE": "LOOK",
-		"DESC":     "LOOK",
-		"?":        "HELP",
-		"/?":       "HELP",
-		"/H":       "HELP",
-		"-H":       "HELP",
-		"H":        "HELP",
-		"INVEN":    "INVENTORY",
-		"I":        "INVENTORY",
-	}
-)
-
-// FindFirstReserved takes the input, tokenizes it, and then checks whether it
-// contains one of the reserved sequences. It can be used to check whether a
-// symbol definition should be rejected by callers and marked as valid/invalid.
-//
-// Returns the first reserved word encountered. If none are encountered it will
-// return the empty string.
-func FindFirstReserved(s string) string {
-	normalizedCase := strings.ToUpper(s)
-	tokens := strings.Fields(normalizedCase)
-
-	for i := range ReservedWords {
-		for j := 0; j < len(tokens); j++ {
-			if tokens[j] == ReservedWords[i] {
-				return ReservedWords[i]
-			}
-		}
-	}
-
-	return ""
-}
-
-// ParseCommand parses a command from the given text. If it cannot, a non-nil
+// parseCommand parses a command from the given text. If it cannot, a non-nil
 // error is returned.
 //
 // If an empty string or a string composed only of whitespace is passed in, nil
 // error is returned and a zero value for Command will be returned.
-func parseCommand(toParse string) (Command, error) {
+func (v *Vocabulary) parseCommand(toParse string) (Command, error) {
 	var parsedCmd Command
 
 	// make entire input upper case to make matching easy
@@ -87,8 +23,12 @@ func parseCommand(toParse string) (Command, error) {
 	// now tokenize our string, collapsing all whitespace
 	originalTokens := strings.Fields(normalizedCase)
 
-	// expand verb aliases up to 2 words long
-	tokens := ExpandAliases(originalTokens, 2)
+	// expand verb aliases, then swap any words that stand in for reserved
+	// words with the reserved words themselves
+	tokens := v.ExpandAliases(originalTokens, MaxAliasWords)
+	for i := 1; i < len(tokens); i++ {
+		tokens[i] = v.Canonical(tokens[i])
+	}
 
 	// some simple sanity checking, make sure we at least have a command
 	if len(tokens) < 1 {
@@ -134,7 +74,7 @@ func parseCommand(toParse string) (Command, error) {
 
 		// get from clause
 		fromIdx := len(tokens)
-		for i := range tokens[1:] {
+		for i := 1; i < len(tokens); i++ {
 			if tokens[i] == "FROM" {
 				if i+1 >= len(tokens) {
 					return parsedCmd, tqerrors.Interpreterf("I don't know where you want to take it from")
@@ -144,8 +84,10 @@ func parseCommand(toParse string) (Command, error) {
 			}
 		}
 
-		// and the object is the rest of the tokens
-		parsedCmd.Recipient = strings.Join(tokens[1:fromIdx], " ")
+		// and the objects are the rest of the tokens
+		if err := parseRecipients(&parsedCmd, tokens[1:fromIdx]); err != nil {
+			return parsedCmd, err
+		}
 	case "DROP":
 		// what are we dropping
 		if len(tokens) < 2 {
@@ -153,7 +95,7 @@ func parseCommand(toParse string) (Command, error) {
 		}
 
 		onIdx := len(tokens)
-		for i := range tokens[1:] {
+		for i := 1; i < len(tokens); i++ {
 			if tokens[i] == "ON" || tokens[i] == "IN" {
 				if i+1 >= len(tokens) {
 					return parsedCmd, tqerrors.Interpreterf("I don't know where you want to put it")
@@ -163,8 +105,10 @@ func parseCommand(toParse string) (Command, error) {
 			}
 		}
 
-		// and the object is the rest of the tokens
-		parsedCmd.Recipient = strings.Join(tokens[1:onIdx], " ")
+		// and the objects are the rest of the tokens
+		if err := parseRecipients(&parsedCmd, tokens[1:onIdx]); err != nil {
+			return parsedCmd, err
+		}
 	case "USE":
 		// what are we using
 		if len(tokens) < 2 {
@@ -172,17 +116,33 @@ func parseCommand(toParse string) (Command, error) {
 		}
 
 		withIdx := len(tokens)
-		for i := range tokens[1:] {
+		for i := 1; i < len(tokens); i++ {
 			if tokens[i] == "WITH" {
 				if i+1 >= len(tokens) {
 					return parsedCmd, tqerrors.Interpreterf("I don't know what you want to use it with")
 				}
 				withIdx = i
-				parsedCmd.Instrument = strings.Join(tokens[i+1:], " ")
+
+				instruments := splitObjects(tokens[i+1:])
+				if len(instruments) < 1 {
+					return parsedCmd, tqerrors.Interpreterf("I don't know what you want to use it with")
+				}
+				parsedCmd.Instrument = instruments[0]
+				if len(instruments) > 1 {
+					parsedCmd.Instruments = instruments
+				}
+				break
 			}
 		}
 
-		parsedCmd.Recipient = strings.Join(tokens[1:withIdx], " ")
+		recipients := splitObjects(tokens[1:withIdx])
+		if len(recipients) < 1 {
+			return parsedCmd, tqerrors.Interpreterf("I don't know what you want to use")
+		}
+		parsedCmd.Recipient = recipients[0]
+		if len(recipients) > 1 {
+			parsedCmd.Recipients = recipients
+		}
 	case "TALK":
 		// talk p much always takes a 'to', make shore we ignore that
 		if len(tokens) > 1 && (tokens[1] == "TO" || tokens[1] == "WITH") {
@@ -195,6 +155,61 @@ func parseCommand(toParse string) (Command, error) {
 		}
 
 		parsedCmd.Recipient = strings.Join(tokens[1:], " ")
+	case "GIVE", "SHOW":
+		// what are we giving or showing, and who to
+		if len(tokens) < 2 {
+			return parsedCmd, tqerrors.Interpreterf("I don't know what you want to %s", strings.ToLower(parsedCmd.Verb))
+		}
+
+		toIdx := len(tokens)
+		for i := 1; i < len(tokens); i++ {
+			if tokens[i] == "TO" {
+				if i+1 >= len(tokens) {
+					return parsedCmd, tqerrors.Interpreterf("I don't know who you want to %s it to", strings.ToLower(parsedCmd.Verb))
+				}
+				toIdx = i
+				parsedCmd.Instrument = strings.Join(tokens[i+1:], " ")
+				break
+			}
+		}
+
+		if toIdx == len(tokens) {
+			return parsedCmd, tqerrors.Interpreterf("I don't know who you want to %s it to", strings.ToLower(parsedCmd.Verb))
+		}
+		if toIdx < 2 {
+			return parsedCmd, tqerrors.Interpreterf("I don't know what you want to %s", strings.ToLower(parsedCmd.Verb))
+		}
+		parsedCmd.Recipient = strings.Join(tokens[1:toIdx], " ")
+	case "OPEN", "CLOSE":
+		// what are we opening or closing
+		if len(tokens) < 2 {
+			return parsedCmd, tqerrors.Interpreterf("I don't know what you want to %s", strings.ToLower(parsedCmd.Verb))
+		}
+
+		parsedCmd.Recipient = strings.Join(tokens[1:], " ")
+	case "LOCK", "UNLOCK":
+		// what are we locking or unlocking
+		if len(tokens) < 2 {
+			return parsedCmd, tqerrors.Interpreterf("I don't know what you want to %s", strings.ToLower(parsedCmd.Verb))
+		}
+
+		// the key is optional and comes after a 'with'
+		withIdx := len(tokens)
+		for i := 1; i < len(tokens); i++ {
+			if tokens[i] == "WITH" {
+				if i+1 >= len(tokens) {
+					return parsedCmd, tqerrors.Interpreterf("I don't know what you want to %s it with", strings.ToLower(parsedCmd.Verb))
+				}
+				withIdx = i
+				parsedCmd.Instrument = strings.Join(tokens[i+1:], " ")
+				break
+			}
+		}
+
+		if withIdx < 2 {
+			return parsedCmd, tqerrors.Interpreterf("I don't know what you want to %s", strings.ToLower(parsedCmd.Verb))
+		}
+		parsedCmd.Recipient = strings.Join(tokens[1:withIdx], " ")
 	case "LOOK":
 		// check for 'at' and remove it
 		if len(tokens) > 1 && (tokens[1] == "AT" || tokens[1] == "IN") {
@@ -255,7 +270,65 @@ func parseCommand(toParse string) (Command, error) {
 			errMsg := "You can't %s *something*; type %s by itself to quit"
 			return parsedCmd, tqerrors.Interpreterf(errMsg, originalTokens[0], originalTokens[0])
 		}
+	case "SAVE", "LOAD":
+		// save and load take an optional slot name, which must be one word
+		// because it is used as a file name.
+		if len(tokens) > 2 {
+			return parsedCmd, tqerrors.Interpreterf("Save names can't have spaces in them")
+		}
+		if len(tokens) > 1 {
+			parsedCmd.Recipient = tokens[1]
+		}
+	case "SAVES":
+		if len(tokens) > 1 {
+			errMsg := "You can't %s *something*; type %s by itself to list saved games"
+			return parsedCmd, tqerrors.Interpreterf(errMsg, originalTokens[0], originalTokens[0])
+		}
+	case "RESTART":
+		if len(tokens) > 1 {
+			errMsg := "You can't %s *something*; type %s by itself to start over"
+			return parsedCmd, tqerrors.Interpreterf(errMsg, originalTokens[0], originalTokens[0])
+		}
+	case "WAIT":
+		if len(tokens) > 1 {
+			errMsg := "You can't %s *something*; type %s by itself to let time pass"
+			return parsedCmd, tqerrors.Interpreterf(errMsg, originalTokens[0], originalTokens[0])
+		}
+	case "UNDO":
+		if len(tokens) > 1 {
+			errMsg := "You can't %s *something*; type %s by itself to take back the last turn"
+			return parsedCmd, tqerrors.Interpreterf(errMsg, originalTokens[0], originalTokens[0])
+		}
+	case "REDO":
+		if len(tokens) > 1 {
+			errMsg := "You can't %s *something*; type %s by itself to redo the last turn taken back"
+			return parsedCmd, tqerrors.Interpreterf(errMsg, originalTokens[0], originalTokens[0])
+		}
+	case "AGAIN":
+		if len(tokens) > 1 {
+			errMsg := "You can't %s *something*; type %s by itself to do the last command again"
+			return parsedCmd, tqerrors.Interpreterf(errMsg, originalTokens[0], originalTokens[0])
+		}
 	default:
+		if def, ok := v.customVerbs[parsedCmd.Verb]; ok {
+			return parseCustomVerb(def, tokens)
+		}
+
+		suggestions := v.closeVerbs(parsedCmd.Verb)
+		if len(suggestions) == 1 && v.canAssumeVerb(parsedCmd.Verb, suggestions[0]) {
+			// assume it was a typo for the only verb it is close to
+			corrected := append([]string{suggestions[0]}, tokens[1:]...)
+			assumedCmd, err := v.parseCommand(strings.Join(corrected, " "))
+			assumedCmd.VerbAssumed = true
+			return assumedCmd, err
+		}
+		if len(suggestions) > 0 {
+			for i := range suggestions {
+				suggestions[i] = strings.ToLower(suggestions[i])
+			}
+			errMsg := "I don't know what you mean by %q. Did you mean %s?"
+			return parsedCmd, tqerrors.Interpreterf(errMsg, originalTokens[0], util.JoinOr(suggestions))
+		}
 		return parsedCmd, tqerrors.Interpreterf("I don't know what you mean by %q", originalTokens[0])
 	}
 
@@ -271,45 +344,231 @@ func parseCommand(toParse string) (Command, error) {
 // QUIT the game
 // LOOK at the current scene or direction
 
-// ExpandAliases takes a slice of tokens of user input and runs alias expansion
-// on it. It expects all strings in the given slice to be upper case; failure to
-// ensure this may cause the expansion to not work properly. The returned slice
-// contains the same tokens but with aliases expanded.
-//
-// The unexpanded tokens slice is not modified during this operation.
-//
-// Aliases up to aliasLimit words long are supported. If it is less than 0, it
-// is assumed to be 0. Passing 0 means the given tokens will be returned
-// unchanged.
-//
-// Aliases will not be multi-expanded; that is, expansion is not applied to the
-// results of an expansion; if the caller needs it, they will need to call
-// ExpandAliases again on its output.
-func ExpandAliases(tokens []string, aliasLimit int) []string {
-	expandedTokens := append([]string{}, tokens...)
-	if aliasLimit < 1 {
-		return expandedTokens
+// parseCustomVerb parses the tokens of a command that uses the verb defined by
+// def. The verb must already have been expanded from any alias.
+func parseCustomVerb(def VerbDef, tokens []string) (Command, error) {
+	cmd := Command{Verb: def.Name}
+	verb := strings.ToLower(def.Name)
+
+	args := tokens[1:]
+	var instrument []string
+	hasInstrument := false
+	if def.Preposition != "" {
+		for i := range args {
+			if args[i] == def.Preposition {
+				instrument = args[i+1:]
+				args = args[:i]
+				hasInstrument = true
+				break
+			}
+		}
+	}
+
+	if len(args) > 0 {
+		if def.Recipient == ArgNone {
+			return Command{}, tqerrors.Interpreterf("You can't %s *something*", verb)
+		}
+		cmd.Recipient = strings.Join(args, " ")
+	} else if def.Recipient == ArgRequired {
+		return Command{}, tqerrors.Interpreterf("I don't know what you want to %s", verb)
+	}
+
+	prep := strings.ToLower(def.Preposition)
+	if hasInstrument {
+		if def.Instrument == ArgNone {
+			return Command{}, tqerrors.Interpreterf("You can't %s %s *something*", verb, prep)
+		}
+		if len(instrument) < 1 {
+			return Command{}, tqerrors.Interpreterf("I don't know what you want to %s it %s", verb, prep)
+		}
+		cmd.Instrument = strings.Join(instrument, " ")
+	} else if def.Preposition != "" && def.Instrument == ArgRequired {
+		return Command{}, tqerrors.Interpreterf("I don't know what you want to %s it %s", verb, prep)
+	}
+
+	return cmd, nil
+}
+
+// closeVerbs returns the verbs and single-word verb aliases that word is close
+// to but does not exactly match, with any that are aliases for the same verb
+// as another one given only once. They are in alphabetical order.
+func (v *Vocabulary) closeVerbs(word string) []string {
+	candidates := append([]string{}, verbs...)
+	candidates = append(candidates, util.OrderedKeys(v.customVerbs)...)
+	for _, alias := range util.OrderedKeys(v.aliases) {
+		if !strings.Contains(alias, " ") {
+			candidates = append(candidates, alias)
+		}
+	}
+	for _, alias := range util.OrderedKeys(v.customVerbAliases) {
+		if !strings.Contains(alias, " ") {
+			candidates = append(candidates, alias)
+		}
+	}
+
+	var near []string
+	meanings := map[string]bool{}
+	for _, c := range candidates {
+		if !util.IsCloseMatch(word, c) {
+			continue
+		}
+		meaning := c
+		if expansion, ok := v.aliases[c]; ok {
+			meaning = expansion
+		} else if name, ok := v.customVerbAliases[c]; ok {
+			meaning = name
+		}
+		if meanings[meaning] {
+			continue
+		}
+		meanings[meaning] = true
+		near = append(near, c)
+	}
+
+	sort.Strings(near)
+	return near
+}
+
+// canAssumeVerb returns whether typed, which is not a verb that v knows, can
+// be taken to mean verb without asking. That is only the case when typed is
+// long enough and only one letter off from verb, so that something else is
+// unlikely to have been meant, and verb is not an alias, as those are often
+// short words that are close to many others, such as "EAST" and "GET". Meta
+// verbs are never assumed, as doing one by mistake can lose progress.
+func (v *Vocabulary) canAssumeVerb(typed, verb string) bool {
+	if len([]rune(typed)) < 4 || util.EditDistance(typed, verb) != 1 {
+		return false
+	}
+	if util.InSlice(verb, metaVerbs) {
+		return false
+	}
+	if _, ok := v.customVerbs[verb]; ok {
+		return true
 	}
+	return util.InSlice(verb, verbs)
+}
 
-	// only modify verb up to minimum of limit and number of tokens
-	if aliasLimit > len(tokens) {
-		aliasLimit = len(tokens)
+// splitCommands splits input into the text of each command in it, as
+// described in ParseAll. The separators are not included in the returned text.
+func (v *Vocabulary) splitCommands(input string) []string {
+	var texts []string
+	var cur []string
+	endCommand := func() {
+		if len(cur) > 0 {
+			texts = append(texts, strings.Join(cur, " "))
+		}
+		cur = nil
 	}
 
-	for curLimit := 1; curLimit <= aliasLimit; curLimit++ {
-		checkStr := strings.Join(tokens[:curLimit], " ")
-		expansion, ok := VerbAliases[checkStr]
-		if ok {
-			replacementTokens := strings.Fields(expansion)
+	rest := input
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+		if len(cur) == 0 && v.takesRestOfLine(rest) {
+			cur = strings.Fields(rest)
+			break
+		}
+		if rest[0] == ';' {
+			endCommand()
+			rest = rest[1:]
+			continue
+		}
+
+		wordEnd := strings.IndexFunc(rest, func(r rune) bool {
+			return r == ';' || unicode.IsSpace(r)
+		})
+		if wordEnd < 0 {
+			wordEnd = len(rest)
+		}
+		word := rest[:wordEnd]
+		rest = rest[wordEnd:]
 
-			// luckily, we know we are operating from start of tokens passed in so we can just trash
-			// all those in the checkStr and replace with the replacementTokens slice
-			expandedTokens = append(replacementTokens, tokens[curLimit:]...)
+		if v.Canonical(strings.ToUpper(word)) == "THEN" {
+			endCommand()
+			continue
+		}
 
-			// we gaurantee only one single substitution, so we can immediately exit
-			return expandedTokens
+		trimmed := strings.TrimRight(word, ".")
+		if trimmed != "" {
+			cur = append(cur, trimmed)
+		}
+		if trimmed != word {
+			endCommand()
 		}
 	}
+	endCommand()
 
-	return expandedTokens
+	return texts
+}
+
+// takesRestOfLine returns whether the command that text starts with is one
+// whose argument is everything after it, which is not split into more
+// commands.
+func (v *Vocabulary) takesRestOfLine(text string) bool {
+	tokens := v.ExpandAliases(strings.Fields(strings.ToUpper(text)), MaxAliasWords)
+	if len(tokens) < 2 || tokens[0] != "DEBUG" {
+		return false
+	}
+	return tokens[1] == "EXEC" || tokens[1] == "EXPAND"
+}
+
+// parseRecipients sets the recipients of cmd from tokens, which give either
+// one or more objects, as in "CUP AND PLATE", or ALL, which may be followed by
+// EXCEPT and objects to leave out, as in "ALL EXCEPT CUP". If tokens do not
+// give any, a non-nil error is returned.
+func parseRecipients(cmd *Command, tokens []string) error {
+	verb := strings.ToLower(cmd.Verb)
+
+	if len(tokens) > 0 && tokens[0] == "ALL" {
+		cmd.All = true
+		if len(tokens) < 2 {
+			return nil
+		}
+		if tokens[1] != "EXCEPT" {
+			return tqerrors.Interpreterf("I don't know what you mean by %q", strings.Join(tokens, " "))
+		}
+		cmd.Except = splitObjects(tokens[2:])
+		if len(cmd.Except) < 1 {
+			return tqerrors.Interpreterf("I don't know what you don't want to %s", verb)
+		}
+		return nil
+	}
+
+	objects := splitObjects(tokens)
+	if len(objects) < 1 {
+		return tqerrors.Interpreterf("I don't know what you want to %s", verb)
+	}
+	cmd.Recipient = objects[0]
+	if len(objects) > 1 {
+		cmd.Recipients = objects
+	}
+	return nil
+}
+
+// splitObjects splits tokens that give one or more objects, separated by AND
+// or commas as in "CUP, PLATE, AND FORK", into each object. If tokens are
+// empty or start or end with a separator, nil is returned.
+func splitObjects(tokens []string) []string {
+	// commas are treated as ANDs, and an AND right after a comma is the same
+	// one
+	tokens = strings.Fields(strings.ReplaceAll(strings.Join(tokens, " "), ",", " AND "))
+	if len(tokens) < 1 || tokens[0] == "AND" || tokens[len(tokens)-1] == "AND" {
+		return nil
+	}
+
+	var objects []string
+	var cur []string
+	for _, tok := range tokens {
+		if tok != "AND" {
+			cur = append(cur, tok)
+			continue
+		}
+		if len(cur) > 0 {
+			objects = append(objects, strings.Join(cur, " "))
+			cur = nil
+		}
+	}
+	return append(objects, strings.Join(cur, " "))
 }
@@ -0,0 +1,426 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseCommand_objects(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		expect    Command
+		expectErr string
+	}{
+		{
+			name:   "one object",
+			input:  "take red key",
+			expect: Command{Verb: "TAKE", Recipient: "RED KEY"},
+		},
+		{
+			name:   "objects joined with and",
+			input:  "get spoon and fork",
+			expect: Command{Verb: "TAKE", Recipient: "SPOON", Recipients: []string{"SPOON", "FORK"}},
+		},
+		{
+			name:   "objects joined with commas",
+			input:  "take spoon, red key, and fork from drawer",
+			expect: Command{Verb: "TAKE", Recipient: "SPOON", Recipients: []string{"SPOON", "RED KEY", "FORK"}, Instrument: "DRAWER"},
+		},
+		{
+			name:   "all",
+			input:  "take all",
+			expect: Command{Verb: "TAKE", All: true},
+		},
+		{
+			name:   "all except",
+			input:  "drop all except wand and hat in bag",
+			expect: Command{Verb: "DROP", All: true, Except: []string{"WAND", "HAT"}, Instrument: "BAG"},
+		},
+		{
+			name:   "several instruments",
+			input:  "use key with lock and chain",
+			expect: Command{Verb: "USE", Recipient: "KEY", Instrument: "LOCK", Instruments: []string{"LOCK", "CHAIN"}},
+		},
+		{
+			name:      "dangling and",
+			input:     "take spoon and",
+			expectErr: "I don't know what you want to take",
+		},
+		{
+			name:      "all followed by something else",
+			input:     "drop all spoons",
+			expectErr: "I don't know what you mean by \"ALL SPOONS\"",
+		},
+		{
+			name:      "unknown verb",
+			input:     "dance",
+			expectErr: "I don't know what you mean by \"DANCE\"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := NewVocabulary().parseCommand(tc.input)
+			if tc.expectErr != "" {
+				assert.Equal(tc.expectErr, tqerrors.GameMessage(err))
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_parseCommand_misspelledVerbs(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		expect    Command
+		expectErr string
+	}{
+		{
+			name:   "one letter off",
+			input:  "tkae spoon",
+			expect: Command{Verb: "TAKE", Recipient: "SPOON", VerbAssumed: true},
+		},
+		{
+			name:      "more than one letter off",
+			input:     "ivnentroy",
+			expectErr: "I don't know what you mean by \"IVNENTROY\". Did you mean inventory?",
+		},
+		{
+			name:      "too short to assume",
+			input:     "set lamp on table",
+			expectErr: "I don't know what you mean by \"SET\". Did you mean get?",
+		},
+		{
+			name:      "close to several",
+			input:     "tak spoon",
+			expectErr: "I don't know what you mean by \"TAK\". Did you mean take or talk?",
+		},
+		{
+			name:      "close to a direction",
+			input:     "eat apple",
+			expectErr: "I don't know what you mean by \"EAT\". Did you mean east?",
+		},
+		{
+			name:      "close to an alias",
+			input:     "kick ball",
+			expectErr: "I don't know what you mean by \"KICK\". Did you mean pick?",
+		},
+		{
+			name:      "one letter off from an alias",
+			input:     "nroth",
+			expectErr: "I don't know what you mean by \"NROTH\". Did you mean north?",
+		},
+		{
+			name:      "meta verb",
+			input:     "loaf",
+			expectErr: "I don't know what you mean by \"LOAF\". Did you mean load?",
+		},
+		{
+			name:      "meta verb with an argument",
+			input:     "loaf slot_1",
+			expectErr: "I don't know what you mean by \"LOAF\". Did you mean load?",
+		},
+		{
+			name:      "quit",
+			input:     "qui",
+			expectErr: "I don't know what you mean by \"QUI\". Did you mean quit?",
+		},
+		{
+			name:      "save",
+			input:     "svae",
+			expectErr: "I don't know what you mean by \"SVAE\". Did you mean save?",
+		},
+		{
+			name:      "restart",
+			input:     "restrat",
+			expectErr: "I don't know what you mean by \"RESTRAT\". Did you mean restart?",
+		},
+		{
+			name:      "undo",
+			input:     "unod",
+			expectErr: "I don't know what you mean by \"UNOD\". Did you mean undo?",
+		},
+		{
+			name:      "redo",
+			input:     "rdeo",
+			expectErr: "I don't know what you mean by \"RDEO\". Did you mean redo?",
+		},
+		{
+			name:      "again",
+			input:     "agian",
+			expectErr: "I don't know what you mean by \"AGIAN\". Did you mean again?",
+		},
+		{
+			name:      "alias for a meta verb",
+			input:     "byee",
+			expectErr: "I don't know what you mean by \"BYEE\". Did you mean bye?",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := NewVocabulary().parseCommand(tc.input)
+			if tc.expectErr != "" {
+				assert.Equal(tc.expectErr, tqerrors.GameMessage(err))
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_parseCommand_customVerbs(t *testing.T) {
+	vocab := NewVocabulary()
+	vocab.SetCustomVerbs([]VerbDef{
+		{Name: "PUSH", Aliases: []string{"SHOVE", "PRESS ON"}, Recipient: ArgRequired, Preposition: "WITH", Instrument: ArgOptional},
+		{Name: "JUMP", Recipient: ArgNone},
+		{Name: "DIG", Recipient: ArgOptional, Preposition: "WITH", Instrument: ArgRequired},
+	})
+
+	testCases := []struct {
+		name      string
+		input     string
+		expect    Command
+		expectErr string
+	}{
+		{
+			name:   "recipient",
+			input:  "push red button",
+			expect: Command{Verb: "PUSH", Recipient: "RED BUTTON"},
+		},
+		{
+			name:   "recipient and instrument through alias",
+			input:  "press on box with long stick",
+			expect: Command{Verb: "PUSH", Recipient: "BOX", Instrument: "LONG STICK"},
+		},
+		{
+			name:      "missing recipient",
+			input:     "shove",
+			expectErr: "I don't know what you want to push",
+		},
+		{
+			name:   "no arguments",
+			input:  "jump",
+			expect: Command{Verb: "JUMP"},
+		},
+		{
+			name:      "argument not allowed",
+			input:     "jump rope",
+			expectErr: "You can't jump *something*",
+		},
+		{
+			name:   "only instrument",
+			input:  "dig with shovel",
+			expect: Command{Verb: "DIG", Instrument: "SHOVEL"},
+		},
+		{
+			name:      "missing instrument",
+			input:     "dig hole",
+			expectErr: "I don't know what you want to dig it with",
+		},
+		{
+			name:   "misspelled",
+			input:  "jmup",
+			expect: Command{Verb: "JUMP", VerbAssumed: true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := vocab.parseCommand(tc.input)
+			if tc.expectErr != "" {
+				assert.Equal(tc.expectErr, tqerrors.GameMessage(err))
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_parseCommand_vocabulary(t *testing.T) {
+	vocab := NewVocabulary()
+	vocab.SetAlias("X", "LOOK")
+	vocab.SetAlias("N", "GO NORTH")
+	vocab.SetAlias("NIMM", "TAKE")
+	vocab.SetAlias("PUT", "USE")
+	vocab.SetAlias("I", "")
+	vocab.SetWord("AUS", "FROM")
+	vocab.SetWord("UND", "AND")
+
+	testCases := []struct {
+		name      string
+		input     string
+		expect    Command
+		expectErr string
+	}{
+		{
+			name:   "added alias",
+			input:  "x",
+			expect: Command{Verb: "LOOK"},
+		},
+		{
+			name:   "added alias with arguments in expansion",
+			input:  "n",
+			expect: Command{Verb: "GO", Recipient: "NORTH"},
+		},
+		{
+			name:   "replaced alias",
+			input:  "put key",
+			expect: Command{Verb: "USE", Recipient: "KEY"},
+		},
+		{
+			name:      "removed alias",
+			input:     "i",
+			expectErr: `I don't know what you mean by "I"`,
+		},
+		{
+			name:   "default alias is kept",
+			input:  "get key",
+			expect: Command{Verb: "TAKE", Recipient: "KEY"},
+		},
+		{
+			name:   "localized words",
+			input:  "nimm schlussel und buch aus kiste",
+			expect: Command{Verb: "TAKE", Recipient: "SCHLUSSEL", Recipients: []string{"SCHLUSSEL", "BUCH"}, Instrument: "KISTE"},
+		},
+		{
+			name:   "default words are kept",
+			input:  "take key from box",
+			expect: Command{Verb: "TAKE", Recipient: "KEY", Instrument: "BOX"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := vocab.parseCommand(tc.input)
+			if tc.expectErr != "" {
+				assert.Equal(tc.expectErr, tqerrors.GameMessage(err))
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+
+	// other vocabularies are not affected
+	_, err := NewVocabulary().parseCommand("x")
+	assert.Error(t, err)
+}
+
+func Test_Vocabulary_ParseAll(t *testing.T) {
+	testCases := []struct {
+		name      string
+		input     string
+		expect    []Command
+		expectErr string
+	}{
+		{
+			name:   "one command",
+			input:  "take spoon",
+			expect: []Command{{Verb: "TAKE", Recipient: "SPOON"}},
+		},
+		{
+			name:  "periods",
+			input: "take spoon. go south. look.",
+			expect: []Command{
+				{Verb: "TAKE", Recipient: "SPOON"},
+				{Verb: "GO", Recipient: "SOUTH"},
+				{Verb: "LOOK"},
+			},
+		},
+		{
+			name:  "semicolons and THEN",
+			input: "take spoon;go south then look ;",
+			expect: []Command{
+				{Verb: "TAKE", Recipient: "SPOON"},
+				{Verb: "GO", Recipient: "SOUTH"},
+				{Verb: "LOOK"},
+			},
+		},
+		{
+			name:   "empty commands are skipped",
+			input:  ". ; look then",
+			expect: []Command{{Verb: "LOOK"}},
+		},
+		{
+			name:  "debug exec takes the rest of the line",
+			input: `look. DEBUG EXEC $OUTPUT("Hi. Bye; then")`,
+			expect: []Command{
+				{Verb: "LOOK"},
+				{Verb: "DEBUG", Recipient: "EXEC", Instrument: `$OUTPUT("Hi. Bye; then")`},
+			},
+		},
+		{
+			name:   "again",
+			input:  "g",
+			expect: []Command{{Verb: "AGAIN"}},
+		},
+		{
+			name:      "one bad command fails all",
+			input:     "take spoon. dance",
+			expectErr: `I don't know what you mean by "DANCE"`,
+		},
+		{
+			name:   "nothing",
+			input:  "  ",
+			expect: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual, err := NewVocabulary().ParseAll(tc.input)
+			if tc.expectErr != "" {
+				assert.Equal(tc.expectErr, tqerrors.GameMessage(err))
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_ResolveAgain(t *testing.T) {
+	assert := assert.New(t)
+
+	look := Command{Verb: "LOOK"}
+	take := Command{Verb: "TAKE", Recipient: "SPOON"}
+	again := Command{Verb: "AGAIN"}
+
+	actual, err := ResolveAgain([]Command{again, take, again}, look)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal([]Command{look, take, take}, actual)
+
+	_, err = ResolveAgain([]Command{again}, Command{})
+	assert.Equal("There's nothing to do again", tqerrors.GameMessage(err))
+}
@@ -0,0 +1,288 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/dekarrin/tunaq/internal/util"
+)
+
+var (
+	// defaultReservedWords is the list of all token sequences that a symbol
+	// cannot have anywhere or it will cause issues in parsing. These are the
+	// words that parseCommand looks for after the verb.
+	defaultReservedWords = []string{
+		"TO",
+		"THROUGH",
+		"INTO",
+		"FROM",
+		"ON",
+		"IN",
+		"WITH",
+		"AT",
+		"AND",
+		"ALL",
+		"EXCEPT",
+		"THEN",
+	}
+)
+
+var (
+	// verbs is every verb that parseCommand understands, in the order they
+	// are checked. It must be kept up to date with parseCommand.
+	verbs = []string{
+		"HELP", "EXITS", "GO", "TAKE", "DROP", "USE", "TALK", "GIVE", "SHOW",
+		"OPEN", "CLOSE", "LOCK", "UNLOCK", "LOOK", "DEBUG", "INVENTORY", "QUIT",
+		"SAVE", "LOAD", "SAVES", "RESTART", "WAIT", "UNDO", "REDO", "AGAIN",
+	}
+
+	// metaVerbs is the verbs that act on the game being played rather than
+	// within it. A misspelling of one is never corrected without asking, as
+	// doing one by mistake can lose progress.
+	metaVerbs = []string{
+		"QUIT", "SAVE", "LOAD", "SAVES", "RESTART", "UNDO", "REDO", "AGAIN",
+	}
+)
+
+var (
+	// defaultVerbAliases maps shorthand verbs (which must be the first words
+	// in a command) to their canonical forms. They are all uppercase.
+	defaultVerbAliases = map[string]string{
+		"NORTH":    "GO NORTH",
+		"SOUTH":    "GO SOUTH",
+		"EAST":     "GO EAST",
+		"WEST":     "GO WEST",
+		"UP":       "GO UP",
+		"DOWN":     "GO DOWN",
+		"MOVE":     "GO",
+		"BYE":      "QUIT",
+		"SPEAK":    "TALK",
+		"COMBINE":  "USE",
+		"PUT":      "DROP",
+		"PUT DOWN": "DROP",
+		"GET":      "TAKE",
+		"PICK":     "TAKE",
+		"PICK UP":  "TAKE",
+		"DESCRIBE": "LOOK",
+		"DESC":     "LOOK",
+		"?":        "HELP",
+		"/?":       "HELP",
+		"/H":       "HELP",
+		"-H":       "HELP",
+		"H":        "HELP",
+		"INVEN":    "INVENTORY",
+		"I":        "INVENTORY",
+		"SHUT":     "CLOSE",
+		"Z":        "WAIT",
+		"G":        "AGAIN",
+	}
+)
+
+// MaxAliasWords is the most words that a verb alias can be made of.
+const MaxAliasWords = 2
+
+// Vocabulary is the words that commands are parsed with. It holds the aliases
+// that can be typed instead of the built-in verbs, any words that stand in for
+// the reserved words commands are built with, such as a word in another
+// language to use instead of "WITH", and any verbs that a world defines in
+// addition to the built-in ones.
+//
+// The zero value is not ready for use; create one with NewVocabulary.
+type Vocabulary struct {
+	// aliases maps shorthand verbs to their canonical forms.
+	aliases map[string]string
+
+	// words maps words that can be typed in commands to the reserved words
+	// they stand for.
+	words map[string]string
+
+	// customVerbs is the verbs set by SetCustomVerbs, by their names.
+	customVerbs map[string]VerbDef
+
+	// customVerbAliases maps the aliases of the verbs in customVerbs to their
+	// names.
+	customVerbAliases map[string]string
+}
+
+// NewVocabulary returns a Vocabulary with the default English verb aliases and
+// no custom verbs.
+func NewVocabulary() *Vocabulary {
+	v := &Vocabulary{
+		aliases:           make(map[string]string, len(defaultVerbAliases)),
+		words:             map[string]string{},
+		customVerbs:       map[string]VerbDef{},
+		customVerbAliases: map[string]string{},
+	}
+	for alias, expansion := range defaultVerbAliases {
+		v.aliases[alias] = expansion
+	}
+	return v
+}
+
+// SetAlias makes alias expand to expansion when it is typed at the start of a
+// command, replacing any alias already set for it. If expansion is empty, the
+// alias is removed instead. Both are converted to upper case.
+func (v *Vocabulary) SetAlias(alias, expansion string) {
+	alias = strings.Join(strings.Fields(strings.ToUpper(alias)), " ")
+	if expansion == "" {
+		delete(v.aliases, alias)
+		return
+	}
+	v.aliases[alias] = strings.ToUpper(expansion)
+}
+
+// SetWord makes word stand for the reserved word meaning anywhere after the
+// verb in a command, as in "MIT" for "WITH". Both are converted to upper case.
+func (v *Vocabulary) SetWord(word, meaning string) {
+	v.words[strings.ToUpper(word)] = strings.ToUpper(meaning)
+}
+
+// SetCustomVerbs sets the verbs that are understood in addition to the
+// built-in ones, replacing any that were set before. Built-in verbs and
+// aliases take precedence over any of them with the same name.
+func (v *Vocabulary) SetCustomVerbs(defs []VerbDef) {
+	v.customVerbs = map[string]VerbDef{}
+	v.customVerbAliases = map[string]string{}
+
+	for _, def := range defs {
+		v.customVerbs[def.Name] = def
+		for _, alias := range def.Aliases {
+			v.customVerbAliases[alias] = def.Name
+		}
+	}
+}
+
+// Canonical returns the reserved word that word stands for. If it does not
+// stand for one, word is returned unchanged.
+func (v *Vocabulary) Canonical(word string) string {
+	if meaning, ok := v.words[word]; ok {
+		return meaning
+	}
+	return word
+}
+
+// IsBuiltInVerb returns whether word is one of the built-in verbs or one of
+// the verb aliases of v.
+func (v *Vocabulary) IsBuiltInVerb(word string) bool {
+	if _, ok := v.aliases[word]; ok {
+		return true
+	}
+	return IsVerb(word)
+}
+
+// IsVerb returns whether word is the canonical name of one of the built-in
+// verbs.
+func IsVerb(word string) bool {
+	return util.InSlice(word, verbs)
+}
+
+// IsReservedWord returns whether word is one of the reserved words that every
+// Vocabulary has, not counting any that stand in for them.
+func IsReservedWord(word string) bool {
+	return util.InSlice(word, defaultReservedWords)
+}
+
+// ReservedWords returns every reserved word of v, including the words that
+// stand in for the default ones.
+func (v *Vocabulary) ReservedWords() []string {
+	return append(append([]string{}, defaultReservedWords...), util.OrderedKeys(v.words)...)
+}
+
+// FindFirstReserved takes the input, tokenizes it, and then checks whether it
+// contains one of the reserved sequences. It can be used to check whether a
+// symbol definition should be rejected by callers and marked as valid/invalid.
+//
+// Returns the first reserved word encountered. If none are encountered it will
+// return the empty string.
+func (v *Vocabulary) FindFirstReserved(s string) string {
+	normalizedCase := strings.ToUpper(s)
+	tokens := strings.Fields(normalizedCase)
+
+	reserved := v.ReservedWords()
+	for i := range reserved {
+		for j := 0; j < len(tokens); j++ {
+			if tokens[j] == reserved[i] {
+				return reserved[i]
+			}
+		}
+	}
+
+	return ""
+}
+
+// ExpandAliases takes a slice of tokens of user input and runs alias expansion
+// on it. It expects all strings in the given slice to be upper case; failure to
+// ensure this may cause the expansion to not work properly. The returned slice
+// contains the same tokens but with aliases expanded.
+//
+// The unexpanded tokens slice is not modified during this operation.
+//
+// Aliases up to aliasLimit words long are supported. If it is less than 0, it
+// is assumed to be 0. Passing 0 means the given tokens will be returned
+// unchanged.
+//
+// Aliases will not be multi-expanded; that is, expansion is not applied to the
+// results of an expansion; if the caller needs it, they will need to call
+// ExpandAliases again on its output.
+func (v *Vocabulary) ExpandAliases(tokens []string, aliasLimit int) []string {
+	expandedTokens := append([]string{}, tokens...)
+	if aliasLimit < 1 {
+		return expandedTokens
+	}
+
+	// only modify verb up to minimum of limit and number of tokens
+	if aliasLimit > len(tokens) {
+		aliasLimit = len(tokens)
+	}
+
+	for curLimit := 1; curLimit <= aliasLimit; curLimit++ {
+		checkStr := strings.Join(tokens[:curLimit], " ")
+		expansion, ok := v.aliases[checkStr]
+		if !ok {
+			expansion, ok = v.customVerbAliases[checkStr]
+		}
+		if ok {
+			replacementTokens := strings.Fields(expansion)
+
+			// luckily, we know we are operating from start of tokens passed in so we can just trash
+			// all those in the checkStr and replace with the replacementTokens slice
+			expandedTokens = append(replacementTokens, tokens[curLimit:]...)
+
+			// we gaurantee only one single substitution, so we can immediately exit
+			return expandedTokens
+		}
+	}
+
+	return expandedTokens
+}
+
+// Parse parses a command from the given text using the words in v. If it
+// cannot, a non-nil error is returned.
+//
+// If an empty string or a string composed only of whitespace is passed in, nil
+// error is returned and a zero value for Command will be returned.
+func (v *Vocabulary) Parse(input string) (Command, error) {
+	return v.parseCommand(input)
+}
+
+// ParseAll parses every command in the given text using the words in v, in
+// the order they are given. Commands are separated by semicolons, periods at
+// the ends of words, or THEN, as in "TAKE SPOON. GO SOUTH THEN LOOK". A DEBUG
+// EXEC or DEBUG EXPAND command takes the rest of the text, so that the code
+// given to it can have any of them. If any command cannot be parsed, a non-nil
+// error is returned and none of them are.
+//
+// Empty commands are skipped; if there are no commands in input, nil error and
+// an empty slice are returned.
+func (v *Vocabulary) ParseAll(input string) ([]Command, error) {
+	var cmds []Command
+	for _, text := range v.splitCommands(input) {
+		cmd, err := v.parseCommand(text)
+		if err != nil {
+			return nil, err
+		}
+		if cmd.Verb != "" {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds, nil
+}
@@ -39,6 +39,108 @@ func (ue UseAction) Copy() UseAction {
 	return aCopy
 }
 
+// ItemHook is the definition of a script that runs when an item is taken,
+// dropped, or looked at. The zero value is a hook that allows the action and
+// does nothing.
+type ItemHook struct {
+	// If gives tunascript that must resolve to true for the action to be
+	// allowed. If no tunascript was parsed, the action is always allowed.
+	If tunascript.AST
+
+	// IfRaw gives the exact source tunascript that was parsed to create If. If
+	// no code was parsed, this will be the empty string.
+	IfRaw string
+
+	// Do contains the tunascript that will be executed after the action is
+	// performed.
+	Do tunascript.AST
+
+	// DoRaw gives the exact source tunascript(s) that were parsed to create Do.
+	DoRaw []string
+
+	// Refusal is the message shown to the player when If prevents the action.
+	// If it is empty, a generic message is shown instead.
+	Refusal string
+
+	// tmplRefusal is the precomputed template AST for the refusal text. It
+	// must generally be filled in with the game engine, and will not be
+	// present directly when loaded from disk.
+	tmplRefusal *tunascript.Template
+}
+
+// Copy returns a deeply-copied ItemHook.
+func (ih ItemHook) Copy() ItemHook {
+	hCopy := ItemHook{
+		If:          ih.If,
+		IfRaw:       ih.IfRaw,
+		Do:          ih.Do,
+		DoRaw:       make([]string, len(ih.DoRaw)),
+		Refusal:     ih.Refusal,
+		tmplRefusal: ih.tmplRefusal,
+	}
+
+	copy(hCopy.DoRaw, ih.DoRaw)
+
+	return hCopy
+}
+
+// GiveAction is the definition of how an NPC reacts when the player gives or
+// shows it an item.
+type GiveAction struct {
+	// With gives the labels (or tags) of items that the reaction is for. It is
+	// used for an item that matches any one of them. If it's empty, it is used
+	// for any item.
+	With []string
+
+	// Refuse is whether the NPC refuses the item. A refused item stays with
+	// the player and Do is not executed.
+	Refuse bool
+
+	// If gives tunascript that must resolve to true for the reaction to be
+	// used. If no tunascript was parsed, this will be something that always
+	// returns true.
+	If tunascript.AST
+
+	// IfRaw gives the exact source tunascript that was parsed to create If. If
+	// no code was parsed, this will be the empty string.
+	IfRaw string
+
+	// Do contains the tunascript that will be executed when the reaction is
+	// used.
+	Do tunascript.AST
+
+	// DoRaw gives the exact source tunascript(s) that were parsed to create Do.
+	DoRaw []string
+
+	// Response is the message shown to the player when the reaction is used.
+	// If it is empty, a generic message is shown instead.
+	Response string
+
+	// tmplResponse is the precomputed template AST for the response text. It
+	// must generally be filled in with the game engine, and will not be
+	// present directly when loaded from disk.
+	tmplResponse *tunascript.Template
+}
+
+// Copy returns a deeply-copied GiveAction.
+func (ga GiveAction) Copy() GiveAction {
+	aCopy := GiveAction{
+		With:         make([]string, len(ga.With)),
+		Refuse:       ga.Refuse,
+		If:           ga.If,
+		IfRaw:        ga.IfRaw,
+		Do:           ga.Do,
+		DoRaw:        make([]string, len(ga.DoRaw)),
+		Response:     ga.Response,
+		tmplResponse: ga.tmplResponse,
+	}
+
+	copy(aCopy.With, ga.With)
+	copy(aCopy.DoRaw, ga.DoRaw)
+
+	return aCopy
+}
+
 // selectBestUseMatch selects the best candidate from several matches.
 func selectBestUseMatch(matches []useMatch) useMatch {
 	// okay, we now have a set of candidate use matches. Let's filter them down
@@ -87,3 +189,73 @@ func selectBestUseMatch(matches []useMatch) useMatch {
 
 	return matches[0]
 }
+
+// VerbAction is the definition of how something in the world reacts when the
+// player uses a verb that the world defines on it, such as "PUSH BOX".
+type VerbAction struct {
+	// Verb is the name of the verb that the reaction is for.
+	Verb string
+
+	// With gives the labels (or tags) of the things the verb can be used with
+	// for the reaction to be used, as "STICK" would be for "PUSH BOX WITH
+	// STICK". It is used for an instrument that matches any one of them. If
+	// it's empty, it is used whether or not an instrument is given.
+	With []string
+
+	// If gives tunascript that must resolve to true for the reaction to be
+	// used. If no tunascript was parsed, this will be something that always
+	// returns true.
+	If tunascript.AST
+
+	// IfRaw gives the exact source tunascript that was parsed to create If. If
+	// no code was parsed, this will be the empty string.
+	IfRaw string
+
+	// Do contains the tunascript that will be executed when the reaction is
+	// used.
+	Do tunascript.AST
+
+	// DoRaw gives the exact source tunascript(s) that were parsed to create Do.
+	DoRaw []string
+
+	// Response is the message shown to the player when the reaction is used.
+	// If it is empty and Do does not output anything, a generic message is
+	// shown instead.
+	Response string
+
+	// tmplResponse is the precomputed template AST for the response text. It
+	// must generally be filled in with the game engine, and will not be
+	// present directly when loaded from disk.
+	tmplResponse *tunascript.Template
+}
+
+// Copy returns a deeply-copied VerbAction.
+func (va VerbAction) Copy() VerbAction {
+	aCopy := VerbAction{
+		Verb:         va.Verb,
+		With:         make([]string, len(va.With)),
+		If:           va.If,
+		IfRaw:        va.IfRaw,
+		Do:           va.Do,
+		DoRaw:        make([]string, len(va.DoRaw)),
+		Response:     va.Response,
+		tmplResponse: va.tmplResponse,
+	}
+
+	copy(aCopy.With, va.With)
+	copy(aCopy.DoRaw, va.DoRaw)
+
+	return aCopy
+}
+
+// copyVerbActions returns a deep copy of actions.
+func copyVerbActions(actions []VerbAction) []VerbAction {
+	if actions == nil {
+		return nil
+	}
+	aCopy := make([]VerbAction, len(actions))
+	for i := range actions {
+		aCopy[i] = actions[i].Copy()
+	}
+	return aCopy
+}
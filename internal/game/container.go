@@ -0,0 +1,416 @@
+package game
+
+// File container.go contains symbols for items and details that can hold other
+// items, such as chests, bags, and drawers.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/rosed"
+	"github.com/dekarrin/tunaq/internal/command"
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+	"github.com/dekarrin/tunaq/internal/util"
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// Container is the items held inside of an Item or a Detail. If the thing that
+// has the Container also has a Lock that is Openable, the Container can only be
+// looked in, taken from, or put into while it is open.
+type Container struct {
+	// Capacity is the maximum number of items that the player can put in the
+	// Container. If it is 0, there is no limit. Items moved in with tunascript
+	// are not limited by it.
+	Capacity int
+
+	// Items is the items currently inside the Container. This can be changed
+	// over time.
+	Items []*Item
+}
+
+// Copy returns a deeply-copied Container.
+func (c Container) Copy() Container {
+	cCopy := Container{
+		Capacity: c.Capacity,
+		Items:    make([]*Item, len(c.Items)),
+	}
+
+	for i := range c.Items {
+		itemCopy := c.Items[i].Copy()
+		cCopy.Items[i] = &itemCopy
+	}
+
+	return cCopy
+}
+
+// GetItemByAlias returns the item in the Container that is represented by the
+// given alias. If no Item visible to asker has that alias, the returned item
+// is nil. To allow returning of an Item regardless of its visibility, simply
+// pass in "" for the asker or nil for the interpreter.
+func (c Container) GetItemByAlias(alias string, asker string, tsEng *tunascript.Interpreter) *Item {
+	var foundItem *Item
+
+	for _, it := range c.Items {
+		for _, al := range it.Aliases {
+			if al == alias {
+				foundItem = it
+				break
+			}
+		}
+		if foundItem != nil {
+			break
+		}
+	}
+
+	// run the If-check
+	if foundItem != nil && asker != "" && tsEng != nil {
+		tsEng.AddFlag(FlagAsker, asker)
+		if !tsEng.Exec(foundItem.If).Bool() {
+			foundItem = nil
+		}
+		tsEng.RemoveFlag(FlagAsker)
+	}
+	return foundItem
+}
+
+// ItemsAvailable returns all items in the Container that the entity with the
+// given label can see, as per the Item's If value.
+func (c Container) ItemsAvailable(asker string, tsEng *tunascript.Interpreter) []*Item {
+	var avail []*Item
+
+	if asker == "" || tsEng == nil {
+		return c.Items
+	}
+
+	tsEng.AddFlag(FlagAsker, asker)
+	for i := range c.Items {
+		if tsEng.Exec(c.Items[i].If).Bool() {
+			avail = append(avail, c.Items[i])
+		}
+	}
+	tsEng.RemoveFlag(FlagAsker)
+
+	return avail
+}
+
+// RemoveItem removes the item of the given label from the Container. If there
+// is already no item with that label in it, this has no effect.
+func (c *Container) RemoveItem(label string) {
+	for idx, it := range c.Items {
+		if it.Label == label {
+			c.Items = append(c.Items[:idx], c.Items[idx+1:]...)
+			return
+		}
+	}
+}
+
+// containerHolder is a lockable that may have a Container.
+type containerHolder interface {
+	lockable
+	GetContainer() *Container
+}
+
+// containedItems returns all items in the Containers of items, and all items in
+// the Containers of those, and so on.
+func containedItems(items []*Item) []*Item {
+	var contained []*Item
+	for _, it := range items {
+		if it.Container != nil {
+			contained = append(contained, it.Container.Items...)
+			contained = append(contained, containedItems(it.Container.Items)...)
+		}
+	}
+	return contained
+}
+
+// initContainers finds every Container in the world and tracks it by the label
+// of the thing that has it. It must be called after the world is loaded and
+// before anything has been moved.
+func (gs *State) initContainers() {
+	gs.containers = make(map[string]containerHolder)
+
+	for _, r := range gs.World {
+		for _, det := range r.Details {
+			if det.Container != nil {
+				gs.containers[det.Label] = det
+			}
+		}
+		for _, it := range r.AllItems() {
+			if it.Container != nil {
+				gs.containers[it.Label] = it
+			}
+		}
+	}
+}
+
+// allItems returns every item in the game, wherever it is, indexed by label.
+func (gs *State) allItems() map[string]*Item {
+	items := map[string]*Item{}
+	for _, r := range gs.World {
+		for _, it := range r.Items {
+			items[it.Label] = it
+		}
+		for _, npc := range r.NPCs {
+			for _, it := range npc.Inventory {
+				items[it.Label] = it
+			}
+		}
+	}
+	for _, it := range gs.Inventory {
+		items[it.Label] = it
+	}
+	for _, holder := range gs.containers {
+		for _, it := range holder.GetContainer().Items {
+			items[it.Label] = it
+		}
+	}
+	return items
+}
+
+// containerOpen returns whether the Container of holder can currently be
+// reached into.
+func (gs *State) containerOpen(holder containerHolder) bool {
+	lock := holder.GetLock()
+	if lock == nil || !lock.Openable {
+		return true
+	}
+	return gs.locks[lock.Label].open
+}
+
+// containerHolds returns whether the item with label inner is somewhere inside
+// of the Container of the item with label outer, even if it is inside of other
+// Containers that are inside of that one.
+func (gs *State) containerHolds(outer, inner string) bool {
+	loc := gs.itemLocations[inner]
+	for {
+		if _, ok := gs.containers[loc]; !ok {
+			return false
+		}
+		if loc == outer {
+			return true
+		}
+		loc = gs.itemLocations[loc]
+	}
+}
+
+// isItemLocation returns whether loc is somewhere that an item can be put; a
+// room label, the label of something with a Container, the label of an NPC, or
+// "@INVEN".
+func (gs *State) isItemLocation(loc string) bool {
+	if loc == "@INVEN" {
+		return true
+	}
+	if _, ok := gs.World[loc]; ok {
+		return true
+	}
+	if _, ok := gs.npcLocations[loc]; ok {
+		return true
+	}
+	_, ok := gs.containers[loc]
+	return ok
+}
+
+// npcByLabel returns the NPC with the given label, wherever it is. If there is
+// no NPC with that label, nil is returned.
+func (gs *State) npcByLabel(label string) *NPC {
+	roomLabel, ok := gs.npcLocations[label]
+	if !ok {
+		return nil
+	}
+	return gs.World[roomLabel].NPCs[label]
+}
+
+// removeItem removes the item with the given label from wherever it is and
+// returns it. If there is no item with that label, nil is returned.
+func (gs *State) removeItem(label string) *Item {
+	loc, ok := gs.itemLocations[label]
+	if !ok {
+		return nil
+	}
+
+	var item *Item
+	if loc == "@INVEN" {
+		item = gs.Inventory[label]
+		delete(gs.Inventory, label)
+	} else if npc := gs.npcByLabel(loc); npc != nil {
+		item = npc.Inventory[label]
+		delete(npc.Inventory, label)
+	} else if holder, ok := gs.containers[loc]; ok {
+		c := holder.GetContainer()
+		for _, it := range c.Items {
+			if it.Label == label {
+				item = it
+				break
+			}
+		}
+		c.RemoveItem(label)
+	} else {
+		r := gs.World[loc]
+		for _, it := range r.Items {
+			if it.Label == label {
+				item = it
+				break
+			}
+		}
+		r.RemoveItem(label)
+	}
+
+	delete(gs.itemLocations, label)
+	return item
+}
+
+// placeItem puts item in loc, which must be a room label, the label of
+// something with a Container, the label of an NPC, or "@INVEN".
+func (gs *State) placeItem(item *Item, loc string) {
+	if loc == "@INVEN" {
+		gs.Inventory[item.Label] = item
+	} else if npc := gs.npcByLabel(loc); npc != nil {
+		if npc.Inventory == nil {
+			npc.Inventory = make(Inventory)
+		}
+		npc.Inventory[item.Label] = item
+	} else if holder, ok := gs.containers[loc]; ok {
+		c := holder.GetContainer()
+		c.Items = append(c.Items, item)
+	} else {
+		r := gs.World[loc]
+		r.Items = append(r.Items, item)
+	}
+
+	gs.itemLocations[item.Label] = loc
+}
+
+// getContainer returns the thing with a Container in the current room or the
+// player's inventory that alias refers to, along with the name to call it. If
+// there is nothing with that alias that has a Container, a non-nil error for
+// showing to the player is returned.
+func (gs *State) getContainer(alias string) (containerHolder, string, error) {
+	tgt, err := gs.findTarget(alias, true)
+	if err != nil {
+		return nil, "", err
+	}
+	if tgt == nil {
+		return nil, "", tqerrors.Interpreterf("I don't see any %q here", alias)
+	}
+
+	name := strings.ToLower(alias)
+	if it, ok := tgt.(*Item); ok {
+		name = it.Name
+	}
+
+	holder, ok := tgt.(containerHolder)
+	if !ok || holder.GetContainer() == nil {
+		return nil, "", tqerrors.Interpreterf("The %s can't hold anything", name)
+	}
+
+	return holder, name, nil
+}
+
+// describeContents returns the text that lists what is in the Container of
+// holder, for showing after its description when the player LOOKs at it.
+func (gs *State) describeContents(holder containerHolder, name string) string {
+	if !gs.containerOpen(holder) {
+		return fmt.Sprintf("The %s is closed.", name)
+	}
+
+	avail := holder.GetContainer().ItemsAvailable(TagPlayer, &gs.scripts)
+	if len(avail) < 1 {
+		return fmt.Sprintf("The %s is empty.", name)
+	}
+
+	var itemNames []string
+	for _, it := range avail {
+		itemNames = append(itemNames, it.Name)
+	}
+	return fmt.Sprintf("Inside the %s, you can see %s.", name, util.MakeTextList(itemNames, true))
+}
+
+// takeFromContainer executes a TAKE command that gives the container to take
+// the item from as its Instrument and returns the output.
+func (gs *State) takeFromContainer(cmd command.Command) (string, error) {
+	holder, name, err := gs.getContainer(cmd.Instrument)
+	if err != nil {
+		return "", err
+	}
+	if !gs.containerOpen(holder) {
+		return "", tqerrors.Interpreterf("The %s is closed", name)
+	}
+
+	find := func(alias string) (*Item, error) {
+		return gs.findContainedItem(holder, alias)
+	}
+	take := func(item *Item) (string, error) {
+		return gs.takeItemFrom(holder, name, item)
+	}
+	contents := holder.GetContainer().ItemsAvailable(TagPlayer, &gs.scripts)
+	return gs.forEachItem(cmd, contents, find, func(alias string) error {
+		return tqerrors.Interpreterf("I don't see any %q in the %s", alias, name)
+	}, take)
+}
+
+// takeItemFrom has the player take item out of the Container of holder, which
+// is called name, and returns the output.
+func (gs *State) takeItemFrom(holder containerHolder, name string, item *Item) (string, error) {
+	if err := gs.checkItemHook(item.OnTake, fmt.Sprintf("You can't pick up the %s", item.Name)); err != nil {
+		return "", err
+	}
+
+	gs.removeItem(item.Label)
+	gs.placeItem(item, "@INVEN")
+
+	output := fmt.Sprintf("You take the %s out of the %s and add it to your inventory", item.Name, name)
+	if hookOutput := gs.runItemHook(item.OnTake); hookOutput != "" {
+		output = rosed.Edit(output + "\n\n" + hookOutput).WithOptions(textFormatOptions).Wrap(gs.io.Width()).String()
+	}
+	return output, nil
+}
+
+// putInContainer executes a DROP command that gives the container to put the
+// item in as its Instrument and returns the output.
+func (gs *State) putInContainer(cmd command.Command) (string, error) {
+	holder, name, err := gs.getContainer(cmd.Instrument)
+	if err != nil {
+		return "", err
+	}
+
+	put := func(item *Item) (string, error) {
+		return gs.putItemIn(holder, name, item)
+	}
+
+	// putting everything in something doesn't include the thing itself
+	var carried []*Item
+	for _, it := range gs.inventoryItems() {
+		if it.Label != holder.GetLabel() {
+			carried = append(carried, it)
+		}
+	}
+	return gs.forEachItem(cmd, carried, gs.findInventoryItem, notCarried, put)
+}
+
+// putItemIn has the player put item from their inventory into the Container of
+// holder, which is called name, and returns the output.
+func (gs *State) putItemIn(holder containerHolder, name string, item *Item) (string, error) {
+	if holder.GetLabel() == item.Label || gs.containerHolds(item.Label, holder.GetLabel()) {
+		return "", tqerrors.Interpreterf("You can't put the %s inside of itself", item.Name)
+	}
+	if !gs.containerOpen(holder) {
+		return "", tqerrors.Interpreterf("The %s is closed", name)
+	}
+	c := holder.GetContainer()
+	if c.Capacity > 0 && len(c.Items) >= c.Capacity {
+		return "", tqerrors.Interpreterf("The %s is full", name)
+	}
+
+	if err := gs.checkItemHook(item.OnDrop, fmt.Sprintf("You can't bring yourself to drop the %s", item.Name)); err != nil {
+		return "", err
+	}
+
+	gs.removeItem(item.Label)
+	gs.placeItem(item, holder.GetLabel())
+
+	output := fmt.Sprintf("You put the %s in the %s", item.Name, name)
+	if hookOutput := gs.runItemHook(item.OnDrop); hookOutput != "" {
+		output = rosed.Edit(output + "\n\n" + hookOutput).WithOptions(textFormatOptions).Wrap(gs.io.Width()).String()
+	}
+	return output, nil
+}
@@ -0,0 +1,132 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/dekarrin/tunaq/tunascript"
+	"github.com/stretchr/testify/assert"
+)
+
+// containerWorld is testWorld with a closed cupboard in the kitchen that has
+// a cup in it, and a bag on the ground that can hold one thing.
+func containerWorld() worldBuilder {
+	world := testWorld()
+
+	cupboard := world.detail("KITCHEN", "CUPBOARD")
+	cupboard.Lock = &Lock{Label: "CUPBOARD", Openable: true}
+	cupboard.Container = &Container{Items: []*Item{
+		{Label: "CUP", Name: "cup", Aliases: []string{"CUP"}, If: tunascript.ReturnTrue},
+	}}
+
+	world.item("KITCHEN", "BAG").Container = &Container{Capacity: 1}
+
+	return world
+}
+
+func Test_State_containers(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		return New(containerWorld(), "KITCHEN", nil, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:      "take from closed container",
+			cmd:       "take cup from cupboard",
+			expectErr: "The cupboard is closed",
+		},
+		{
+			name:         "look in open container",
+			setup:        []string{"open cupboard"},
+			cmd:          "look at cupboard",
+			expectOutput: "Inside the cupboard, you can see a cup.",
+		},
+		{
+			name:       "take from open container",
+			setup:      []string{"open cupboard"},
+			cmd:        "take cup from cupboard",
+			expectTrue: []string{"$IN_INVEN(CUP)"},
+		},
+		{
+			name:       "put into carried container",
+			setup:      []string{"open cupboard", "take cup from cupboard", "take bag"},
+			cmd:        "put cup in bag",
+			expectTrue: []string{"$NOT($IN_INVEN(CUP))", "$IN_INVEN(BAG)"},
+		},
+		{
+			name:      "put container inside of itself",
+			setup:     []string{"take bag"},
+			cmd:       "put bag in bag",
+			expectErr: "You can't put the bag inside of itself",
+		},
+		{
+			name:       "put into full container",
+			setup:      []string{"take spoon", "take fork", "put fork in bag"},
+			cmd:        "put spoon in bag",
+			expectErr:  "The bag is full",
+			expectTrue: []string{"$IN_INVEN(SPOON)"},
+		},
+	})
+}
+
+func Test_State_containers_move(t *testing.T) {
+	testCases := []struct {
+		name         string
+		scripts      []string
+		expectMoved  bool
+		expectLocs   map[string]string
+		expectCounts map[string]int
+	}{
+		{
+			name:         "into container",
+			scripts:      []string{"$MOVE(CUP, BAG)"},
+			expectMoved:  true,
+			expectLocs:   map[string]string{"CUP": "BAG"},
+			expectCounts: map[string]int{"CUPBOARD": 0, "BAG": 1},
+		},
+		{
+			name:         "container into container",
+			scripts:      []string{"$MOVE(CUP, BAG)", "$MOVE(BAG, CUPBOARD)"},
+			expectMoved:  true,
+			expectLocs:   map[string]string{"CUP": "BAG", "BAG": "CUPBOARD"},
+			expectCounts: map[string]int{"CUPBOARD": 1, "BAG": 1},
+		},
+		{
+			name:         "container into something inside of it",
+			scripts:      []string{"$MOVE(BAG, CUPBOARD)", "$MOVE(CUPBOARD, BAG)"},
+			expectMoved:  false,
+			expectLocs:   map[string]string{"BAG": "CUPBOARD"},
+			expectCounts: map[string]int{"CUPBOARD": 2},
+		},
+		{
+			name:         "container into itself",
+			scripts:      []string{"$MOVE(BAG, BAG)"},
+			expectMoved:  false,
+			expectLocs:   map[string]string{"BAG": "KITCHEN"},
+			expectCounts: map[string]int{"BAG": 0},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gs, err := New(containerWorld(), "KITCHEN", nil, &nopIODevice{})
+			if !assert.NoError(err) {
+				return
+			}
+
+			var moved bool
+			for _, code := range tc.scripts {
+				moved = gs.scripts.Exec(mustParseScript(code)).Bool()
+			}
+
+			assert.Equal(tc.expectMoved, moved)
+			for label, loc := range tc.expectLocs {
+				assert.Equal(loc, gs.itemLocations[label], label)
+			}
+			for label, count := range tc.expectCounts {
+				assert.Len(gs.containers[label].GetContainer().Items, count, label)
+			}
+		})
+	}
+}
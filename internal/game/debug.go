@@ -215,6 +215,11 @@ func (gs *State) GetNPCInfo(label string) (string, error) {
 
 		npcInfo = append(npcInfo, [2]string{"Allowed Rooms", allowed})
 		npcInfo = append(npcInfo, [2]string{"Forbidden Rooms", forbidden})
+	} else if npc.Movement.Action == RouteGoto {
+		npcInfo = append(npcInfo, [2]string{"Target Room", npc.Movement.Target})
+	}
+	if npc.sentTo != "" {
+		npcInfo = append(npcInfo, [2]string{"Sent To", npc.sentTo})
 	}
 
 	diaStr := "(none defined)"
@@ -68,6 +68,30 @@ type DialogStep struct {
 	// blank on a PAUSE step, it will resume with the next step in the tree.
 	ResumeAt string
 
+	// If is the tunascript that is evaluated to determine whether this step is
+	// shown when the conversation reaches it. If it evaluates to false, the
+	// step is skipped and the conversation moves on to the next one. It is
+	// only used if Action is DialogLine or DialogChoice. If it has no nodes,
+	// the step is always shown.
+	If tunascript.AST
+
+	// IfRaw is the string that contains the TunaScript source code that was
+	// parsed into the AST located in If. It will be empty if no code was parsed
+	// to do so.
+	IfRaw string
+
+	// Do is the tunascript that is executed each time this step is shown. It
+	// is only used if Action is DialogLine or DialogChoice.
+	Do tunascript.AST
+
+	// DoRaw gives the exact source tunascript(s) that were parsed to create Do.
+	DoRaw []string
+
+	// ChoiceScripts is the tunascript for each option in Choices, at the same
+	// index as the option it is for. If it has fewer entries than Choices,
+	// options without one are always shown and do nothing extra when picked.
+	ChoiceScripts []DialogChoiceScript
+
 	// tmplResponse is the pre-computed tunascript template AST for the
 	// Response. It can only be created by a Tunascript engine and will not be
 	// present on initial load of DialogStep from disk.
@@ -84,24 +108,75 @@ type DialogStep struct {
 	tmplChoices []*tunascript.Template
 }
 
+// DialogChoiceScript is the tunascript attached to a single option of a CHOICE
+// DialogStep.
+type DialogChoiceScript struct {
+	// If is the tunascript that is evaluated to determine whether the option
+	// is shown to the player. If it has no nodes, the option is always shown.
+	If tunascript.AST
+
+	// IfRaw is the string that contains the TunaScript source code that was
+	// parsed into the AST located in If. It will be empty if no code was parsed
+	// to do so.
+	IfRaw string
+
+	// Do is the tunascript that is executed when the player picks the option.
+	Do tunascript.AST
+
+	// DoRaw gives the exact source tunascript(s) that were parsed to create Do.
+	DoRaw []string
+}
+
+// Copy returns a deeply-copied DialogChoiceScript.
+func (dcs DialogChoiceScript) Copy() DialogChoiceScript {
+	dcsCopy := DialogChoiceScript{
+		If:    dcs.If,
+		IfRaw: dcs.IfRaw,
+		Do:    dcs.Do,
+		DoRaw: make([]string, len(dcs.DoRaw)),
+	}
+
+	copy(dcsCopy.DoRaw, dcs.DoRaw)
+
+	return dcsCopy
+}
+
 // Copy returns a deeply-copied DialogStep.
 func (ds DialogStep) Copy() DialogStep {
 	dsCopy := DialogStep{
-		Action:   ds.Action,
-		Label:    ds.Label,
-		Response: ds.Response,
-		Choices:  make([][2]string, len(ds.Choices)),
-		Content:  ds.Content,
-		ResumeAt: ds.ResumeAt,
+		Action:        ds.Action,
+		Label:         ds.Label,
+		Response:      ds.Response,
+		Choices:       make([][2]string, len(ds.Choices)),
+		Content:       ds.Content,
+		ResumeAt:      ds.ResumeAt,
+		If:            ds.If,
+		IfRaw:         ds.IfRaw,
+		Do:            ds.Do,
+		DoRaw:         make([]string, len(ds.DoRaw)),
+		ChoiceScripts: make([]DialogChoiceScript, len(ds.ChoiceScripts)),
 	}
 
 	for idx, v := range ds.Choices {
 		dsCopy.Choices[idx] = [2]string{v[0], v[1]}
 	}
+	copy(dsCopy.DoRaw, ds.DoRaw)
+	for idx := range ds.ChoiceScripts {
+		dsCopy.ChoiceScripts[idx] = ds.ChoiceScripts[idx].Copy()
+	}
 
 	return dsCopy
 }
 
+// choiceScript returns the DialogChoiceScript for the option at the given index
+// in Choices. If there isn't one, an empty DialogChoiceScript is returned.
+func (ds DialogStep) choiceScript(idx int) DialogChoiceScript {
+	if idx < len(ds.ChoiceScripts) {
+		return ds.ChoiceScripts[idx]
+	}
+	return DialogChoiceScript{}
+}
+
 func (ds DialogStep) String() string {
 	str := fmt.Sprintf("DialogStep<%q", ds.Action)
 
@@ -225,6 +300,10 @@ func (gs *State) RunConversation(npc *NPC) error {
 				npc.Convo = nil
 				return nil
 			case DialogLine:
+				if !gs.dialogCondition(step.If) {
+					continue
+				}
+
 				line := gs.Expand(step.tmplContent)
 				ed := rosed.Edit("\n"+strings.ToUpper(npc.Name)+":\n").WithOptions(textFormatOptions).
 					CharsFrom(rosed.End).
@@ -243,6 +322,7 @@ func (gs *State) RunConversation(npc *NPC) error {
 				if err := gs.io.Output(output); err != nil {
 					return err
 				}
+				gs.scripts.Exec(step.Do)
 
 				stopCmd, err := gs.io.Input("(Enter to continue, 'STOP' to end) ==>")
 				if err != nil {
@@ -254,6 +334,22 @@ func (gs *State) RunConversation(npc *NPC) error {
 					return nil
 				}
 			case DialogChoice:
+				if !gs.dialogCondition(step.If) {
+					continue
+				}
+
+				// only the options whose conditions are met are offered, and
+				// are numbered in the order they are shown.
+				var shown []int
+				for idx := range step.Choices {
+					if gs.dialogCondition(step.choiceScript(idx).If) {
+						shown = append(shown, idx)
+					}
+				}
+				if len(shown) < 1 {
+					continue
+				}
+
 				line := gs.Expand(step.tmplContent)
 				ed := rosed.Edit("\n"+strings.ToUpper(npc.Name)+":\n").WithOptions(textFormatOptions).
 					CharsFrom(rosed.End).
@@ -262,15 +358,10 @@ func (gs *State) RunConversation(npc *NPC) error {
 					Insert(rosed.End, "\n\n").
 					CharsFrom(rosed.End)
 
-				var choiceOut = make([]string, len(step.Choices))
-				for idx := range step.Choices {
-					tsCh := step.tmplChoices[idx]
-					chDest := step.Choices[idx][1]
-					choiceOut[idx] = chDest
-
-					ch := gs.Expand(tsCh)
+				for num, idx := range shown {
+					ch := gs.Expand(step.tmplChoices[idx])
 
-					ed = ed.Insert(rosed.End, fmt.Sprintf("%d) \"%s\"\n", idx+1, strings.TrimSpace(ch)))
+					ed = ed.Insert(rosed.End, fmt.Sprintf("%d) \"%s\"\n", num+1, strings.TrimSpace(ch)))
 				}
 				ed = ed.Apply(func(idx int, line string) []string {
 					return []string{rosed.Edit(line).Wrap(gs.io.Width()).String()}
@@ -282,6 +373,7 @@ func (gs *State) RunConversation(npc *NPC) error {
 				if err != nil {
 					return err
 				}
+				gs.scripts.Exec(step.Do)
 
 				var validNum bool
 				var choiceNum int
@@ -290,8 +382,8 @@ func (gs *State) RunConversation(npc *NPC) error {
 					if err != nil {
 						return err
 					} else {
-						if choiceNum < 1 || len(step.Choices) < choiceNum {
-							err = gs.io.Output("Please enter a number between 1 and %d\n", len(step.Choices))
+						if choiceNum < 1 || len(shown) < choiceNum {
+							err = gs.io.Output("Please enter a number between 1 and %d\n", len(shown))
 							if err != nil {
 								return err
 							}
@@ -301,8 +393,9 @@ func (gs *State) RunConversation(npc *NPC) error {
 					}
 				}
 
-				dest := choiceOut[choiceNum-1]
-				npc.Convo.JumpTo(dest)
+				picked := shown[choiceNum-1]
+				gs.scripts.Exec(step.choiceScript(picked).Do)
+				npc.Convo.JumpTo(step.Choices[picked][1])
 			case DialogPause:
 				// if resumeAt is not set, no jump needs to be made, convo will
 				// resume with NextStep.
@@ -317,3 +410,12 @@ func (gs *State) RunConversation(npc *NPC) error {
 		}
 	}
 }
+
+// dialogCondition returns whether the given 'if' of a dialog step or choice is
+// met. Steps and choices without an 'if' always are.
+func (gs *State) dialogCondition(cond tunascript.AST) bool {
+	if len(cond.Nodes) < 1 {
+		return true
+	}
+	return gs.scripts.Exec(cond).Bool()
+}
@@ -0,0 +1,64 @@
+package game
+
+import "testing"
+
+func Test_State_RunConversation_scripts(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		world := testWorld()
+		world["KITCHEN"].NPCs["CHEF"].Dialog = []*DialogStep{
+			{Action: DialogLine, Content: "Welcome back.", If: mustParseScript("$FLAG_ENABLED(MET_CHEF)")},
+			{Action: DialogLine, Content: "Who are you?", If: mustParseScript("$FLAG_DISABLED(MET_CHEF)")},
+			{
+				Action:  DialogChoice,
+				Content: "Hungry?",
+				Choices: [][2]string{
+					{"I'm a stranger.", "STRANGER"},
+					{"Starving!", "FED"},
+				},
+				ChoiceScripts: []DialogChoiceScript{
+					{If: mustParseScript("$FLAG_DISABLED(MET_CHEF)")},
+					{Do: mustParseScript("$ENABLE(FED)")},
+				},
+			},
+			{Action: DialogLine, Label: "STRANGER", Content: "Get out."},
+			{Action: DialogEnd},
+			{Action: DialogLine, Label: "FED", Content: "Eat up."},
+		}
+		return New(world, "KITCHEN", nil, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:            "line hidden by if",
+			setup:           []string{"debug exec $ENABLE(MET_CHEF)"},
+			cmd:             "talk to chef",
+			answers:         []string{"", "1"},
+			expectOutput:    "Welcome back.",
+			expectNotOutput: "Who are you?",
+		},
+		{
+			name:            "choice hidden by if",
+			setup:           []string{"debug exec $ENABLE(MET_CHEF)"},
+			cmd:             "talk to chef",
+			answers:         []string{"", "1"},
+			expectOutput:    "1) \"Starving!\"",
+			expectNotOutput: "I'm a stranger.",
+			expectTrue:      []string{"$FED"},
+		},
+		{
+			name:         "choice without do",
+			cmd:          "talk to chef",
+			answers:      []string{"", "1"},
+			expectOutput: "Get out.",
+			expectTrue:   []string{"$NOT($FED)"},
+		},
+		{
+			name:            "choice with do",
+			cmd:             "talk to chef",
+			answers:         []string{"", "2"},
+			expectOutput:    "Eat up.",
+			expectNotOutput: "Get out.",
+			expectTrue:      []string{"$FED"},
+		},
+	})
+}
@@ -0,0 +1,323 @@
+package game
+
+// File disambiguate.go contains symbols for working out which thing the player
+// means when an alias they use refers to more than one, or when they use a
+// pronoun instead of an alias.
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+	"github.com/dekarrin/tunaq/internal/util"
+)
+
+// referredToBy returns whether t is referred to by alias. Besides any of the
+// aliases of t, an Item is also referred to by one of its aliases with any
+// number of its adjectives in front of it, such as "RED KEY" or "SMALL RED
+// KEY".
+func referredToBy(t Targetable, alias string) bool {
+	var adjectives []string
+	if it, ok := t.(*Item); ok {
+		adjectives = it.Adjectives
+	}
+
+	for _, al := range t.GetAliases() {
+		if al == alias {
+			return true
+		}
+		if len(adjectives) < 1 || !strings.HasSuffix(alias, " "+al) {
+			continue
+		}
+
+		qualified := true
+		for _, word := range strings.Fields(strings.TrimSuffix(alias, " "+al)) {
+			if !util.InSlice(word, adjectives) {
+				qualified = false
+				break
+			}
+		}
+		if qualified {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matching returns the ones in candidates that are referred to by alias, in
+// the same order as they are in candidates.
+func matching[T Targetable](candidates []T, alias string) []T {
+	var matches []T
+	for _, c := range candidates {
+		if referredToBy(c, alias) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// closeMatching returns the ones in candidates that have an alias alias is
+// close to but does not exactly match, in the same order as they are in
+// candidates. Only visible things should be given as candidates, so that
+// nothing hidden is suggested to the player.
+func closeMatching[T Targetable](candidates []T, alias string) []T {
+	var matches []T
+	for _, c := range candidates {
+		// allow the adjectives of items, such as in "RED KYE"
+		noun := alias
+		if it, ok := Targetable(c).(*Item); ok {
+			words := strings.Fields(alias)
+			for len(words) > 1 && util.InSlice(words[0], it.Adjectives) {
+				words = words[1:]
+			}
+			noun = strings.Join(words, " ")
+		}
+
+		for _, al := range c.GetAliases() {
+			if util.IsCloseMatch(noun, al) {
+				matches = append(matches, c)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// targetName returns what to call t when asking the player which of several
+// things they meant.
+func targetName(t Targetable) string {
+	switch tgt := t.(type) {
+	case *Item:
+		// make sure the adjectives that tell the item apart are shown even if
+		// the name doesn't include them
+		var missing []string
+		for _, adj := range tgt.Adjectives {
+			if !strings.Contains(strings.ToUpper(tgt.Name), adj) {
+				missing = append(missing, strings.ToLower(adj))
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Sprintf("%s (%s)", tgt.Name, strings.Join(missing, " "))
+		}
+		return tgt.Name
+	case *NPC:
+		return tgt.Name
+	case *Egress:
+		return "the way out " + strings.ToLower(tgt.Aliases[0])
+	default:
+		return "the " + strings.ToLower(t.GetAliases()[0])
+	}
+}
+
+// pick returns the one of matches that the player meant by alias. If there is
+// more than one, the player is shown a numbered list of them and asked to
+// choose, either by number or by saying which one they meant with more of its
+// adjectives. If the player gives a blank answer, a non-nil error for showing
+// to the player is returned. If matches is empty, the zero value of T is
+// returned.
+func pick[T Targetable](gs *State, alias string, matches []T) (T, error) {
+	var none T
+	if len(matches) < 1 {
+		return none, nil
+	}
+
+	for len(matches) > 1 {
+		prompt := fmt.Sprintf("\nWhich %s do you mean?\n", strings.ToLower(alias))
+		for i, m := range matches {
+			prompt += fmt.Sprintf("%d) %s\n", i+1, targetName(m))
+		}
+		if err := gs.io.Output("%s", prompt); err != nil {
+			return none, err
+		}
+
+		answer, err := gs.io.Input("==> ")
+		if err != nil {
+			return none, err
+		}
+		answer = strings.Join(strings.Fields(strings.ToUpper(answer)), " ")
+		if answer == "" {
+			return none, tqerrors.Interpreterf("Never mind, then")
+		}
+
+		if num, err := strconv.Atoi(answer); err == nil {
+			if num < 1 || len(matches) < num {
+				if err := gs.io.Output("Please enter a number between 1 and %d\n", len(matches)); err != nil {
+					return none, err
+				}
+				continue
+			}
+			return matches[num-1], nil
+		}
+
+		// the player may have given the whole thing, like "RED KEY", or just
+		// the adjectives, like "RED"
+		narrowed := matching(matches, answer)
+		if len(narrowed) < 1 {
+			narrowed = matching(matches, answer+" "+alias)
+		}
+		if len(narrowed) < 1 {
+			if err := gs.io.Output("Please enter a number between 1 and %d\n", len(matches)); err != nil {
+				return none, err
+			}
+			continue
+		}
+		matches = narrowed
+	}
+
+	return matches[0], nil
+}
+
+// lookup returns the one of candidates that the player means by alias. If
+// alias is a pronoun for something the player referred to before, that is
+// used; otherwise it is whichever of the candidates alias refers to, asking
+// the player which one if there is more than one. If none of them is, alias is
+// taken to be a misspelling of the one candidate it is close to; if it is
+// close to several, a non-nil error suggesting them to the player is returned.
+// The result is remembered for later pronouns. If none of candidates is
+// referred to by alias or close to it, the zero value of T is returned.
+func lookup[T Targetable](gs *State, alias string, candidates []T) (T, error) {
+	var none T
+	var matches []T
+	if referent := gs.pronounReferent(alias); referent != nil {
+		for _, c := range candidates {
+			if sameTarget(c, referent) {
+				matches = append(matches, c)
+			}
+		}
+	} else {
+		matches = matching(candidates, alias)
+		if len(matches) < 1 {
+			near := closeMatching(candidates, alias)
+			if len(near) > 1 {
+				var names []string
+				for _, c := range near {
+					names = append(names, targetName(c))
+				}
+				return none, tqerrors.Interpreterf("I don't see any %q here. Did you mean %s?", alias, util.JoinOr(names))
+			}
+			matches = near
+		}
+	}
+
+	found, err := pick(gs, alias, matches)
+	if err != nil || len(matches) < 1 {
+		return found, err
+	}
+
+	gs.rememberReferent(found)
+	return found, nil
+}
+
+// pronounReferent returns the thing that alias refers to if it is a pronoun
+// for something the player referred to before. An NPC is referred to by the
+// nominative or objective pronoun in its PronounSet, and anything else is
+// referred to by "IT", "THEM", or "THEY". The most recent thing that alias
+// could refer to is used. If alias isn't such a pronoun, nil is returned.
+func (gs *State) pronounReferent(alias string) Targetable {
+	for _, t := range gs.referents {
+		if npc, ok := t.(*NPC); ok {
+			if alias == npc.Pronouns.Nominative || alias == npc.Pronouns.Objective {
+				return npc
+			}
+		} else if alias == "IT" || alias == "THEM" || alias == "THEY" {
+			return t
+		}
+	}
+	return nil
+}
+
+// rememberReferent records that the player referred to t so that it can be
+// referred to by a pronoun later.
+func (gs *State) rememberReferent(t Targetable) {
+	referents := []Targetable{t}
+	for _, other := range gs.referents {
+		if !sameTarget(other, t) {
+			referents = append(referents, other)
+		}
+	}
+	gs.referents = referents
+}
+
+// sameTarget returns whether a and b are the same thing in the world. They
+// may be different copies of it, such as from before and after a game is
+// loaded.
+func sameTarget(a, b Targetable) bool {
+	if a.GetLabel() != b.GetLabel() {
+		return false
+	}
+	return IsItem(a) == IsItem(b) && IsNPC(a) == IsNPC(b) && IsEgress(a) == IsEgress(b) && IsDetail(a) == IsDetail(b)
+}
+
+// roomTargets returns everything in the current room that the player can see
+// and refer to, in the order that they are checked for an alias: details,
+// then exits, then items, then NPCs.
+func (gs *State) roomTargets() []Targetable {
+	var targets []Targetable
+	for _, det := range gs.CurrentRoom.DetailsAvailable(TagPlayer, &gs.scripts) {
+		targets = append(targets, det)
+	}
+	for _, eg := range gs.CurrentRoom.ExitsAvailable(TagPlayer, &gs.scripts) {
+		targets = append(targets, eg)
+	}
+	for _, it := range gs.CurrentRoom.ItemsAvailable(TagPlayer, &gs.scripts) {
+		targets = append(targets, it)
+	}
+	for _, npc := range gs.CurrentRoom.NPCsAvailable(TagPlayer, &gs.scripts) {
+		targets = append(targets, npc)
+	}
+	return targets
+}
+
+// inventoryItems returns the items in the player's inventory, ordered by
+// label.
+func (gs *State) inventoryItems() []*Item {
+	var items []*Item
+	for _, label := range util.OrderedKeys(gs.Inventory) {
+		items = append(items, gs.Inventory[label])
+	}
+	return items
+}
+
+// findTarget returns the thing in the current room that the player means by
+// alias, asking them which one if there is more than one. If withInventory is
+// true, the items in the player's inventory are considered as well. If nothing
+// is referred to by alias, nil is returned.
+func (gs *State) findTarget(alias string, withInventory bool) (Targetable, error) {
+	candidates := gs.roomTargets()
+	if withInventory {
+		for _, it := range gs.inventoryItems() {
+			candidates = append(candidates, it)
+		}
+	}
+	return lookup(gs, alias, candidates)
+}
+
+// findRoomItem returns the item on the ground in the current room that the
+// player means by alias, asking them which one if there is more than one. If
+// no item is referred to by alias, nil is returned.
+func (gs *State) findRoomItem(alias string) (*Item, error) {
+	return lookup(gs, alias, gs.CurrentRoom.ItemsAvailable(TagPlayer, &gs.scripts))
+}
+
+// findInventoryItem returns the item in the player's inventory that the player
+// means by alias, asking them which one if there is more than one. If no item
+// is referred to by alias, nil is returned.
+func (gs *State) findInventoryItem(alias string) (*Item, error) {
+	return lookup(gs, alias, gs.inventoryItems())
+}
+
+// findContainedItem returns the item inside of the Container of holder that
+// the player means by alias, asking them which one if there is more than one.
+// If no item is referred to by alias, nil is returned.
+func (gs *State) findContainedItem(holder containerHolder, alias string) (*Item, error) {
+	return lookup(gs, alias, holder.GetContainer().ItemsAvailable(TagPlayer, &gs.scripts))
+}
+
+// findRoomNPC returns the NPC in the current room that the player means by
+// alias, asking them which one if there is more than one. If no NPC is
+// referred to by alias, nil is returned.
+func (gs *State) findRoomNPC(alias string) (*NPC, error) {
+	return lookup(gs, alias, gs.CurrentRoom.NPCsAvailable(TagPlayer, &gs.scripts))
+}
@@ -0,0 +1,150 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+func Test_State_disambiguation(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		world := testWorld()
+		redKey := world.item("KITCHEN", "RED_KEY")
+		redKey.Name = "red key"
+		redKey.Aliases = []string{"KEY"}
+		redKey.Adjectives = []string{"RED"}
+		blueKey := world.item("KITCHEN", "BLUE_KEY")
+		blueKey.Name = "key"
+		blueKey.Aliases = []string{"KEY"}
+		blueKey.Adjectives = []string{"BLUE"}
+		return New(world, "KITCHEN", nil, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:            "adjective picks one without asking",
+			cmd:             "take red key",
+			expectNotOutput: "Which key do you mean?",
+			expectTrue:      []string{"$IN_INVEN(RED_KEY)", "$NOT($IN_INVEN(BLUE_KEY))"},
+		},
+		{
+			name:            "only one matches without asking",
+			setup:           []string{"take red key"},
+			cmd:             "drop key",
+			expectNotOutput: "Which key do you mean?",
+			expectTrue:      []string{"$NOT($IN_INVEN(RED_KEY))"},
+		},
+		{
+			name:         "answered with an adjective",
+			setup:        []string{"take red key", "take key"},
+			answers:      []string{"green", "red"},
+			cmd:          "drop key",
+			expectOutput: "Which key do you mean?\n1) key (blue)\n2) red key\nPlease enter a number between 1 and 2",
+			expectTrue:   []string{"$IN_INVEN(BLUE_KEY)", "$NOT($IN_INVEN(RED_KEY))"},
+		},
+		{
+			name:         "answered with a number",
+			answers:      []string{"1"},
+			cmd:          "take key",
+			expectOutput: "Which key do you mean?",
+			expectTrue:   []string{"$IN_INVEN(RED_KEY)", "$NOT($IN_INVEN(BLUE_KEY))"},
+		},
+		{
+			name:         "not answered",
+			cmd:          "look at key",
+			expectOutput: "Which key do you mean?",
+			expectErr:    "Never mind, then",
+		},
+	})
+}
+
+func Test_State_pronouns(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		world := giveWorld()
+		world.item("KITCHEN", "KEYS")
+		world.npc("KITCHEN", "BARD").Pronouns = PronounsNonBinary
+		return New(world, "KITCHEN", nil, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:      "nothing referred to yet",
+			cmd:       "take it",
+			expectErr: "I don't see any \"IT\" here",
+		},
+		{
+			name:         "it is the last thing referred to",
+			setup:        []string{"look at spoon"},
+			cmd:          "look at it",
+			expectOutput: "You examine the SPOON.",
+		},
+		{
+			name:       "it after taking",
+			setup:      []string{"look at spoon"},
+			cmd:        "take it",
+			expectTrue: []string{"$IN_INVEN(SPOON)"},
+		},
+		{
+			name:      "her before referring to an NPC",
+			setup:     []string{"take spoon"},
+			cmd:       "give it to her",
+			expectErr: "I don't see a \"HER\" you can give things to here",
+		},
+		{
+			name:      "pronoun that the NPC doesn't go by",
+			setup:     []string{"take spoon", "look at chef"},
+			cmd:       "give spoon to him",
+			expectErr: "I don't see a \"HIM\" you can give things to here",
+		},
+		{
+			name:       "pronoun that the NPC goes by",
+			setup:      []string{"take spoon", "look at chef"},
+			cmd:        "give spoon to her",
+			expectTrue: []string{"$NPC_HAS(CHEF, SPOON)"},
+		},
+		{
+			name:       "thing referred to after an NPC with the same pronoun",
+			setup:      []string{"look at bard", "take keys"},
+			cmd:        "drop them",
+			expectTrue: []string{"$NOT($IN_INVEN(KEYS))"},
+		},
+	})
+}
+
+func Test_State_misspellings(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		world := testWorld()
+		world.item("KITCHEN", "SPOOL")
+		world.item("KITCHEN", "SPOOK").If = tunascript.ReturnFalse
+		return New(world, "KITCHEN", nil, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:      "close to more than one, but not to a hidden one",
+			cmd:       "take spoo",
+			expectErr: "I don't see any \"SPOO\" here. Did you mean spoon or spool?",
+		},
+		{
+			name:       "close to only one item",
+			cmd:        "take frok",
+			expectTrue: []string{"$IN_INVEN(FORK)"},
+		},
+		{
+			name:         "close to only one NPC",
+			cmd:          "look at che",
+			expectOutput: "CHEF",
+		},
+		{
+			name:      "not close to anything",
+			cmd:       "take bowl",
+			expectErr: "I don't see any \"BOWL\" here",
+		},
+		{
+			name:         "verb is assumed",
+			cmd:          "tkae fork",
+			expectOutput: "(assuming take)",
+			expectTrue:   []string{"$IN_INVEN(FORK)"},
+		},
+	})
+}
@@ -0,0 +1,146 @@
+package game
+
+// File event.go contains symbols for keeping track of game time and for the
+// events that the world schedules to happen as it passes.
+
+import (
+	"strings"
+
+	"github.com/dekarrin/rosed"
+	"github.com/dekarrin/tunaq/internal/command"
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// Event is tunascript that the world schedules to be executed at the end of
+// certain turns, regardless of what the player is doing. An Event is scheduled
+// by exactly one of AtTurn, EveryNTurns, or AfterFlag, except that AtTurn may
+// be given along with EveryNTurns to give the first turn it is executed on.
+type Event struct {
+	// Label is a unique identifier for the Event.
+	Label string
+
+	// AtTurn is the turn at the end of which the Event is executed. If it is 0,
+	// the Event is not scheduled by turn number. If EveryNTurns is also set,
+	// this is the first turn it is executed on instead of the only one.
+	AtTurn int
+
+	// EveryNTurns is how often the Event is executed. If it is set, the Event
+	// is executed at the end of every turn that is a multiple of it, or at
+	// the end of AtTurn and every EveryNTurns turns after it if AtTurn is also
+	// set. If it is 0, the Event does not repeat.
+	EveryNTurns int
+
+	// AfterFlag is the label of a flag that schedules the Event. Delay turns
+	// after the end of the first turn that the flag is found to be enabled,
+	// the Event is executed. If it is empty, the Event is not scheduled by a
+	// flag.
+	AfterFlag string
+
+	// Delay is the number of turns to wait after AfterFlag is enabled before
+	// executing the Event. It is only used with AfterFlag.
+	Delay int
+
+	// If is tunascript that is checked when the Event is due. If it is false,
+	// the Event is not executed that time. If no tunascript was parsed, this
+	// will be something that always returns true.
+	If tunascript.AST
+
+	// IfRaw gives the exact source tunascript that was parsed to create If. If
+	// no code was parsed, this will be the empty string.
+	IfRaw string
+
+	// Do is the tunascript that is executed when the Event happens. Anything
+	// it outputs with $OUTPUT() is shown to the player after the result of the
+	// command that ended the turn.
+	Do tunascript.AST
+
+	// DoRaw gives the exact source tunascript(s) that were parsed to create Do.
+	DoRaw []string
+}
+
+// due returns whether ev is scheduled to be executed at the end of turn. For
+// an Event scheduled by AfterFlag, flaggedAt is the turn at the end of which
+// the flag was first found to be enabled, and ok is whether it has been.
+func (ev Event) due(turn int, flaggedAt int, ok bool) bool {
+	if ev.AfterFlag != "" {
+		return ok && turn == flaggedAt+ev.Delay
+	}
+
+	if ev.EveryNTurns > 0 {
+		if ev.AtTurn > 0 {
+			return turn >= ev.AtTurn && (turn-ev.AtTurn)%ev.EveryNTurns == 0
+		}
+		return turn%ev.EveryNTurns == 0
+	}
+
+	return turn == ev.AtTurn
+}
+
+// SetEvents sets the Events that are scheduled in the game. It should be
+// called after New and before the first command is given.
+func (gs *State) SetEvents(events []*Event) {
+	gs.events = events
+	gs.flaggedAt = make(map[string]int)
+}
+
+// passTurn advances the turn counter, moves every NPC, and then executes every
+// Event that is due at the end of the new turn, in the order they were given
+// to SetEvents. The narration of the NPCs and any output of their scripts is
+// returned, followed by the output of the Events, with the output of each in
+// its own paragraph.
+func (gs *State) passTurn() string {
+	gs.turn++
+
+	// enable buffering so any output of the NPCs and the events can be shown
+	// after the output of the command that ended the turn
+	gs.tsBufferOutput = true
+	defer func() {
+		gs.tsBuf.Reset()
+		gs.tsBufferOutput = false
+	}()
+
+	var outputs []string
+	if npcMessages := gs.MoveNPCs(); npcMessages != "" {
+		outputs = append(outputs, npcMessages)
+	}
+	if npcOutput := strings.TrimSpace(gs.tsBuf.String()); npcOutput != "" {
+		outputs = append(outputs, npcOutput)
+	}
+	gs.tsBuf.Reset()
+
+	for _, ev := range gs.events {
+		if ev.AfterFlag != "" {
+			if _, ok := gs.flaggedAt[ev.Label]; !ok {
+				if val, _ := gs.scripts.FlagValue(ev.AfterFlag); val.CastToBool().Bool() {
+					gs.flaggedAt[ev.Label] = gs.turn
+				}
+			}
+		}
+
+		flaggedAt, ok := gs.flaggedAt[ev.Label]
+		if !ev.due(gs.turn, flaggedAt, ok) {
+			continue
+		}
+		if len(ev.If.Nodes) > 0 && !gs.scripts.Exec(ev.If).Bool() {
+			continue
+		}
+		gs.scripts.Exec(ev.Do)
+
+		// each event gets its own paragraph
+		if evOutput := strings.TrimSpace(gs.tsBuf.String()); evOutput != "" {
+			outputs = append(outputs, evOutput)
+		}
+		gs.tsBuf.Reset()
+	}
+
+	if len(outputs) < 1 {
+		return ""
+	}
+	return rosed.Edit(strings.Join(outputs, "\n\n")).WithOptions(textFormatOptions).Wrap(gs.io.Width()).String()
+}
+
+// ExecuteCommandWait executes the WAIT command with the arguments in the
+// provided Command and returns the output.
+func (gs *State) ExecuteCommandWait(cmd command.Command) (string, error) {
+	return "Time passes...", nil
+}
@@ -0,0 +1,109 @@
+package game
+
+import "testing"
+
+func Test_State_events(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		gs, err := New(testWorld(), "KITCHEN", nil, ioDev)
+		if err != nil {
+			return nil, err
+		}
+		gs.SetEvents([]*Event{
+			{Label: "COLLAPSE", AfterFlag: "CROSSED", Delay: 2, Do: mustParseScript("$INC(COLLAPSES)")},
+			{Label: "BELL", AtTurn: 2, EveryNTurns: 3, Do: mustParseScript("$INC(BELLS)")},
+		})
+		return gs, nil
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:       "nothing due",
+			cmd:        "wait",
+			expectTrue: []string{"$TURN() == 1", "$NOT($BELLS)"},
+		},
+		{
+			name:       "help doesn't take a turn",
+			setup:      []string{"wait"},
+			cmd:        "help",
+			expectTrue: []string{"$TURN() == 1"},
+		},
+		{
+			name:       "at turn",
+			setup:      []string{"wait"},
+			cmd:        "wait",
+			expectTrue: []string{"$TURN() == 2", "$FLAG_IS(BELLS, 1)"},
+		},
+		{
+			name:       "every n turns",
+			setup:      []string{"wait", "wait", "wait", "wait"},
+			cmd:        "wait",
+			expectTrue: []string{"$TURN() == 5", "$FLAG_IS(BELLS, 2)"},
+		},
+		{
+			name:       "flag not yet delayed enough",
+			setup:      []string{"debug exec $ENABLE(CROSSED)", "wait"},
+			cmd:        "wait",
+			expectTrue: []string{"$TURN() == 2", "$NOT($COLLAPSES)"},
+		},
+		{
+			name:       "after flag with delay",
+			setup:      []string{"wait", "debug exec $ENABLE(CROSSED)", "wait", "wait"},
+			cmd:        "wait",
+			expectTrue: []string{"$TURN() == 4", "$FLAG_IS(BELLS, 1)", "$FLAG_IS(COLLAPSES, 1)"},
+		},
+	})
+}
+
+func Test_State_passTurn_movesNPCs(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		world := testWorld()
+		world["KITCHEN"].NPCs["CHEF"].Movement = Route{Action: RoutePatrol, Path: []string{"HALL", "KITCHEN"}}
+		return New(world, "KITCHEN", nil, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:       "command that isn't movement",
+			cmd:        "look",
+			expectNPCs: map[string]string{"CHEF": "HALL"},
+		},
+		{
+			name:       "wait",
+			cmd:        "wait",
+			expectNPCs: map[string]string{"CHEF": "HALL"},
+		},
+		{
+			name:       "command outside of game time",
+			cmd:        "help",
+			expectNPCs: map[string]string{"CHEF": "KITCHEN"},
+		},
+	})
+}
+
+func Test_State_passTurn_output(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		world := testWorld()
+		world["KITCHEN"].NPCs["CHEF"].If = mustParseScript("$OUTPUT(@The chef hums.@)")
+		gs, err := New(world, "KITCHEN", nil, ioDev)
+		if err != nil {
+			return nil, err
+		}
+		gs.SetEvents([]*Event{
+			{Label: "BELL", AtTurn: 1, Do: mustParseScript("$OUTPUT(@A bell rings.@)")},
+		})
+		return gs, nil
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:         "output of NPCs and events comes after the command",
+			cmd:          "wait",
+			expectOutput: "Time passes...\n\nThe chef hums.\n\nA bell rings.",
+		},
+		{
+			name:            "nothing happens when no turn passes",
+			cmd:             "help",
+			expectNotOutput: "The chef hums.",
+		},
+	})
+}
@@ -2,8 +2,10 @@ package game
 
 import (
 	"fmt"
+	"math/rand"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/dekarrin/rosed"
 	"github.com/dekarrin/tunaq/internal/command"
@@ -18,22 +20,63 @@ const (
 	FlagAsker = "TQ_ASKER"
 )
 
+// Flags that are set while an NPC's enter or leave message is expanded. Each
+// pronoun flag is set to the lower-case form of that pronoun from the NPC's
+// PronounSet.
+const (
+	FlagNPCName     = "TQ_NPC"
+	FlagNominative  = "TQ_NOMINATIVE"
+	FlagObjective   = "TQ_OBJECTIVE"
+	FlagPossessive  = "TQ_POSSESSIVE"
+	FlagDeterminer  = "TQ_DETERMINER"
+	FlagReflexive   = "TQ_REFLEXIVE"
+	FlagNPCExitName = "TQ_EXIT"
+)
+
+// IsEngineFlag returns whether label is the label of a flag that the engine
+// itself sets while scripts or templates are being run, as opposed to one
+// that a world defines.
+func IsEngineFlag(label string) bool {
+	switch label {
+	case FlagAsker, FlagNPCName, FlagNominative, FlagObjective, FlagPossessive, FlagDeterminer, FlagReflexive, FlagNPCExitName:
+		return true
+	default:
+		return false
+	}
+}
+
+// maxRoomHookDepth is the most room hooks that can be running at once. It
+// keeps a hook that moves the player into a room whose hooks move the player
+// back from running forever.
+const maxRoomHookDepth = 16
+
 var commandHelp = [][2]string{
 	{"HELP", "show this help"},
-	{"DROP/PUT", "put down an object in the room"},
+	{"AGAIN/G", "do the last command again"},
+	{"DROP/PUT [IN something]", "put down an object in the room, or inside of something; several can be given with AND, or ALL [EXCEPT something]"},
 	{"DEBUG NPC", "print info on all NPCs, or a single NPC with label LABEL if 'DEBUG NPC LABEL' is typed, or steps all NPCs if 'DEBUG NPC @STEP' is typed."},
 	{"DEBUG ROOM", "print info on the current room, or teleport to room with label LABEL if 'DEBUG ROOM LABEL' is typed."},
 	{"DEBUG EXEC [code]", "print what the tunascript code evaluates to"},
 	{"DEBUG EXPAND [text]", "print the given text with tunascript $IFs and flags expanded"},
 	{"DEBUG FLAGS", "print all flags and their values"},
 	{"EXITS", "show the names of all exits from the room"},
+	{"GIVE/SHOW", "give an object in your inventory to someone in the room, or show it to them"},
 	{"GO/MOVE", "go to another room via one of the exits"},
 	{"INVENTORY/INVEN", "show your current inventory"},
 	{"LOOK [something]", "show the description of something, or the room with LOOK by itself"},
+	{"LOAD [name]", "load a saved game, from the save called 'name' if given"},
+	{"LOCK/UNLOCK", "lock or unlock a door or other thing in the room, with a key from your inventory if needed"},
+	{"OPEN/CLOSE", "open or close a door or other thing in the room"},
 	{"QUIT/BYE", "end the game"},
-	{"TAKE/GET", "pick up an object in the room"},
+	{"RESTART", "start the game over from the beginning"},
+	{"SAVE [name]", "save the game, to a save called 'name' if given"},
+	{"SAVES", "list all saved games"},
+	{"TAKE/GET [FROM something]", "pick up an object in the room, or from inside of something; several can be given with AND, or ALL [EXCEPT something]"},
 	{"TALK/SPEAK", "talk to someone/something in the room"},
-	{"USE", "use an object in your inventory [WIP]"},
+	{"UNDO", "take back the last turn"},
+	{"REDO", "redo the last turn taken back with UNDO"},
+	{"USE [WITH something]", "use an object in your inventory [WIP], with one or more other things joined with AND if given"},
+	{"WAIT/Z", "let a turn pass without doing anything"},
 }
 
 var textFormatOptions = rosed.Options{
@@ -73,6 +116,45 @@ type State struct {
 	// purposes.
 	detailLocations map[string]string
 
+	// visited is the labels of every room that the player has been in.
+	visited map[string]bool
+
+	// hookDepth is how many room hooks are currently being executed.
+	hookDepth int
+
+	// locks is the current state of every Lock in the world by its label.
+	locks map[string]*lockState
+
+	// lockLabels maps the labels of every Lock and of every exit, detail, and
+	// item that has one to the label of the Lock. It never changes after New.
+	lockLabels map[string]string
+
+	// containers is every item and detail that has a Container, by label.
+	containers map[string]containerHolder
+
+	// pathfinder finds the ways that NPCs walk to the rooms they are going
+	// to.
+	pathfinder Pathfinder
+
+	// turn is the number of turns that have passed.
+	turn int
+
+	// events is every Event in the world, in the order they are executed.
+	events []*Event
+
+	// flaggedAt maps the labels of Events scheduled by a flag to the turn
+	// that the flag was first found to be enabled. Events whose flag has not
+	// yet been are not included.
+	flaggedAt map[string]int
+
+	// verbs is every verb that the world defines, by name.
+	verbs map[string]*CustomVerb
+
+	// referents is every thing that the player has referred to, starting
+	// with the most recent, for use when the player refers to one of them by
+	// a pronoun.
+	referents []Targetable
+
 	// tsBufferOutput will send tunascript to tsBuf instead of to the io device
 	// if set to true. methods of *State can call this before executing
 	// tunascript to control exactly when it is output.
@@ -86,6 +168,21 @@ type State struct {
 	io IODevice
 
 	scripts tunascript.Interpreter
+
+	// seed is the value that randSrc was last seeded with.
+	seed int64
+
+	// randSrc is the source of all random decisions made in the game. Its
+	// state is saved with the rest of the game's progress.
+	randSrc *randSource
+
+	// rng draws random values from randSrc.
+	rng *rand.Rand
+
+	// pendingRestore is progress that was decoded with UnmarshalBinary into a
+	// State that has no world loaded. It is applied to a loaded State with
+	// Resume.
+	pendingRestore *savedState
 }
 
 type IODevice interface {
@@ -121,6 +218,9 @@ type IODevice interface {
 // io.Width is how wide the output should be. State will try to make all
 // output fit within this width. If not set or < 2, it will be automatically
 // assumed to be 80.
+//
+// The random source of the returned State is seeded with the current time;
+// call SetSeed to use a specific seed instead.
 func New(world map[string]*Room, startingRoom string, flags map[string]string, ioDev IODevice) (*State, error) {
 	if ioDev == nil {
 		return nil, fmt.Errorf("io device must not be nil")
@@ -137,17 +237,21 @@ func New(world map[string]*Room, startingRoom string, flags map[string]string, i
 		itemLocations:   make(map[string]string),
 		exitLocations:   make(map[string]string),
 		detailLocations: make(map[string]string),
+		visited:         make(map[string]bool),
+		flaggedAt:       make(map[string]int),
 		tsBuf:           &strings.Builder{},
 		io:              ioDev,
+		seed:            time.Now().UnixNano(),
 	}
+	gs.randSrc, gs.rng = newRandom(gs.seed)
+	gs.pathfinder = Pathfinder{World: world}
 
 	// first, go through and track all taggables
 	var taggedNPCs, taggedExits, taggedDetails, taggedItems []Targetable
 
 	for roomLabel := range gs.World {
 		r := gs.World[roomLabel]
-		for i := range r.Items {
-			item := r.Items[i]
+		for _, item := range r.AllItems() {
 			taggedItems = append(taggedItems, item)
 			for _, tag := range item.Tags {
 				tagged := gs.TagSets[tag]
@@ -155,7 +259,6 @@ func New(world map[string]*Room, startingRoom string, flags map[string]string, i
 				gs.TagSets[tag] = tagged
 			}
 			item.Tags = append(item.Tags, "@ITEM")
-			r.Items[i] = item
 		}
 		for i := range r.NPCs {
 			npc := r.NPCs[i]
@@ -203,12 +306,19 @@ func New(world map[string]*Room, startingRoom string, flags map[string]string, i
 	if !startExists {
 		return gs, fmt.Errorf("starting room with label %q does not exist in passed-in rooms", startingRoom)
 	}
+	gs.visited[startingRoom] = true
+
+	gs.initContainers()
+	gs.initLocks()
 
 	// read current targetable entity locations. for NPCs, prep them for movement
 	for _, r := range gs.World {
 		for _, npc := range r.NPCs {
 			npc.ResetRoute()
 			gs.npcLocations[npc.Label] = r.Label
+			for _, item := range npc.Inventory {
+				gs.itemLocations[item.Label] = npc.Label
+			}
 		}
 		for _, item := range r.Items {
 			gs.itemLocations[item.Label] = r.Label
@@ -220,6 +330,11 @@ func New(world map[string]*Room, startingRoom string, flags map[string]string, i
 			gs.exitLocations[eg.Label] = r.Label
 		}
 	}
+	for holderLabel, holder := range gs.containers {
+		for _, item := range holder.GetContainer().Items {
+			gs.itemLocations[item.Label] = holderLabel
+		}
+	}
 
 	// start scripting engine
 	gs.scripts = tunascript.Interpreter{
@@ -243,12 +358,49 @@ func New(world map[string]*Room, startingRoom string, flags map[string]string, i
 	return gs, nil
 }
 
+// movePlayer moves the player into dest, executing the OnExit hook of the room
+// they are leaving and then the OnFirstEnter and OnEnter hooks of dest. Output
+// from the hooks goes wherever tunascript output is currently being sent. If
+// too many hooks are already running, such as when the hooks of two rooms keep
+// moving the player between them, the player is moved without executing any.
+func (gs *State) movePlayer(dest *Room) {
+	if gs.hookDepth >= maxRoomHookDepth {
+		gs.CurrentRoom = dest
+		gs.visited[dest.Label] = true
+		return
+	}
+
+	gs.hookDepth++
+	defer func() { gs.hookDepth-- }()
+
+	gs.scripts.Exec(gs.CurrentRoom.OnExit)
+
+	gs.CurrentRoom = dest
+	firstVisit := !gs.visited[dest.Label]
+	gs.visited[dest.Label] = true
+
+	if firstVisit {
+		gs.scripts.Exec(dest.OnFirstEnter)
+	}
+
+	// a first-enter hook may have already moved the player somewhere else
+	if gs.CurrentRoom == dest {
+		gs.scripts.Exec(dest.OnEnter)
+	}
+}
+
 // MoveNPCs applies all movements on NPCs that are in the world whose If's
-// currently evaluate to true.
-func (gs *State) MoveNPCs() {
+// currently evaluate to true. The messages for every NPC that entered or left
+// the room the player is in are returned, each in its own paragraph. If no
+// NPC did, "" is returned.
+func (gs *State) MoveNPCs() string {
 	newLocs := map[string]string{}
+	var messages []string
 
-	for npcLabel, roomLabel := range gs.npcLocations {
+	// go in a consistent order so that NPCs make the same random choices
+	// every time for the same seed
+	for _, npcLabel := range util.OrderedKeys(gs.npcLocations) {
+		roomLabel := gs.npcLocations[npcLabel]
 		room := gs.World[roomLabel]
 		npc := room.NPCs[npcLabel]
 
@@ -257,22 +409,108 @@ func (gs *State) MoveNPCs() {
 		gs.scripts.RemoveFlag(FlagAsker)
 
 		if !isActive {
+			newLocs[npc.Label] = room.Label
 			continue
 		}
 
-		next := npc.NextRouteStep(room, &gs.scripts)
+		var next string
+		if dest, ok := gs.npcDestination(npc); ok {
+			next = gs.stepToward(npc, room, dest)
+		} else {
+			next = npc.NextRouteStep(room, &gs.scripts, gs.rng)
+		}
 
 		if next != "" {
 			nextRoom := gs.World[next]
 			nextRoom.NPCs[npc.Label] = npc
 			delete(room.NPCs, npc.Label)
 			newLocs[npc.Label] = nextRoom.Label
+
+			var msg string
+			if room == gs.CurrentRoom {
+				msg = gs.npcLeaveMessage(npc, room, nextRoom)
+			} else if nextRoom == gs.CurrentRoom {
+				msg = gs.npcEnterMessage(npc, room, nextRoom)
+			}
+			if msg != "" {
+				messages = append(messages, msg)
+			}
 		} else {
 			newLocs[npc.Label] = room.Label
 		}
 	}
 
 	gs.npcLocations = newLocs
+
+	return strings.Join(messages, "\n\n")
+}
+
+// npcDestination returns the label of the room that npc is walking toward,
+// either because it was sent there with $SEND() or because its Movement is
+// RouteGoto or RouteFollow. If it isn't walking toward a room, ok is false.
+func (gs *State) npcDestination(npc *NPC) (dest string, ok bool) {
+	if npc.sentTo != "" {
+		return npc.sentTo, true
+	}
+
+	switch npc.Movement.Action {
+	case RouteGoto:
+		return npc.Movement.Target, true
+	case RouteFollow:
+		return gs.CurrentRoom.Label, true
+	default:
+		return "", false
+	}
+}
+
+// stepToward returns the label of the room that npc, which is in room, should
+// go to next in order to reach dest. The shortest path through the world is
+// taken as long as the NPC can currently go through every exit on it;
+// otherwise, a new path is planned through only the exits that the NPC can
+// currently go through. If the NPC is already in dest or can't currently get
+// to it, "" is returned.
+func (gs *State) stepToward(npc *NPC, room *Room, dest string) string {
+	if room.Label == dest {
+		return ""
+	}
+
+	canUse := func(egress *Egress) bool {
+		if gs.lockBlocks(egress) != "" {
+			return false
+		}
+		gs.scripts.AddFlag(FlagAsker, npc.Label)
+		defer gs.scripts.RemoveFlag(FlagAsker)
+		return gs.scripts.Exec(egress.If).Bool()
+	}
+
+	path := gs.pathfinder.Dijkstra(room.Label, dest)
+	if len(path) > 1 && gs.pathIsOpen(path, canUse) {
+		return path[1]
+	}
+
+	path = gs.pathfinder.DijkstraThrough(room.Label, dest, canUse)
+	if len(path) > 1 {
+		return path[1]
+	}
+	return ""
+}
+
+// pathIsOpen returns whether every step of path, which is a list of room
+// labels, can be taken through an exit that canUse returns true for.
+func (gs *State) pathIsOpen(path []string, canUse func(egress *Egress) bool) bool {
+	for i := 0; i+1 < len(path); i++ {
+		open := false
+		for _, egress := range gs.World[path[i]].Exits {
+			if egress.DestLabel == path[i+1] && canUse(egress) {
+				open = true
+				break
+			}
+		}
+		if !open {
+			return false
+		}
+	}
+	return true
 }
 
 // Expand executes the given template text and turns it into the resulting text.
@@ -283,6 +521,12 @@ func (gs *State) Expand(s *tunascript.Template) string {
 	return expanded
 }
 
+// FlagValue gets the current value of the tunascript flag with the given label.
+// If no flag with that label is defined, the zero Value and false are returned.
+func (gs *State) FlagValue(label string) (tunascript.Value, bool) {
+	return gs.scripts.FlagValue(label)
+}
+
 // Look gets the look description as a single long string. It returns non-nil
 // error if there are issues retrieving it. If alias is empty, the room is
 // looked at. The returned string is not formatted except that any seperate
@@ -291,12 +535,14 @@ func (gs *State) Expand(s *tunascript.Template) string {
 func (gs *State) Look(alias string) (string, error) {
 	var desc string
 	if alias != "" {
-		lookTarget := gs.CurrentRoom.GetTargetable(alias, TagPlayer, &gs.scripts)
+		lookTarget, err := gs.findTarget(alias, false)
+		if err != nil {
+			return "", err
+		}
 		if lookTarget == nil {
 			return "", tqerrors.Interpreterf("I don't see any %q here", alias)
 		}
-
-		desc = gs.Expand(lookTarget.GetDescription())
+		return gs.lookAt(lookTarget, alias)
 	} else {
 		desc = gs.Expand(gs.CurrentRoom.tmplDescription)
 
@@ -341,25 +587,74 @@ func (gs *State) Look(alias string) (string, error) {
 	return desc, nil
 }
 
+// lookAt gets the look description of lookTarget, which the player referred to
+// by alias. It returns a non-nil error if the player can't look at it. The
+// returned string is not formatted except that any separate listings will be
+// separated by "\n\n".
+func (gs *State) lookAt(lookTarget Targetable, alias string) (string, error) {
+	item, isItem := lookTarget.(*Item)
+	if isItem {
+		if err := gs.checkItemHook(item.OnLook, fmt.Sprintf("You can't get a good look at the %s", item.Name)); err != nil {
+			return "", err
+		}
+	}
+
+	desc := gs.Expand(lookTarget.GetDescription())
+
+	if holder, ok := lookTarget.(containerHolder); ok && holder.GetContainer() != nil {
+		name := strings.ToLower(alias)
+		if isItem {
+			name = item.Name
+		}
+		desc += "\n\n" + gs.describeContents(holder, name)
+	}
+
+	if isItem {
+		if hookOutput := gs.runItemHook(item.OnLook); hookOutput != "" {
+			desc += "\n\n" + hookOutput
+		}
+	}
+
+	return desc, nil
+}
+
 // Advance advances the game state based on the given command. If there is a
 // problem executing the command, it is given in the error output and the game
 // state is not advanced. If it is, the result of the command is written to the
-// provided output stream.
+// provided output stream. If the verb of cmd was assumed from a misspelling,
+// what it was assumed to be is written first.
 //
 // Invalid commands will be returned as non-nil errors as opposed to writing
 // directly to the IO stream; the caller can decide whether to do this themself.
 //
 // Note that for this, QUIT is not considered a valid command is it would be on
-// a controlling engine to end the game state based on that.
+// a controlling engine to end the game state based on that. The same goes for
+// SAVE, LOAD, SAVES, and RESTART, which require a controlling engine to manage
+// saved games, for UNDO and REDO, which require a controlling engine to keep a
+// history of turns, and for AGAIN, which the controlling engine must replace
+// with the last command it ran.
 //
 // TODO: differentiate syntax errors from io errors
 func (gs *State) Advance(cmd command.Command) error {
 	var output string
 	var err error
 
+	// the player must know what was done if it isn't what they typed
+	if cmd.VerbAssumed {
+		if err := gs.io.Output("\n(assuming %s)\n", strings.ToLower(cmd.Verb)); err != nil {
+			return err
+		}
+	}
+
 	switch cmd.Verb {
 	case "QUIT":
 		return tqerrors.Interpreterf("I can't QUIT; I'm not being executed by a quitable engine")
+	case "SAVE", "LOAD", "SAVES", "RESTART":
+		return tqerrors.Interpreterf("I can't %s; I'm not being executed by an engine that can save games", cmd.Verb)
+	case "UNDO", "REDO":
+		return tqerrors.Interpreterf("I can't %s; I'm not being executed by an engine that keeps a turn history", cmd.Verb)
+	case "AGAIN":
+		return tqerrors.Interpreterf("I can't AGAIN; I'm not being executed by an engine that remembers the last command")
 	case "GO":
 		output, err = gs.ExecuteCommandGo(cmd)
 	case "EXITS":
@@ -376,18 +671,39 @@ func (gs *State) Advance(cmd command.Command) error {
 		output, err = gs.ExecuteCommandInventory(cmd)
 	case "TALK":
 		output, err = gs.ExecuteCommandTalk(cmd)
+	case "GIVE":
+		output, err = gs.ExecuteCommandGive(cmd)
+	case "SHOW":
+		output, err = gs.ExecuteCommandShow(cmd)
+	case "OPEN":
+		output, err = gs.ExecuteCommandOpen(cmd)
+	case "CLOSE":
+		output, err = gs.ExecuteCommandClose(cmd)
+	case "LOCK":
+		output, err = gs.ExecuteCommandLock(cmd)
+	case "UNLOCK":
+		output, err = gs.ExecuteCommandUnlock(cmd)
 	case "DEBUG":
 		output, err = gs.ExecuteCommandDebug(cmd)
+	case "WAIT":
+		output, err = gs.ExecuteCommandWait(cmd)
 	case "HELP":
 		output, err = gs.ExecuteCommandHelp(cmd)
 	default:
-		return tqerrors.Interpreterf("I don't know how to %q", cmd.Verb)
+		output, err = gs.ExecuteCommandCustom(cmd)
 	}
 
 	if err != nil {
 		return err
 	}
 
+	// asking for help and debugging the game happen outside of game time
+	if cmd.Verb != "HELP" && cmd.Verb != "DEBUG" {
+		if eventOutput := gs.passTurn(); eventOutput != "" {
+			output += "\n\n" + eventOutput
+		}
+	}
+
 	// IO to give output:
 	return gs.io.Output("\n" + output + "\n\n")
 }
@@ -397,12 +713,14 @@ func (gs *State) Advance(cmd command.Command) error {
 func (gs *State) ExecuteCommandUse(cmd command.Command) (string, error) {
 	// allToBeUsed
 	var useAliases []string
-	useAliases = append(useAliases, cmd.Recipient)
-
-	// TODO: in future when player command parser supports with multiple, bring
-	// in multiple stuff here. For now, just add the one and only other thing if
-	// it is present
-	if cmd.Instrument != "" {
+	if len(cmd.Recipients) > 0 {
+		useAliases = append(useAliases, cmd.Recipients...)
+	} else {
+		useAliases = append(useAliases, cmd.Recipient)
+	}
+	if len(cmd.Instruments) > 0 {
+		useAliases = append(useAliases, cmd.Instruments...)
+	} else if cmd.Instrument != "" {
 		useAliases = append(useAliases, cmd.Instrument)
 	}
 
@@ -410,16 +728,12 @@ func (gs *State) ExecuteCommandUse(cmd command.Command) (string, error) {
 	// in backpack)
 	useTargets := make([]Targetable, len(useAliases))
 	for i := range useAliases {
-		var tgt Targetable
-		tgtItem := gs.Inventory.GetItemByAlias(useAliases[i])
-		if tgtItem == nil {
-			// if not in invent, it could be in the room
-			tgt = gs.CurrentRoom.GetTargetable(useAliases[i], TagPlayer, &gs.scripts)
-			if tgt == nil {
-				return "", tqerrors.Interpreterf("I don't see any %q here or in your inventory", useAliases[i])
-			}
-		} else {
-			tgt = tgtItem
+		tgt, err := gs.findTarget(useAliases[i], true)
+		if err != nil {
+			return "", err
+		}
+		if tgt == nil {
+			return "", tqerrors.Interpreterf("I don't see any %q here or in your inventory", useAliases[i])
 		}
 
 		useTargets[i] = tgt
@@ -498,22 +812,37 @@ func (gs *State) ExecuteCommandGo(cmd command.Command) (string, error) {
 	if egress == nil {
 		return "", tqerrors.Interpreterf("%q isn't a place you can go from here", cmd.Recipient)
 	}
+	if blocked := gs.lockBlocks(egress); blocked != "" {
+		return "", tqerrors.Interpreterf("%s", blocked)
+	}
+
+	expanded := gs.Expand(egress.tmplTravelMessage)
 
-	gs.CurrentRoom = gs.World[egress.DestLabel]
+	// enable buffering so that $OUTPUT() in room hooks is shown between the
+	// travel message and the description of the new room
+	gs.tsBufferOutput = true
+	defer func() {
+		gs.tsBuf.Reset()
+		gs.tsBufferOutput = false
+	}()
 
-	gs.MoveNPCs()
+	gs.movePlayer(gs.World[egress.DestLabel])
 
 	lookText, err := gs.Look("")
 	if err != nil {
 		return "", err
 	}
 
-	expanded := gs.Expand(egress.tmplTravelMessage)
-
-	output := rosed.Edit(expanded).WithOptions(textFormatOptions).
+	ed := rosed.Edit(expanded).WithOptions(textFormatOptions).
 		Wrap(gs.io.Width()).
 		Insert(rosed.End, "\n\n").
-		CharsFrom(rosed.End).
+		CharsFrom(rosed.End)
+
+	if tsOutput := strings.TrimSpace(gs.tsBuf.String()); tsOutput != "" {
+		ed = ed.Insert(rosed.End, tsOutput+"\n\n")
+	}
+
+	output := ed.
 		Insert(rosed.End, lookText).
 		Wrap(gs.io.Width()).
 		String()
@@ -537,6 +866,13 @@ func (gs *State) ExecuteCommandExits(cmd command.Command) (string, error) {
 
 		for _, eg := range foundExits {
 			expanded := gs.Expand(eg.tmplDescription)
+			if eg.Lock != nil {
+				if ls := gs.locks[eg.Lock.Label]; ls.locked {
+					expanded += " (locked)"
+				} else if eg.Lock.Openable && !ls.open {
+					expanded += " (closed)"
+				}
+			}
 			ed = ed.Insert(rosed.End, "XX* "+eg.Aliases[0]+": "+expanded+"\n")
 		}
 
@@ -559,9 +895,21 @@ func (gs *State) ExecuteCommandExits(cmd command.Command) (string, error) {
 // ExecuteCommandTake executes the TAKE command with the arguments in the
 // provided Command and returns the output.
 func (gs *State) ExecuteCommandTake(cmd command.Command) (string, error) {
-	item := gs.CurrentRoom.GetItemByAlias(cmd.Recipient, TagPlayer, &gs.scripts)
-	if item == nil {
-		return "", tqerrors.Interpreterf("I don't see any %q here", cmd.Recipient)
+	if cmd.Instrument != "" {
+		return gs.takeFromContainer(cmd)
+	}
+
+	roomItems := gs.CurrentRoom.ItemsAvailable(TagPlayer, &gs.scripts)
+	return gs.forEachItem(cmd, roomItems, gs.findRoomItem, func(alias string) error {
+		return tqerrors.Interpreterf("I don't see any %q here", alias)
+	}, gs.takeItem)
+}
+
+// takeItem has the player pick up item from the ground in the current room and
+// returns the output.
+func (gs *State) takeItem(item *Item) (string, error) {
+	if err := gs.checkItemHook(item.OnTake, fmt.Sprintf("You can't pick up the %s", item.Name)); err != nil {
+		return "", err
 	}
 
 	// first remove the item from the room
@@ -573,15 +921,27 @@ func (gs *State) ExecuteCommandTake(cmd command.Command) (string, error) {
 	gs.itemLocations[item.Label] = "@INVEN"
 
 	output := fmt.Sprintf("You pick up the %s and add it to your inventory", item.Name)
+	if hookOutput := gs.runItemHook(item.OnTake); hookOutput != "" {
+		output = rosed.Edit(output + "\n\n" + hookOutput).WithOptions(textFormatOptions).Wrap(gs.io.Width()).String()
+	}
 	return output, nil
 }
 
 // ExecuteCommandDrop executes the DROP command with the arguments in the
 // provided Command and returns the output.
 func (gs *State) ExecuteCommandDrop(cmd command.Command) (string, error) {
-	item := gs.Inventory.GetItemByAlias(cmd.Recipient)
-	if item == nil {
-		return "", tqerrors.Interpreterf("You don't have a %q", cmd.Recipient)
+	if cmd.Instrument != "" {
+		return gs.putInContainer(cmd)
+	}
+
+	return gs.forEachItem(cmd, gs.inventoryItems(), gs.findInventoryItem, notCarried, gs.dropItem)
+}
+
+// dropItem has the player drop item from their inventory onto the ground in the
+// current room and returns the output.
+func (gs *State) dropItem(item *Item) (string, error) {
+	if err := gs.checkItemHook(item.OnDrop, fmt.Sprintf("You can't bring yourself to drop the %s", item.Name)); err != nil {
+		return "", err
 	}
 
 	// first remove item from inven
@@ -592,13 +952,151 @@ func (gs *State) ExecuteCommandDrop(cmd command.Command) (string, error) {
 	gs.itemLocations[item.Label] = gs.CurrentRoom.Label
 
 	output := fmt.Sprintf("You drop the %s onto the ground", item.Name)
+	if hookOutput := gs.runItemHook(item.OnDrop); hookOutput != "" {
+		output = rosed.Edit(output + "\n\n" + hookOutput).WithOptions(textFormatOptions).Wrap(gs.io.Width()).String()
+	}
+	return output, nil
+}
+
+// notCarried returns the error for showing to the player when they refer to
+// something by alias that isn't in their inventory.
+func notCarried(alias string) error {
+	return tqerrors.Interpreterf("You don't have a %q", alias)
+}
+
+// forEachItem runs act on every item that the recipients of cmd refer to and
+// returns the output. Items are looked up with find, and notFound gives the
+// error for a recipient that find doesn't find. If the recipient is given as
+// ALL, act is instead run on every item in candidates that isn't left out
+// with EXCEPT.
+//
+// If there is only one recipient, the output and error of act are returned as
+// they are. Otherwise, the result for each item is given in its own paragraph
+// whether it is an error or not, and a non-nil error is only returned if act
+// failed for every item.
+func (gs *State) forEachItem(cmd command.Command, candidates []*Item, find func(alias string) (*Item, error), notFound func(alias string) error, act func(item *Item) (string, error)) (string, error) {
+	if !cmd.All && len(cmd.Recipients) < 2 {
+		item, err := find(cmd.Recipient)
+		if err != nil {
+			return "", err
+		}
+		if item == nil {
+			return "", notFound(cmd.Recipient)
+		}
+		return act(item)
+	}
+
+	var results []string
+	succeeded := false
+	record := func(output string, err error) {
+		if err != nil {
+			results = append(results, tqerrors.GameMessage(err))
+		} else {
+			results = append(results, output)
+			succeeded = true
+		}
+	}
+
+	if cmd.All {
+		for _, it := range candidates {
+			excepted := false
+			for _, alias := range cmd.Except {
+				if referredToBy(it, alias) {
+					excepted = true
+					break
+				}
+			}
+			if !excepted {
+				record(act(it))
+			}
+		}
+		if len(results) < 1 {
+			return "", tqerrors.Interpreterf("There isn't anything to %s", strings.ToLower(cmd.Verb))
+		}
+	} else {
+		for _, alias := range cmd.Recipients {
+			item, err := find(alias)
+			if err == nil && item == nil {
+				err = notFound(alias)
+			}
+			if err != nil {
+				record("", err)
+				continue
+			}
+			record(act(item))
+		}
+	}
+
+	output := rosed.Edit(strings.Join(results, "\n\n")).WithOptions(textFormatOptions).Wrap(gs.io.Width()).String()
+	if !succeeded {
+		return "", tqerrors.Interpreterf("%s", output)
+	}
 	return output, nil
 }
 
+// checkItemHook evaluates the If of hook to see whether the action it is for
+// is allowed. If it is not, the returned error gives the refusal message of
+// the hook, or defaultRefusal if it does not have one.
+func (gs *State) checkItemHook(hook ItemHook, defaultRefusal string) error {
+	if len(hook.If.Nodes) < 1 || gs.scripts.Exec(hook.If).Bool() {
+		return nil
+	}
+
+	refusal := defaultRefusal
+	if hook.tmplRefusal != nil {
+		if expanded := strings.TrimSpace(gs.Expand(hook.tmplRefusal)); expanded != "" {
+			refusal = expanded
+		}
+	}
+	return tqerrors.Interpreterf("%s", refusal)
+}
+
+// runItemHook executes the Do of hook and returns anything that it output with
+// $OUTPUT().
+func (gs *State) runItemHook(hook ItemHook) string {
+	// enable buffering so any output doesn't just go directly to gs before we
+	// get a chance to write any other output
+	gs.tsBufferOutput = true
+	defer func() {
+		gs.tsBuf.Reset()
+		gs.tsBufferOutput = false
+	}()
+
+	gs.scripts.Exec(hook.Do)
+
+	return strings.TrimSpace(gs.tsBuf.String())
+}
+
 // ExecuteCommandDrop executes the LOOK command with the arguments in the
 // provided Command and returns the output.
 func (gs *State) ExecuteCommandLook(cmd command.Command) (string, error) {
-	output, err := gs.Look(cmd.Recipient)
+	// find what is being looked at here instead of leaving it to Look so that
+	// the player is only asked once if it's ambiguous
+	var tgt Targetable
+	alias := cmd.Recipient
+	if alias != "" {
+		var err error
+		tgt, err = gs.findTarget(alias, false)
+		if err != nil {
+			return "", err
+		}
+		if tgt == nil {
+			return "", tqerrors.Interpreterf("I don't see any %q here", alias)
+		}
+
+		// say what "IT" or a misspelling actually is
+		if !referredToBy(tgt, alias) {
+			alias = tgt.GetAliases()[0]
+		}
+	}
+
+	var output string
+	var err error
+	if tgt == nil {
+		output, err = gs.Look("")
+	} else {
+		output, err = gs.lookAt(tgt, alias)
+	}
 	if err != nil {
 		return "", err
 	}
@@ -609,14 +1107,12 @@ func (gs *State) ExecuteCommandLook(cmd command.Command) (string, error) {
 		ed = ed.Insert(rosed.End, "You check your surroundings.\n\n")
 	} else {
 		// is this an NPC? don't use 'the' with them
-		tgt := gs.CurrentRoom.GetTargetable(cmd.Recipient, TagPlayer, &gs.scripts)
-
 		theText := "the"
 		if IsNPC(tgt) {
 			theText = ""
 		}
 
-		ed = ed.Insert(rosed.End, fmt.Sprintf("You examine %s %s.\n\n", theText, cmd.Recipient))
+		ed = ed.Insert(rosed.End, fmt.Sprintf("You examine %s %s.\n\n", theText, alias))
 	}
 
 	output = ed.
@@ -653,7 +1149,10 @@ func (gs *State) ExecuteCommandInventory(cmd command.Command) (string, error) {
 // loop that will not exit until the conversation is PAUSED or an END step is
 // reached in it.
 func (gs *State) ExecuteCommandTalk(cmd command.Command) (string, error) {
-	npc := gs.CurrentRoom.GetNPCByAlias(cmd.Recipient, TagPlayer, &gs.scripts)
+	npc, err := gs.findRoomNPC(cmd.Recipient)
+	if err != nil {
+		return "", err
+	}
 	if npc == nil {
 		return "", tqerrors.Interpreterf("I don't see a %q you can talk to here.", cmd.Recipient)
 	}
@@ -662,7 +1161,7 @@ func (gs *State) ExecuteCommandTalk(cmd command.Command) (string, error) {
 		npc.Convo = &Conversation{Dialog: npc.Dialog}
 	}
 
-	err := gs.RunConversation(npc)
+	err = gs.RunConversation(npc)
 	if err != nil {
 		return "", err
 	}
@@ -693,12 +1192,22 @@ func (gs *State) ExecuteCommandDebug(cmd command.Command) (string, error) {
 // ExecuteCommandHelp executes the HELP command with the arguments in the
 // provided Command and returns the output.
 func (gs *State) ExecuteCommandHelp(cmd command.Command) (string, error) {
+	helpTable := append([][2]string{}, commandHelp...)
+	for _, name := range util.OrderedKeys(gs.verbs) {
+		v := gs.verbs[name]
+		if v.Help == "" {
+			continue
+		}
+		helpTable = append(helpTable, [2]string{strings.Join(append([]string{v.Def.Name}, v.Def.Aliases...), "/"), v.Help})
+	}
+
 	output := rosed.Edit("").WithOptions(
 		textFormatOptions.
 			WithParagraphSeparator("\n").
 			WithNoTrailingLineSeparators(true)).
 		Insert(rosed.End, "Here are the commands you can use (WIP commands do not yet work fully):\n").
-		InsertDefinitionsTable(rosed.End, commandHelp, gs.io.Width()).String()
+		InsertDefinitionsTable(rosed.End, helpTable, gs.io.Width()).
+		Insert(rosed.End, "\n\nSeveral commands can be given at once by separating them with periods or THEN.").String()
 
 	return output, nil
 }
@@ -716,6 +1225,9 @@ func (gs *State) preParseAllTunascriptTemplates() error {
 			return fmt.Errorf("room %q: description: %w", r.Label, err)
 		}
 		r.tmplDescription = preComp
+		if err := gs.preParseVerbActions(r.OnVerb); err != nil {
+			return fmt.Errorf("room %q: %w", r.Label, err)
+		}
 
 		// compute room exit descs and messages
 		for i := range r.Exits {
@@ -745,13 +1257,15 @@ func (gs *State) preParseAllTunascriptTemplates() error {
 				return fmt.Errorf("room %q: detail %d: description: %w", r.Label, i, err)
 			}
 			det.tmplDescription = detComp
+			if err := gs.preParseVerbActions(det.OnVerb); err != nil {
+				return fmt.Errorf("room %q: detail %d: %w", r.Label, i, err)
+			}
 
 			r.Details[i] = det
 		}
 
 		// compute item descs
-		for i := range r.Items {
-			it := r.Items[i]
+		for _, it := range r.AllItems() {
 
 			itemComp, err := gs.preParseTemplate(it.Description)
 			if err != nil {
@@ -759,7 +1273,19 @@ func (gs *State) preParseAllTunascriptTemplates() error {
 			}
 			it.tmplDescription = itemComp
 
-			r.Items[i] = it
+			hooks := map[string]*ItemHook{"on_take": &it.OnTake, "on_drop": &it.OnDrop, "on_look": &it.OnLook}
+			for _, hookName := range util.OrderedKeys(hooks) {
+				hook := hooks[hookName]
+				refusalComp, err := gs.preParseTemplate(hook.Refusal)
+				if err != nil {
+					return fmt.Errorf("item %q: %s: refusal: %w", it.Label, hookName, err)
+				}
+				hook.tmplRefusal = refusalComp
+			}
+
+			if err := gs.preParseVerbActions(it.OnVerb); err != nil {
+				return fmt.Errorf("item %q: %w", it.Label, err)
+			}
 		}
 
 		// compute NPC descs
@@ -773,6 +1299,17 @@ func (gs *State) preParseAllTunascriptTemplates() error {
 			}
 			npc.tmplDescription = npcComp
 
+			enterComp, err := gs.preParseTemplate(npc.EnterMessage)
+			if err != nil {
+				return fmt.Errorf("npc %q: enter message: %w", npc.Label, err)
+			}
+			npc.tmplEnterMessage = enterComp
+			leaveComp, err := gs.preParseTemplate(npc.LeaveMessage)
+			if err != nil {
+				return fmt.Errorf("npc %q: leave message: %w", npc.Label, err)
+			}
+			npc.tmplLeaveMessage = leaveComp
+
 			// no need to re-assign to map bc npc is a ptr-to so mutations are
 			// reflected in map
 
@@ -801,12 +1338,43 @@ func (gs *State) preParseAllTunascriptTemplates() error {
 				dia.tmplContent = diaContentComp
 				dia.tmplChoices = diaChoiceComps
 			}
+
+			// and each give and show reaction's response
+			for i := range npc.OnGive {
+				respComp, err := gs.preParseTemplate(npc.OnGive[i].Response)
+				if err != nil {
+					return fmt.Errorf("npc %q: on_give %d: response: %w", npc.Label, i, err)
+				}
+				npc.OnGive[i].tmplResponse = respComp
+			}
+			for i := range npc.OnShow {
+				respComp, err := gs.preParseTemplate(npc.OnShow[i].Response)
+				if err != nil {
+					return fmt.Errorf("npc %q: on_show %d: response: %w", npc.Label, i, err)
+				}
+				npc.OnShow[i].tmplResponse = respComp
+			}
+			if err := gs.preParseVerbActions(npc.OnVerb); err != nil {
+				return fmt.Errorf("npc %q: %w", npc.Label, err)
+			}
 		}
 	}
 
 	return nil
 }
 
+// preParseVerbActions sets the precomputed response text of each of actions.
+func (gs *State) preParseVerbActions(actions []VerbAction) error {
+	for i := range actions {
+		respComp, err := gs.preParseTemplate(actions[i].Response)
+		if err != nil {
+			return fmt.Errorf("on_verb %d: response: %w", i, err)
+		}
+		actions[i].tmplResponse = respComp
+	}
+	return nil
+}
+
 func (gs *State) preParseTemplate(toExpand string) (*tunascript.Template, error) {
 	preComp, err := gs.scripts.ParseTemplate(toExpand)
 	if err != nil {
@@ -971,12 +1539,3 @@ func (gs *State) concreteMatchesLatagList(concrete []string, latags []string) bo
 
 	return false
 }
-
-func (g State) MarshalBinary() (data []byte, err error) {
-	return []byte{0x00, 0x01}, nil
-}
-
-func (g *State) UnmarshalBinary(data []byte) error {
-	*g = State{}
-	return nil
-}
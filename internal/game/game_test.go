@@ -0,0 +1,224 @@
+package game
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/dekarrin/tunaq/internal/command"
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+	"github.com/dekarrin/tunaq/tunascript"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopIODevice struct {
+	width int
+}
+
+func (nop *nopIODevice) Width() int                              { return nop.width }
+func (nop *nopIODevice) SetWidth(w int)                          { nop.width = w }
+func (nop *nopIODevice) Output(s string, a ...interface{}) error { return nil }
+func (nop *nopIODevice) Input(prompt string) (string, error)     { return "", nil }
+func (nop *nopIODevice) InputInt(prompt string) (int, error)     { return 0, nil }
+
+// answeringIODevice is an IODevice that answers every prompt with the next one
+// of answers and records all output. Once there are no answers left, text
+// prompts are answered with a blank line and number prompts give an error.
+type answeringIODevice struct {
+	nopIODevice
+	answers []string
+	out     strings.Builder
+}
+
+func (aio *answeringIODevice) Output(s string, a ...interface{}) error {
+	aio.out.WriteString(fmt.Sprintf(s, a...))
+	return nil
+}
+
+func (aio *answeringIODevice) Input(prompt string) (string, error) {
+	if len(aio.answers) < 1 {
+		return "", nil
+	}
+	next := aio.answers[0]
+	aio.answers = aio.answers[1:]
+	return next, nil
+}
+
+func (aio *answeringIODevice) InputInt(prompt string) (int, error) {
+	if len(aio.answers) < 1 {
+		return 0, fmt.Errorf("no answer for %q", prompt)
+	}
+	answer, _ := aio.Input(prompt)
+	return strconv.Atoi(answer)
+}
+
+func mustParseScript(code string) tunascript.AST {
+	ast, err := tunascript.Parse(code, "")
+	if err != nil {
+		panic(err)
+	}
+	return ast
+}
+
+// worldBuilder builds worlds for tests. Everything added to it can be seen and
+// used unless its If is changed.
+type worldBuilder map[string]*Room
+
+// newWorld returns a worldBuilder with an empty room for each of the given
+// labels.
+func newWorld(rooms ...string) worldBuilder {
+	world := worldBuilder{}
+	for _, label := range rooms {
+		world[label] = &Room{Label: label, Name: "the " + strings.ToLower(label), NPCs: map[string]*NPC{}}
+	}
+	return world
+}
+
+// exit adds an exit from one room to another that can be referred to by the
+// label of the room it goes to, and returns it.
+func (world worldBuilder) exit(from, to string) *Egress {
+	egress := &Egress{Label: from + "_TO_" + to, DestLabel: to, Aliases: []string{to}, If: tunascript.ReturnTrue}
+	world[from].Exits = append(world[from].Exits, egress)
+	return egress
+}
+
+// item adds an item to a room that can be referred to by its label, and
+// returns it.
+func (world worldBuilder) item(room, label string) *Item {
+	it := &Item{Label: label, Name: strings.ToLower(label), Aliases: []string{label}, If: tunascript.ReturnTrue}
+	world[room].Items = append(world[room].Items, it)
+	return it
+}
+
+// npc adds an NPC to a room that can be referred to by its label, and returns
+// it.
+func (world worldBuilder) npc(room, label string) *NPC {
+	npc := &NPC{Label: label, Name: "the " + strings.ToLower(label), Aliases: []string{label}, If: tunascript.ReturnTrue}
+	world[room].NPCs[label] = npc
+	return npc
+}
+
+// detail adds a detail to a room that can be referred to by its label, and
+// returns it.
+func (world worldBuilder) detail(room, label string) *Detail {
+	detail := &Detail{Label: label, Aliases: []string{label}, If: tunascript.ReturnTrue}
+	world[room].Details = append(world[room].Details, detail)
+	return detail
+}
+
+// testWorld is a kitchen and a hall that lead to each other. The kitchen has a
+// spoon, a fork, and a chef to talk to.
+func testWorld() worldBuilder {
+	world := newWorld("KITCHEN", "HALL")
+	world.exit("KITCHEN", "HALL")
+	world.exit("HALL", "KITCHEN")
+	world.item("KITCHEN", "SPOON")
+	world.item("KITCHEN", "FORK")
+	world.npc("KITCHEN", "CHEF").Dialog = []*DialogStep{
+		{Action: DialogLine, Content: "hello"},
+		{Action: DialogLine, Content: "goodbye"},
+	}
+	return world
+}
+
+// playCommands parses each of cmds with the default vocabulary and the custom
+// verbs of gs and gives it to gs with Advance. If any command can't be parsed
+// or gives an error, the rest are not given and the error is returned.
+func playCommands(gs *State, cmds ...string) error {
+	var defs []command.VerbDef
+	for _, cv := range gs.verbs {
+		defs = append(defs, cv.Def)
+	}
+	vocab := command.NewVocabulary()
+	vocab.SetCustomVerbs(defs)
+
+	for _, text := range cmds {
+		cmd, err := vocab.Parse(text)
+		if err != nil {
+			return err
+		}
+		if err := gs.Advance(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assertScripts asserts that every one of the TunaScript expressions in
+// expectTrue is true in gs.
+func assertScripts(assert *assert.Assertions, gs *State, expectTrue []string) {
+	for _, code := range expectTrue {
+		assert.True(gs.scripts.Exec(mustParseScript(code)).Bool(), code)
+	}
+}
+
+// commandTest is a test of what a command does when given to a new game.
+type commandTest struct {
+	name string
+
+	// setup is the commands given before cmd.
+	setup []string
+
+	cmd string
+
+	// answers is what the player answers the prompts during cmd with, in
+	// order.
+	answers []string
+
+	// expectOutput is text that the output of cmd must contain.
+	expectOutput string
+
+	// expectNotOutput is text that the output of cmd must not contain. It is
+	// not checked if empty.
+	expectNotOutput string
+
+	// expectErr is the game message of the error that cmd must give. If
+	// empty, cmd must not give one.
+	expectErr string
+
+	// expectTrue is TunaScript expressions that must be true afterward.
+	expectTrue []string
+
+	// expectNPCs maps the labels of NPCs to the rooms they must be in
+	// afterward.
+	expectNPCs map[string]string
+}
+
+// runCommandTests runs each of tests as a subtest of t. Each one gets its own
+// game from newGame, which must use the given IODevice.
+func runCommandTests(t *testing.T, newGame func(ioDev IODevice) (*State, error), tests []commandTest) {
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			aio := &answeringIODevice{}
+			gs, err := newGame(aio)
+			if !assert.NoError(err) {
+				return
+			}
+			if !assert.NoError(playCommands(gs, tc.setup...)) {
+				return
+			}
+
+			aio.answers = tc.answers
+			aio.out.Reset()
+			err = playCommands(gs, tc.cmd)
+			if tc.expectErr != "" {
+				assert.Equal(tc.expectErr, tqerrors.GameMessage(err))
+			} else {
+				assert.NoError(err)
+			}
+
+			out := aio.out.String()
+			assert.Contains(out, tc.expectOutput)
+			if tc.expectNotOutput != "" {
+				assert.NotContains(out, tc.expectNotOutput)
+			}
+			assertScripts(assert, gs, tc.expectTrue)
+			for npc, room := range tc.expectNPCs {
+				assert.Equal(room, gs.npcLocations[npc], npc)
+			}
+		})
+	}
+}
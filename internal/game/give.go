@@ -0,0 +1,103 @@
+package game
+
+// File give.go contains symbols for giving and showing items to NPCs.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/rosed"
+	"github.com/dekarrin/tunaq/internal/command"
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+)
+
+// getGiveTargets returns the item in the player's inventory and the NPC in the
+// current room that a GIVE or SHOW command refers to. If either can't be
+// found, a non-nil error for showing to the player is returned.
+func (gs *State) getGiveTargets(cmd command.Command) (*Item, *NPC, error) {
+	item, err := gs.findInventoryItem(cmd.Recipient)
+	if err != nil {
+		return nil, nil, err
+	}
+	if item == nil {
+		return nil, nil, tqerrors.Interpreterf("You don't have a %q", cmd.Recipient)
+	}
+
+	npc, err := gs.findRoomNPC(cmd.Instrument)
+	if err != nil {
+		return nil, nil, err
+	}
+	if npc == nil {
+		return nil, nil, tqerrors.Interpreterf("I don't see a %q you can %s things to here", cmd.Instrument, strings.ToLower(cmd.Verb))
+	}
+
+	return item, npc, nil
+}
+
+// runGiveAction executes the Do of act and returns output that starts with msg
+// and then gives the response of act and anything that was output with
+// $OUTPUT().
+func (gs *State) runGiveAction(act *GiveAction, msg string) string {
+	paragraphs := append([]string{msg}, gs.runReaction(act)...)
+	return rosed.Edit(strings.Join(paragraphs, "\n\n")).WithOptions(textFormatOptions).Wrap(gs.io.Width()).String()
+}
+
+// refusal returns the response of act if it has one, or defaultRefusal if it
+// does not.
+func (gs *State) refusal(act *GiveAction, defaultRefusal string) string {
+	if act != nil && act.tmplResponse != nil {
+		if response := strings.TrimSpace(gs.Expand(act.tmplResponse)); response != "" {
+			return response
+		}
+	}
+	return defaultRefusal
+}
+
+// ExecuteCommandGive executes the GIVE command with the arguments in the
+// provided Command and returns the output.
+func (gs *State) ExecuteCommandGive(cmd command.Command) (string, error) {
+	item, npc, err := gs.getGiveTargets(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	act := findReaction(gs, npc.OnGive, item.Label)
+	if act == nil || act.Refuse {
+		doesnt := "doesn't"
+		if npc.Pronouns.Plural {
+			doesnt = "don't"
+		}
+		defaultRefusal := fmt.Sprintf("You offer the %s to %s, but %s %s want it", item.Name, npc.Name, strings.ToLower(npc.Pronouns.Nominative), doesnt)
+		return "", tqerrors.Interpreterf("%s", gs.refusal(act, defaultRefusal))
+	}
+
+	if err := gs.checkItemHook(item.OnDrop, fmt.Sprintf("You can't bring yourself to give away the %s", item.Name)); err != nil {
+		return "", err
+	}
+
+	gs.removeItem(item.Label)
+	gs.placeItem(item, npc.Label)
+
+	return gs.runGiveAction(act, fmt.Sprintf("You give the %s to %s", item.Name, npc.Name)), nil
+}
+
+// ExecuteCommandShow executes the SHOW command with the arguments in the
+// provided Command and returns the output.
+func (gs *State) ExecuteCommandShow(cmd command.Command) (string, error) {
+	item, npc, err := gs.getGiveTargets(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	act := findReaction(gs, npc.OnShow, item.Label)
+	if act == nil || act.Refuse {
+		doesnt := "doesn't"
+		if npc.Pronouns.Plural {
+			doesnt = "don't"
+		}
+		defaultRefusal := fmt.Sprintf("You show the %s to %s, but %s %s seem interested", item.Name, npc.Name, strings.ToLower(npc.Pronouns.Nominative), doesnt)
+		return "", tqerrors.Interpreterf("%s", gs.refusal(act, defaultRefusal))
+	}
+
+	return gs.runGiveAction(act, fmt.Sprintf("You show the %s to %s", item.Name, npc.Name)), nil
+}
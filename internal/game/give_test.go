@@ -0,0 +1,69 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// giveWorld is testWorld with a chef who wants the spoon, won't take anything
+// else, and starts out holding a ladle.
+func giveWorld() worldBuilder {
+	world := testWorld()
+
+	chef := world["KITCHEN"].NPCs["CHEF"]
+	chef.Pronouns = PronounsFeminine
+	chef.Inventory = Inventory{
+		"LADLE": {Label: "LADLE", Name: "ladle", Aliases: []string{"LADLE"}, If: tunascript.ReturnTrue},
+	}
+	chef.OnGive = []GiveAction{
+		{Refuse: true},
+		{With: []string{"SPOON"}, Response: "Just what I needed."},
+	}
+	chef.OnShow = []GiveAction{
+		{With: []string{"FORK"}, Response: "Nice fork."},
+	}
+
+	return world
+}
+
+func Test_State_give(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		return New(giveWorld(), "KITCHEN", nil, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:       "NPC starts with inventory",
+			cmd:        "look at chef",
+			expectTrue: []string{"$NPC_HAS(CHEF, LADLE)"},
+		},
+		{
+			name:       "item not in inventory",
+			cmd:        "give spoon to chef",
+			expectErr:  "You don't have a \"SPOON\"",
+			expectTrue: []string{"$NOT($NPC_HAS(CHEF, SPOON))"},
+		},
+		{
+			name:         "label match is used over catch-all refusal",
+			setup:        []string{"take spoon"},
+			cmd:          "give spoon to chef",
+			expectOutput: "Just what I needed.",
+			expectTrue:   []string{"$NPC_HAS(CHEF, SPOON)", "$NOT($IN_INVEN(SPOON))"},
+		},
+		{
+			name:       "refused",
+			setup:      []string{"take fork"},
+			cmd:        "give fork to chef",
+			expectErr:  "You offer the fork to the chef, but she doesn't want it",
+			expectTrue: []string{"$IN_INVEN(FORK)", "$NOT($NPC_HAS(CHEF, FORK))"},
+		},
+		{
+			name:         "showing doesn't move the item",
+			setup:        []string{"take fork"},
+			cmd:          "show fork to chef",
+			expectOutput: "Nice fork.",
+			expectTrue:   []string{"$IN_INVEN(FORK)", "$NOT($NPC_HAS(CHEF, FORK))"},
+		},
+	})
+}
@@ -39,10 +39,9 @@ func (inv Inventory) GetItemByAlias(alias string) *Item {
 }
 
 // Item is an object that can be picked up. It contains a unique label, a
-// description, and aliases that it can be referred to by. All aliases SHOULD be
-// unique in case an item is dropped with another, but as long as at least ONE
-// alias is present, we can handle the ambiguous case by asking player to
-// restate.
+// description, and aliases that it can be referred to by. Aliases need not be
+// unique; if the player uses one that refers to more than one item, they are
+// asked which one they meant.
 type Item struct {
 	// Tags is a list of all tags that will include this Item. Each tag
 	// includes the leading @-sign. All items are also implicitly included by
@@ -66,6 +65,11 @@ type Item struct {
 	// explicitly given.
 	Aliases []string
 
+	// Adjectives are words that the player can put in front of any of the
+	// Aliases to tell the item apart from others with the same alias, such as
+	// "RED" for "RED KEY". They are all upper case.
+	Adjectives []string
+
 	// If is the tunascript that is evaluated to determine if this item is
 	// interactable and visible to the user. If IfRaw is empty, this will be an
 	// expression that always returns true.
@@ -81,6 +85,29 @@ type Item struct {
 	// must be true.
 	OnUse []UseAction
 
+	// OnTake is executed when the player picks up the item. Its If can prevent
+	// the item from being taken.
+	OnTake ItemHook
+
+	// OnDrop is executed when the player drops the item. Its If can prevent
+	// the item from being dropped.
+	OnDrop ItemHook
+
+	// OnLook is executed when the player LOOKs at the item. Its If can prevent
+	// the player from looking at it.
+	OnLook ItemHook
+
+	// OnVerb is how the item reacts to the player using verbs that the world
+	// defines on it. The first most specific one that matches is used.
+	OnVerb []VerbAction
+
+	// Lock is how the item can be opened and locked. It is nil if it can't be.
+	Lock *Lock
+
+	// Container is the items inside the item. It is nil if the item can't hold
+	// items.
+	Container *Container
+
 	// tmplDescription is the precomputed template AST for the description text.
 	// It must generally be filled in with the game engine, and will not be
 	// present directly when loaded from disk.
@@ -98,18 +125,33 @@ func (item Item) Copy() Item {
 		Name:        item.Name,
 		Description: item.Description,
 		Aliases:     make([]string, len(item.Aliases)),
+		Adjectives:  make([]string, len(item.Adjectives)),
 		Tags:        make([]string, len(item.Tags)),
 		If:          item.If,
 		IfRaw:       item.IfRaw,
 		OnUse:       make([]UseAction, len(item.OnUse)),
+		OnTake:      item.OnTake.Copy(),
+		OnDrop:      item.OnDrop.Copy(),
+		OnLook:      item.OnLook.Copy(),
+		OnVerb:      copyVerbActions(item.OnVerb),
 
 		tmplDescription: item.tmplDescription,
 	}
 
 	copy(iCopy.Aliases, item.Aliases)
+	copy(iCopy.Adjectives, item.Adjectives)
 	copy(iCopy.Tags, item.Tags)
 	copy(iCopy.OnUse, item.OnUse)
 
+	if item.Lock != nil {
+		lockCopy := item.Lock.Copy()
+		iCopy.Lock = &lockCopy
+	}
+	if item.Container != nil {
+		containerCopy := item.Container.Copy()
+		iCopy.Container = &containerCopy
+	}
+
 	return iCopy
 }
 
@@ -121,6 +163,14 @@ func (item Item) GetDescription() *tunascript.Template {
 	return item.tmplDescription
 }
 
+func (item Item) GetLock() *Lock {
+	return item.Lock
+}
+
+func (item Item) GetContainer() *Container {
+	return item.Container
+}
+
 func (item Item) GetLabel() string {
 	return item.Label
 }
@@ -0,0 +1,85 @@
+package game
+
+import "testing"
+
+func Test_State_itemHooks(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		world := testWorld()
+		spoon := world["KITCHEN"].Items[0]
+		spoon.Description = "A shiny spoon."
+		spoon.OnTake = ItemHook{
+			If:      mustParseScript("$FLAG_ENABLED(SPOON_LOOSE)"),
+			Refusal: "The spoon is glued to the counter.",
+			Do:      mustParseScript("$OUTPUT(@It is sticky.@)"),
+		}
+		spoon.OnDrop = ItemHook{If: mustParseScript("$FLAG_DISABLED(CURSED)")}
+		spoon.OnLook = ItemHook{Do: mustParseScript("$ENABLE(SPOON_LOOSE)")}
+		return New(world, "KITCHEN", map[string]string{"CURSED": "true"}, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:       "take refused",
+			cmd:        "take spoon",
+			expectErr:  "The spoon is glued to the counter.",
+			expectTrue: []string{"$NOT($IN_INVEN(SPOON))"},
+		},
+		{
+			name:         "look runs do",
+			cmd:          "look at spoon",
+			expectOutput: "A shiny spoon.",
+			expectTrue:   []string{"$SPOON_LOOSE"},
+		},
+		{
+			name:         "take allowed",
+			setup:        []string{"look at spoon"},
+			cmd:          "take spoon",
+			expectOutput: "It is sticky.",
+			expectTrue:   []string{"$IN_INVEN(SPOON)"},
+		},
+		{
+			name:       "drop refused with default refusal",
+			setup:      []string{"look at spoon", "take spoon"},
+			cmd:        "drop spoon",
+			expectErr:  "You can't bring yourself to drop the spoon",
+			expectTrue: []string{"$IN_INVEN(SPOON)"},
+		},
+		{
+			name:       "item without hooks",
+			cmd:        "take fork",
+			expectTrue: []string{"$IN_INVEN(FORK)"},
+		},
+	})
+}
+
+func Test_State_takeAndDropMany(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		return New(testWorld(), "KITCHEN", nil, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:         "missing object doesn't stop the others",
+			cmd:          "take spoon and knife",
+			expectOutput: "You pick up the spoon and add it to your inventory\n\nI don't see any \"KNIFE\" here",
+			expectTrue:   []string{"$IN_INVEN(SPOON)"},
+		},
+		{
+			name:       "take all",
+			cmd:        "take all",
+			expectTrue: []string{"$IN_INVEN(SPOON)", "$IN_INVEN(FORK)"},
+		},
+		{
+			name:      "take all with nothing left",
+			setup:     []string{"take all"},
+			cmd:       "take all",
+			expectErr: "There isn't anything to take",
+		},
+		{
+			name:       "drop all except",
+			setup:      []string{"take all"},
+			cmd:        "drop all except fork",
+			expectTrue: []string{"$NOT($IN_INVEN(SPOON))", "$IN_INVEN(FORK)"},
+		},
+	})
+}
@@ -0,0 +1,326 @@
+package game
+
+// File lock.go contains symbols for exits, details, and items that can be
+// opened, closed, locked, and unlocked, such as doors and chests.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/tunaq/internal/command"
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+	"github.com/dekarrin/tunaq/internal/util"
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// Lock is the definition of how an Egress, Detail, or Item can be opened and
+// locked.
+// Everything with a Lock of the same Label shares whether it is open and
+// whether it is locked, so the two sides of a door can be given the same one.
+type Lock struct {
+	// Label is the identifier of the state that the lock shares with all
+	// other locks with the same label.
+	Label string
+
+	// Openable is whether the thing can be opened and closed. An Egress that
+	// is openable can only be gone through when it is open.
+	Openable bool
+
+	// Lockable is whether the thing can be locked and unlocked. An Egress that
+	// is locked can never be gone through, and a locked thing cannot be
+	// opened.
+	Lockable bool
+
+	// Open is whether the thing is open at the start of the game.
+	Open bool
+
+	// Locked is whether the thing is locked at the start of the game.
+	Locked bool
+
+	// Keys is the labels (or tags) of items, any one of which must be in the
+	// player's inventory to lock or unlock it. If it is empty, no key is
+	// needed.
+	Keys []string
+
+	// If gives tunascript that must resolve to true for the player to lock or
+	// unlock it. If no tunascript was parsed, this will be something that
+	// always returns true.
+	If tunascript.AST
+
+	// IfRaw gives the exact source tunascript that was parsed to create If. If
+	// no code was parsed, this will be the empty string.
+	IfRaw string
+}
+
+// Copy returns a deeply-copied Lock.
+func (lock Lock) Copy() Lock {
+	lCopy := Lock{
+		Label:    lock.Label,
+		Openable: lock.Openable,
+		Lockable: lock.Lockable,
+		Open:     lock.Open,
+		Locked:   lock.Locked,
+		Keys:     make([]string, len(lock.Keys)),
+		If:       lock.If,
+		IfRaw:    lock.IfRaw,
+	}
+
+	copy(lCopy.Keys, lock.Keys)
+
+	return lCopy
+}
+
+// lockState is the current state of all Locks with the same label.
+type lockState struct {
+	open   bool
+	locked bool
+}
+
+// lockable is a Targetable that may have a Lock.
+type lockable interface {
+	Targetable
+	GetLock() *Lock
+}
+
+// initLocks sets up the state of every Lock in the world from the values they
+// are defined with. It must be called after the world is loaded and after
+// initContainers.
+func (gs *State) initLocks() {
+	gs.locks = make(map[string]*lockState)
+	gs.lockLabels = make(map[string]string)
+
+	var things []lockable
+	for _, roomLabel := range util.OrderedKeys(gs.World) {
+		r := gs.World[roomLabel]
+		for _, eg := range r.Exits {
+			things = append(things, eg)
+		}
+		for _, det := range r.Details {
+			things = append(things, det)
+		}
+	}
+	items := gs.allItems()
+	for _, itemLabel := range util.OrderedKeys(items) {
+		things = append(things, items[itemLabel])
+	}
+
+	for _, t := range things {
+		lock := t.GetLock()
+		if lock == nil {
+			continue
+		}
+		gs.lockLabels[t.GetLabel()] = lock.Label
+		gs.lockLabels[lock.Label] = lock.Label
+		if _, ok := gs.locks[lock.Label]; !ok {
+			gs.locks[lock.Label] = &lockState{open: lock.Open, locked: lock.Locked}
+		}
+	}
+}
+
+// lockStateOf returns the current state of the lock with the given label, or
+// of the lock of the exit, detail, or item with the given label. If there is
+// no such lock, nil is returned.
+func (gs *State) lockStateOf(label string) *lockState {
+	lockLabel, ok := gs.lockLabels[strings.ToUpper(label)]
+	if !ok {
+		return nil
+	}
+	return gs.locks[lockLabel]
+}
+
+// lockBlocks returns a message saying why the player cannot go through egress
+// because of its Lock. If they can, an empty string is returned.
+func (gs *State) lockBlocks(egress *Egress) string {
+	if egress.Lock == nil {
+		return ""
+	}
+	ls := gs.locks[egress.Lock.Label]
+
+	if ls.locked {
+		return "You can't go that way; it's locked"
+	}
+	if egress.Lock.Openable && !ls.open {
+		return "You can't go that way; it's closed"
+	}
+	return ""
+}
+
+// getLockTarget returns the thing in the current room or the player's
+// inventory that alias refers to, along with its Lock. If there is nothing
+// with that alias that has a Lock, a non-nil error for showing to the player
+// is returned.
+func (gs *State) getLockTarget(alias string, verb string) (*Lock, string, error) {
+	tgt, err := gs.findTarget(alias, true)
+	if err != nil {
+		return nil, "", err
+	}
+	if tgt == nil {
+		return nil, "", tqerrors.Interpreterf("I don't see any %q here", alias)
+	}
+
+	name := strings.ToLower(alias)
+	if it, ok := tgt.(*Item); ok {
+		name = it.Name
+	}
+	lt, ok := tgt.(lockable)
+	if !ok || lt.GetLock() == nil {
+		return nil, "", tqerrors.Interpreterf("You can't %s the %s", strings.ToLower(verb), name)
+	}
+
+	return lt.GetLock(), name, nil
+}
+
+// findKey returns the label of an item in the player's inventory that fits
+// lock. If keyAlias is not empty, only the item with that alias is
+// considered. If lock needs no key, "" is returned with a nil error. If there
+// is no key, a non-nil error for showing to the player is returned.
+func (gs *State) findKey(lock *Lock, keyAlias string, name string) (string, error) {
+	if keyAlias != "" {
+		keyItem, err := gs.findInventoryItem(keyAlias)
+		if err != nil {
+			return "", err
+		}
+		if keyItem == nil {
+			return "", tqerrors.Interpreterf("You don't have a %q", keyAlias)
+		}
+		if len(lock.Keys) > 0 && !fitsLock(keyItem, lock) {
+			return "", tqerrors.Interpreterf("The %s doesn't fit the %s", keyItem.Name, name)
+		}
+		return keyItem.Label, nil
+	}
+
+	if len(lock.Keys) < 1 {
+		return "", nil
+	}
+
+	for _, itemLabel := range util.OrderedKeys(gs.Inventory) {
+		if fitsLock(gs.Inventory[itemLabel], lock) {
+			return itemLabel, nil
+		}
+	}
+
+	return "", tqerrors.Interpreterf("You don't have anything that fits the %s", name)
+}
+
+// fitsLock returns whether the item is one of the keys of lock.
+func fitsLock(item *Item, lock *Lock) bool {
+	for _, key := range lock.Keys {
+		if key == item.Label {
+			return true
+		}
+		for _, tag := range item.Tags {
+			if key == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ExecuteCommandOpen executes the OPEN command with the arguments in the
+// provided Command and returns the output.
+func (gs *State) ExecuteCommandOpen(cmd command.Command) (string, error) {
+	lock, name, err := gs.getLockTarget(cmd.Recipient, cmd.Verb)
+	if err != nil {
+		return "", err
+	}
+	if !lock.Openable {
+		return "", tqerrors.Interpreterf("The %s can't be opened", name)
+	}
+
+	ls := gs.locks[lock.Label]
+	if ls.open {
+		return "", tqerrors.Interpreterf("The %s is already open", name)
+	}
+	if ls.locked {
+		return "", tqerrors.Interpreterf("You try to open the %s, but it's locked", name)
+	}
+
+	ls.open = true
+	return fmt.Sprintf("You open the %s", name), nil
+}
+
+// ExecuteCommandClose executes the CLOSE command with the arguments in the
+// provided Command and returns the output.
+func (gs *State) ExecuteCommandClose(cmd command.Command) (string, error) {
+	lock, name, err := gs.getLockTarget(cmd.Recipient, cmd.Verb)
+	if err != nil {
+		return "", err
+	}
+	if !lock.Openable {
+		return "", tqerrors.Interpreterf("The %s can't be closed", name)
+	}
+
+	ls := gs.locks[lock.Label]
+	if !ls.open {
+		return "", tqerrors.Interpreterf("The %s is already closed", name)
+	}
+
+	ls.open = false
+	return fmt.Sprintf("You close the %s", name), nil
+}
+
+// ExecuteCommandLock executes the LOCK command with the arguments in the
+// provided Command and returns the output.
+func (gs *State) ExecuteCommandLock(cmd command.Command) (string, error) {
+	lock, name, err := gs.getLockTarget(cmd.Recipient, cmd.Verb)
+	if err != nil {
+		return "", err
+	}
+	if !lock.Lockable {
+		return "", tqerrors.Interpreterf("The %s doesn't have a lock", name)
+	}
+
+	ls := gs.locks[lock.Label]
+	if ls.locked {
+		return "", tqerrors.Interpreterf("The %s is already locked", name)
+	}
+	if ls.open {
+		return "", tqerrors.Interpreterf("You'll have to close the %s first", name)
+	}
+
+	keyLabel, err := gs.findKey(lock, cmd.Instrument, name)
+	if err != nil {
+		return "", err
+	}
+	if len(lock.If.Nodes) > 0 && !gs.scripts.Exec(lock.If).Bool() {
+		return "", tqerrors.Interpreterf("You try to lock the %s, but it won't budge", name)
+	}
+
+	ls.locked = true
+	if keyLabel != "" {
+		return fmt.Sprintf("You lock the %s with the %s", name, gs.Inventory[keyLabel].Name), nil
+	}
+	return fmt.Sprintf("You lock the %s", name), nil
+}
+
+// ExecuteCommandUnlock executes the UNLOCK command with the arguments in the
+// provided Command and returns the output.
+func (gs *State) ExecuteCommandUnlock(cmd command.Command) (string, error) {
+	lock, name, err := gs.getLockTarget(cmd.Recipient, cmd.Verb)
+	if err != nil {
+		return "", err
+	}
+	if !lock.Lockable {
+		return "", tqerrors.Interpreterf("The %s doesn't have a lock", name)
+	}
+
+	ls := gs.locks[lock.Label]
+	if !ls.locked {
+		return "", tqerrors.Interpreterf("The %s isn't locked", name)
+	}
+
+	keyLabel, err := gs.findKey(lock, cmd.Instrument, name)
+	if err != nil {
+		return "", err
+	}
+	if len(lock.If.Nodes) > 0 && !gs.scripts.Exec(lock.If).Bool() {
+		return "", tqerrors.Interpreterf("You try to unlock the %s, but it won't budge", name)
+	}
+
+	ls.locked = false
+	if keyLabel != "" {
+		return fmt.Sprintf("You unlock the %s with the %s", name, gs.Inventory[keyLabel].Name), nil
+	}
+	return fmt.Sprintf("You unlock the %s", name), nil
+}
@@ -0,0 +1,66 @@
+package game
+
+import "testing"
+
+// lockedDoorWorld is testWorld with a locked door between the kitchen and the
+// hall that the spoon unlocks.
+func lockedDoorWorld() worldBuilder {
+	world := testWorld()
+	door := Lock{Label: "HALL_DOOR", Openable: true, Lockable: true, Locked: true, Keys: []string{"SPOON"}}
+	for _, egress := range []*Egress{world["KITCHEN"].Exits[0], world["HALL"].Exits[0]} {
+		side := door.Copy()
+		egress.Aliases = append(egress.Aliases, "DOOR")
+		egress.Lock = &side
+	}
+	return world
+}
+
+func Test_State_locks(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		return New(lockedDoorWorld(), "KITCHEN", nil, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:      "locked exit can't be used",
+			cmd:       "go hall",
+			expectErr: "You can't go that way; it's locked",
+		},
+		{
+			name:      "unlock without a key",
+			cmd:       "unlock door",
+			expectErr: "You don't have anything that fits the door",
+		},
+		{
+			name:       "unlock with a key",
+			setup:      []string{"take spoon"},
+			cmd:        "unlock door with spoon",
+			expectTrue: []string{"$NOT($IS_LOCKED(HALL_DOOR))"},
+		},
+		{
+			name:      "closed exit can't be used",
+			setup:     []string{"take spoon", "unlock door with spoon"},
+			cmd:       "go hall",
+			expectErr: "You can't go that way; it's closed",
+		},
+		{
+			name:       "open exit can be used",
+			setup:      []string{"take spoon", "unlock door with spoon", "open door"},
+			cmd:        "go hall",
+			expectTrue: []string{"$VISITED(HALL)"},
+		},
+		{
+			name:       "other side of the door is the same door",
+			setup:      []string{"take spoon", "unlock door with spoon", "open door", "go hall"},
+			cmd:        "lock door",
+			expectErr:  "You'll have to close the door first",
+			expectTrue: []string{"$IS_OPEN(HALL_TO_KITCHEN)"},
+		},
+		{
+			name:       "lock a closed door",
+			setup:      []string{"take spoon", "unlock door with spoon", "open door", "go hall", "close door"},
+			cmd:        "lock door",
+			expectTrue: []string{"$IS_LOCKED(KITCHEN_TO_HALL)", "$NOT($IS_OPEN(HALL_DOOR))"},
+		},
+	})
+}
@@ -0,0 +1,85 @@
+package game
+
+// File narration.go contains symbols for telling the player about NPCs that
+// come and go from the room they are in.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// npcEnterMessage returns the message shown when npc moves from room into
+// nextRoom, which the player is in.
+func (gs *State) npcEnterMessage(npc *NPC, room, nextRoom *Room) string {
+	// the NPC comes in from the way that leads back to where they were
+	exitName := exitNameToward(nextRoom, room.Label)
+
+	if npc.EnterMessage == "" {
+		if exitName == "" {
+			return fmt.Sprintf("%s arrives.", capitalize(npc.Name))
+		}
+		return fmt.Sprintf("%s enters from the %s.", capitalize(npc.Name), exitName)
+	}
+	return gs.expandNPCMessage(npc, exitName, npc.tmplEnterMessage)
+}
+
+// npcLeaveMessage returns the message shown when npc moves from room, which the
+// player is in, into nextRoom.
+func (gs *State) npcLeaveMessage(npc *NPC, room, nextRoom *Room) string {
+	exitName := exitNameToward(room, nextRoom.Label)
+
+	if npc.LeaveMessage == "" {
+		if exitName == "" {
+			return fmt.Sprintf("%s leaves.", capitalize(npc.Name))
+		}
+		return fmt.Sprintf("%s leaves toward the %s.", capitalize(npc.Name), exitName)
+	}
+	return gs.expandNPCMessage(npc, exitName, npc.tmplLeaveMessage)
+}
+
+// expandNPCMessage expands tmpl, which is the enter or leave message of npc,
+// with the flags for the name and pronouns of the NPC and the name of the exit
+// it used set.
+func (gs *State) expandNPCMessage(npc *NPC, exitName string, tmpl *tunascript.Template) string {
+	narrationFlags := map[string]string{
+		FlagNPCName:     npc.Name,
+		FlagNominative:  strings.ToLower(npc.Pronouns.Nominative),
+		FlagObjective:   strings.ToLower(npc.Pronouns.Objective),
+		FlagPossessive:  strings.ToLower(npc.Pronouns.Possessive),
+		FlagDeterminer:  strings.ToLower(npc.Pronouns.Determiner),
+		FlagReflexive:   strings.ToLower(npc.Pronouns.Reflexive),
+		FlagNPCExitName: exitName,
+	}
+	for fl, val := range narrationFlags {
+		gs.scripts.AddFlag(fl, val)
+	}
+	defer func() {
+		for fl := range narrationFlags {
+			gs.scripts.RemoveFlag(fl)
+		}
+	}()
+
+	return strings.TrimSpace(gs.Expand(tmpl))
+}
+
+// exitNameToward returns the name of the first exit in room that goes to the
+// room with label dest, for use in narration. If there is no such exit, or it
+// has no aliases, "" is returned.
+func exitNameToward(room *Room, dest string) string {
+	for _, egress := range room.Exits {
+		if egress.DestLabel == dest && len(egress.Aliases) > 0 {
+			return strings.ToLower(egress.Aliases[0])
+		}
+	}
+	return ""
+}
+
+// capitalize returns s with its first letter in upper case.
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 
+	"github.com/dekarrin/tunaq/internal/util"
 	"github.com/dekarrin/tunaq/tunascript"
 )
 
@@ -61,14 +62,53 @@ type NPC struct {
 	// to do so.
 	IfRaw string
 
+	// Inventory is the items that the NPC is holding. This can be changed over
+	// time.
+	Inventory Inventory
+
+	// OnGive is how the NPC reacts to the player giving it an item. The first
+	// most specific one that matches the item is used.
+	OnGive []GiveAction
+
+	// OnShow is how the NPC reacts to the player showing it an item. The first
+	// most specific one that matches the item is used.
+	OnShow []GiveAction
+
+	// OnVerb is how the NPC reacts to the player using verbs that the world
+	// defines on it. The first most specific one that matches is used.
+	OnVerb []VerbAction
+
+	// EnterMessage is shown when the NPC moves into the room that the player
+	// is in. If it is empty, a default message is used.
+	EnterMessage string
+
+	// LeaveMessage is shown when the NPC moves out of the room that the player
+	// is in. If it is empty, a default message is used.
+	LeaveMessage string
+
 	// for NPCs with a path movement route, routeCur gives the step it is
 	// currently on.
 	routeCur *int
 
+	// sentTo is the label of the room that the NPC was sent to with $SEND().
+	// If it is set, the NPC walks toward that room and then stays there instead
+	// of following its Movement.
+	sentTo string
+
 	// tmplDescription is the precomputed template AST for the description text.
 	// It must generally be filled in with the game engine, and will not be
 	// present directly when loaded from disk.
 	tmplDescription *tunascript.Template
+
+	// tmplEnterMessage is the precomputed template AST for the enter message
+	// text. It must generally be filled in with the game engine, and will not
+	// be present directly when loaded from disk.
+	tmplEnterMessage *tunascript.Template
+
+	// tmplLeaveMessage is the precomputed template AST for the leave message
+	// text. It must generally be filled in with the game engine, and will not
+	// be present directly when loaded from disk.
+	tmplLeaveMessage *tunascript.Template
 }
 
 // ResetRoute resets the route of the NPC. It should always be called before
@@ -83,8 +123,9 @@ func (npc *NPC) ResetRoute() {
 // should stay. The tsInterpreter engine, if provided, is used to evaluate which
 // exits are visible/usable to this NPC.
 //
-// room is the current room that they are in.
-func (npc NPC) NextRouteStep(room *Room, tsEng *tunascript.Interpreter) string {
+// room is the current room that they are in. rng is used to make any random
+// choices the route requires.
+func (npc NPC) NextRouteStep(room *Room, tsEng *tunascript.Interpreter, rng *rand.Rand) string {
 	if npc.routeCur == nil {
 		return ""
 	}
@@ -126,12 +167,11 @@ func (npc NPC) NextRouteStep(room *Room, tsEng *tunascript.Interpreter) string {
 			return ""
 		}
 
-		candidateRoomsSlice := []string{}
-		for k := range candidateRooms {
-			candidateRoomsSlice = append(candidateRoomsSlice, k)
-		}
+		// order must be consistent so the same random value always gives the
+		// same choice
+		candidateRoomsSlice := util.OrderedKeys(candidateRooms)
 
-		selectionIdx := rand.Intn(len(candidateRoomsSlice))
+		selectionIdx := rng.Intn(len(candidateRoomsSlice))
 		choice := candidateRoomsSlice[selectionIdx]
 		return choice
 	default:
@@ -142,19 +182,39 @@ func (npc NPC) NextRouteStep(room *Room, tsEng *tunascript.Interpreter) string {
 // Copy returns a deeply-copied NPC.
 func (npc NPC) Copy() NPC {
 	npcCopy := NPC{
-		Label:       npc.Label,
-		Name:        npc.Name,
-		Description: npc.Description,
-		Pronouns:    npc.Pronouns,
-		Start:       npc.Start,
-		Movement:    npc.Movement.Copy(),
-		Dialog:      make([]*DialogStep, len(npc.Dialog)),
-		Aliases:     make([]string, len(npc.Aliases)),
-		Tags:        make([]string, len(npc.Tags)),
-		If:          npc.If,
-		IfRaw:       npc.IfRaw,
-
-		tmplDescription: npc.tmplDescription,
+		Label:        npc.Label,
+		Name:         npc.Name,
+		Description:  npc.Description,
+		Pronouns:     npc.Pronouns,
+		Start:        npc.Start,
+		Movement:     npc.Movement.Copy(),
+		Dialog:       make([]*DialogStep, len(npc.Dialog)),
+		Aliases:      make([]string, len(npc.Aliases)),
+		Tags:         make([]string, len(npc.Tags)),
+		If:           npc.If,
+		IfRaw:        npc.IfRaw,
+		Inventory:    make(Inventory, len(npc.Inventory)),
+		OnGive:       make([]GiveAction, len(npc.OnGive)),
+		OnShow:       make([]GiveAction, len(npc.OnShow)),
+		OnVerb:       copyVerbActions(npc.OnVerb),
+		EnterMessage: npc.EnterMessage,
+		LeaveMessage: npc.LeaveMessage,
+
+		sentTo:           npc.sentTo,
+		tmplDescription:  npc.tmplDescription,
+		tmplEnterMessage: npc.tmplEnterMessage,
+		tmplLeaveMessage: npc.tmplLeaveMessage,
+	}
+
+	for label, it := range npc.Inventory {
+		itemCopy := it.Copy()
+		npcCopy.Inventory[label] = &itemCopy
+	}
+	for i := range npc.OnGive {
+		npcCopy.OnGive[i] = npc.OnGive[i].Copy()
+	}
+	for i := range npc.OnShow {
+		npcCopy.OnShow[i] = npc.OnShow[i].Copy()
 	}
 
 	for i := range npc.Dialog {
@@ -0,0 +1,176 @@
+package game
+
+import "testing"
+
+// squareWorld is four rooms in a square, with two ways to get from NORTH to
+// SOUTH: through EAST, which is the shorter way by label order, or through
+// WEST. The door between EAST and SOUTH is closed unless doorOpen is set. A
+// guard starts in NORTH.
+func squareWorld(guardMovement Route, doorOpen bool) worldBuilder {
+	world := newWorld("NORTH", "EAST", "WEST", "SOUTH")
+	world.exit("NORTH", "EAST")
+	world.exit("NORTH", "WEST")
+	world.exit("EAST", "NORTH")
+	eastSide := world.exit("EAST", "SOUTH")
+	world.exit("WEST", "NORTH")
+	world.exit("WEST", "SOUTH")
+	southSide := world.exit("SOUTH", "EAST")
+	world.exit("SOUTH", "WEST")
+
+	door := Lock{Label: "DOOR", Openable: true, Open: doorOpen}
+	eastLock := door.Copy()
+	southLock := door.Copy()
+	eastSide.Lock = &eastLock
+	southSide.Lock = &southLock
+
+	world.npc("NORTH", "GUARD").Movement = guardMovement
+
+	return world
+}
+
+func Test_State_MoveNPCs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		movement Route
+		doorOpen bool
+		tests    []commandTest
+	}{
+		{
+			name:     "goto",
+			movement: Route{Action: RouteGoto, Target: "SOUTH"},
+			tests: []commandTest{
+				{
+					name:       "goes around a closed door",
+					cmd:        "wait",
+					expectNPCs: map[string]string{"GUARD": "WEST"},
+				},
+				{
+					name:       "stays once there",
+					setup:      []string{"wait", "wait"},
+					cmd:        "wait",
+					expectNPCs: map[string]string{"GUARD": "SOUTH"},
+				},
+				{
+					name:       "send overrides movement",
+					setup:      []string{"debug exec $SEND(GUARD, @WEST@)", "wait"},
+					cmd:        "wait",
+					expectNPCs: map[string]string{"GUARD": "WEST"},
+				},
+				{
+					name:       "moves during commands that aren't movement",
+					cmd:        "look",
+					expectNPCs: map[string]string{"GUARD": "WEST"},
+				},
+				{
+					name:       "help doesn't move NPCs",
+					cmd:        "help",
+					expectNPCs: map[string]string{"GUARD": "NORTH"},
+				},
+			},
+		},
+		{
+			name:     "goto with the door open",
+			movement: Route{Action: RouteGoto, Target: "SOUTH"},
+			doorOpen: true,
+			tests: []commandTest{
+				{
+					name:       "takes the shortest way",
+					cmd:        "wait",
+					expectNPCs: map[string]string{"GUARD": "EAST"},
+				},
+			},
+		},
+		{
+			name:     "static",
+			movement: Route{Action: RouteStatic},
+			tests: []commandTest{
+				{
+					name:       "doesn't move",
+					cmd:        "wait",
+					expectNPCs: map[string]string{"GUARD": "NORTH"},
+				},
+				{
+					name:       "sent NPC moves during commands that aren't movement",
+					setup:      []string{"debug exec $SEND(GUARD, @SOUTH@)", "look", "inventory"},
+					cmd:        "exits",
+					expectNPCs: map[string]string{"GUARD": "SOUTH"},
+				},
+			},
+		},
+		{
+			name:     "follow",
+			movement: Route{Action: RouteFollow},
+			tests: []commandTest{
+				{
+					name:       "follows the player",
+					setup:      []string{"go west", "go south"},
+					cmd:        "wait",
+					expectNPCs: map[string]string{"GUARD": "SOUTH"},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runCommandTests(t, func(ioDev IODevice) (*State, error) {
+				return New(squareWorld(tc.movement, tc.doorOpen), "NORTH", nil, ioDev)
+			}, tc.tests)
+		})
+	}
+}
+
+func Test_State_MoveNPCs_narration(t *testing.T) {
+	testCases := []struct {
+		name     string
+		movement Route
+		tests    []commandTest
+	}{
+		{
+			name:     "follow",
+			movement: Route{Action: RouteFollow},
+			tests: []commandTest{
+				{
+					name:         "NPC enters the player's room",
+					cmd:          "go west",
+					expectOutput: "The guard enters from the north.",
+				},
+				{
+					name:         "NPC leaves the player's room",
+					setup:        []string{"go west", "debug exec $SEND(GUARD, @EAST@)"},
+					cmd:          "wait",
+					expectOutput: "the guard heads north with her spear.",
+				},
+			},
+		},
+		{
+			name:     "goto",
+			movement: Route{Action: RouteGoto, Target: "SOUTH"},
+			tests: []commandTest{
+				{
+					name:         "NPC leaves during a command that isn't movement",
+					cmd:          "look",
+					expectOutput: "the guard heads west with her spear.",
+				},
+				{
+					name:            "NPC moves out of sight",
+					setup:           []string{"wait"},
+					cmd:             "wait",
+					expectNotOutput: "guard",
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runCommandTests(t, func(ioDev IODevice) (*State, error) {
+				world := squareWorld(tc.movement, false)
+				guard := world["NORTH"].NPCs["GUARD"]
+				guard.Pronouns = PronounsFeminine
+				guard.LeaveMessage = "$TQ_NPC heads $TQ_EXIT with $TQ_DETERMINER spear."
+				return New(world, "NORTH", nil, ioDev)
+			}, tc.tests)
+		})
+	}
+}
@@ -74,8 +74,9 @@ func (pf *Pathfinder) ValidatePath(path []string, loop bool) error {
 // should verify that the returned sequence of rooms is traversable before
 // attempting to use it for such purposes.
 //
-// Returns nil or empty []string if the startLabel does not exist in the world,
-// if the endLabel does not exist in the world, or if the path is not possible.
+// The returned path starts with startLabel and ends with endLabel. Returns nil
+// or empty []string if the startLabel does not exist in the world, if the
+// endLabel does not exist in the world, or if the path is not possible.
 func (pf *Pathfinder) Dijkstra(startLabel, endLabel string) []string {
 	if pf.dijkstraTable != nil {
 		if solution, ok := pf.dijkstraTable[[2]string{startLabel, endLabel}]; ok {
@@ -97,6 +98,35 @@ func (pf *Pathfinder) Dijkstra(startLabel, endLabel string) []string {
 		return []string{}
 	}
 
+	solution := pf.shortestPath(source, target, nil)
+
+	if pf.dijkstraTable == nil {
+		pf.dijkstraTable = pathCache{}
+	}
+	pf.dijkstraTable[[2]string{startLabel, endLabel}] = make([]string, len(solution))
+	copy(pf.dijkstraTable[[2]string{startLabel, endLabel}], solution)
+
+	return solution
+}
+
+// DijkstraThrough is the same as Dijkstra, but it only finds paths that go
+// through exits that canUse returns true for. Because what canUse allows can
+// change from call to call, its results are not cached.
+func (pf *Pathfinder) DijkstraThrough(startLabel, endLabel string, canUse func(egress *Egress) bool) []string {
+	source := pf.World[startLabel]
+	target := pf.World[endLabel]
+
+	if source == nil || target == nil || startLabel == endLabel {
+		return []string{}
+	}
+
+	return pf.shortestPath(source, target, canUse)
+}
+
+// shortestPath finds the shortest path from source to target using Dijkstra's
+// Algorithm. If canUse is not nil, only exits it returns true for are
+// considered. Returns an empty slice if target cannot be reached.
+func (pf *Pathfinder) shortestPath(source, target *Room, canUse func(egress *Egress) bool) []string {
 	dist := map[string]uint{}
 	prev := map[string]*Room{}
 	searchSetQ := map[string]*Room{}
@@ -111,20 +141,24 @@ func (pf *Pathfinder) Dijkstra(startLabel, endLabel string) []string {
 	for len(searchSetQ) > 0 {
 		var minDist uint = math.MaxUint
 		uLabel := ""
-		for label, d := range dist {
-			if d <= minDist {
+		for label := range searchSetQ {
+			if d := dist[label]; d < minDist || (d == minDist && (uLabel == "" || label < uLabel)) {
 				uLabel = label
 				minDist = d
 			}
 		}
 
-		if uLabel == target.Label {
+		// if the closest remaining room can't be reached, none of them can
+		if minDist == math.MaxUint || uLabel == target.Label {
 			break
 		}
 		u := searchSetQ[uLabel]
 		delete(searchSetQ, uLabel)
 
 		for _, vEgress := range u.Exits {
+			if canUse != nil && !canUse(vEgress) {
+				continue
+			}
 			vLabel := vEgress.DestLabel
 			v, vOK := searchSetQ[vLabel]
 			if !vOK {
@@ -150,16 +184,10 @@ func (pf *Pathfinder) Dijkstra(startLabel, endLabel string) []string {
 	if prev[target.Label] != nil { // only do this if target is reachable
 		u := target
 		for u != nil {
-			solution = append(solution, u.Label)
+			solution = append([]string{u.Label}, solution...)
 			u = prev[u.Label]
 		}
 	}
 
-	if pf.dijkstraTable == nil {
-		pf.dijkstraTable = pathCache{}
-	}
-	pf.dijkstraTable[[2]string{startLabel, endLabel}] = make([]string, len(solution))
-	copy(pf.dijkstraTable[[2]string{startLabel, endLabel}], solution)
-
 	return solution
 }
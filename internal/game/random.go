@@ -0,0 +1,56 @@
+package game
+
+// File random.go contains the source of random numbers used by the game.
+
+import (
+	"math/rand"
+)
+
+// randSource is a rand.Source64 whose entire state is a single integer, so
+// that it can be saved and restored along with the rest of the game. It uses
+// the SplitMix64 algorithm.
+type randSource struct {
+	state uint64
+}
+
+// Seed sets the state of the source from the given seed.
+func (src *randSource) Seed(seed int64) {
+	src.state = uint64(seed)
+}
+
+// Uint64 returns a pseudo-random 64-bit value and advances the state.
+func (src *randSource) Uint64() uint64 {
+	src.state += 0x9e3779b97f4a7c15
+	z := src.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer and advances the
+// state.
+func (src *randSource) Int63() int64 {
+	return int64(src.Uint64() >> 1)
+}
+
+// Seed gives the seed that the random source of the game was last seeded
+// with.
+func (gs *State) Seed() int64 {
+	return gs.seed
+}
+
+// SetSeed re-seeds the random source of the game. Every random decision made
+// by the game, such as where wandering NPCs go, is made with it, so two games
+// in the same world with the same seed given the same commands play out
+// identically.
+func (gs *State) SetSeed(seed int64) {
+	gs.seed = seed
+	gs.randSrc.Seed(seed)
+}
+
+// newRandom creates a new random source and a rand.Rand that draws from it.
+func newRandom(seed int64) (*randSource, *rand.Rand) {
+	src := &randSource{}
+	src.Seed(seed)
+	return src, rand.New(src)
+}
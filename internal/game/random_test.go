@@ -0,0 +1,81 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// wanderWorld is four rooms that all lead to each other, with a cat that
+// wanders between them starting in NORTH.
+func wanderWorld() worldBuilder {
+	labels := []string{"NORTH", "SOUTH", "EAST", "WEST"}
+	world := newWorld(labels...)
+	for _, from := range labels {
+		for _, to := range labels {
+			if from != to {
+				world.exit(from, to)
+			}
+		}
+	}
+	world.npc("NORTH", "CAT").Movement = Route{Action: RouteWander}
+	return world
+}
+
+// wanderPath moves all NPCs the given number of times and returns the rooms
+// the CAT NPC was in after each move.
+func wanderPath(gs *State, steps int) []string {
+	var path []string
+	for i := 0; i < steps; i++ {
+		gs.MoveNPCs()
+		path = append(path, gs.npcLocations["CAT"])
+	}
+	return path
+}
+
+func Test_State_SetSeed(t *testing.T) {
+	testCases := []struct {
+		name       string
+		seed       int64
+		otherSeed  int64
+		expectSame bool
+	}{
+		{
+			name:       "same seed",
+			seed:       8,
+			otherSeed:  8,
+			expectSame: true,
+		},
+		{
+			name:       "different seed",
+			seed:       8,
+			otherSeed:  413,
+			expectSame: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			first, err := New(wanderWorld(), "NORTH", nil, &nopIODevice{})
+			if !assert.NoError(err) {
+				return
+			}
+			second, err := New(wanderWorld(), "NORTH", nil, &nopIODevice{})
+			if !assert.NoError(err) {
+				return
+			}
+
+			first.SetSeed(tc.seed)
+			second.SetSeed(tc.otherSeed)
+
+			assert.Equal(tc.seed, first.Seed())
+			if tc.expectSame {
+				assert.Equal(wanderPath(first, 20), wanderPath(second, 20))
+			} else {
+				assert.NotEqual(wanderPath(first, 20), wanderPath(second, 20))
+			}
+		})
+	}
+}
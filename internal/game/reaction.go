@@ -0,0 +1,118 @@
+package game
+
+// File reaction.go contains symbols for choosing and running the reactions
+// that things in the world have to what the player does with them, such as
+// GiveActions and VerbActions.
+
+import (
+	"strings"
+
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// reaction is a way that something in the world reacts to the player. It is
+// implemented by GiveAction and VerbAction.
+type reaction interface {
+	// latags returns the labels (or tags) of the things the reaction is for.
+	// If there are none, it is for anything.
+	latags() []string
+
+	// condition returns the tunascript that must be true for the reaction to
+	// be used.
+	condition() tunascript.AST
+
+	// script returns the tunascript that is executed when the reaction is
+	// used.
+	script() tunascript.AST
+
+	// response returns the template for the message shown when the reaction
+	// is used. It may be nil.
+	response() *tunascript.Template
+}
+
+func (ga GiveAction) latags() []string               { return ga.With }
+func (ga GiveAction) condition() tunascript.AST      { return ga.If }
+func (ga GiveAction) script() tunascript.AST         { return ga.Do }
+func (ga GiveAction) response() *tunascript.Template { return ga.tmplResponse }
+
+func (va VerbAction) latags() []string               { return va.With }
+func (va VerbAction) condition() tunascript.AST      { return va.If }
+func (va VerbAction) script() tunascript.AST         { return va.Do }
+func (va VerbAction) response() *tunascript.Template { return va.tmplResponse }
+
+// findReaction returns the reaction in reactions that applies to the thing
+// with the given label. Of all the ones whose latags match label and whose
+// condition is true, the first one that matches by label is used, then the
+// first one that matches by tag, then the first one with no latags at all. If
+// label is "", only ones with no latags can apply. If none apply, nil is
+// returned.
+func findReaction[R reaction](gs *State, reactions []R, label string) *R {
+	var best *R
+	bestSpecific := -1
+
+	for i := range reactions {
+		r := reactions[i]
+
+		specific := 0
+		if len(r.latags()) > 0 {
+			if label == "" {
+				continue
+			}
+			specific = -1
+			for _, latag := range r.latags() {
+				if !gs.concreteMatchesLatagList([]string{label}, []string{latag}) {
+					continue
+				}
+				if strings.HasPrefix(latag, "@") {
+					if specific < 1 {
+						specific = 1
+					}
+				} else {
+					specific = 2
+				}
+			}
+			if specific < 0 {
+				continue
+			}
+		}
+
+		if specific <= bestSpecific {
+			continue
+		}
+		if cond := r.condition(); len(cond.Nodes) > 0 && !gs.scripts.Exec(cond).Bool() {
+			continue
+		}
+
+		best = &reactions[i]
+		bestSpecific = specific
+	}
+
+	return best
+}
+
+// runReaction executes the script of r and returns the paragraphs of output
+// it gives: its response, and then anything that was output with $OUTPUT().
+// Either one is left out if it is empty.
+func (gs *State) runReaction(r reaction) []string {
+	// enable buffering so any output doesn't just go directly to gs before we
+	// get a chance to write any other output
+	gs.tsBufferOutput = true
+	defer func() {
+		gs.tsBuf.Reset()
+		gs.tsBufferOutput = false
+	}()
+
+	gs.scripts.Exec(r.script())
+
+	var paragraphs []string
+	if tmpl := r.response(); tmpl != nil {
+		if response := strings.TrimSpace(gs.Expand(tmpl)); response != "" {
+			paragraphs = append(paragraphs, response)
+		}
+	}
+	if tsOutput := strings.TrimSpace(gs.tsBuf.String()); tsOutput != "" {
+		paragraphs = append(paragraphs, tsOutput)
+	}
+
+	return paragraphs
+}
@@ -0,0 +1,100 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/dekarrin/tunaq/tunascript"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_findReaction(t *testing.T) {
+	testCases := []struct {
+		name      string
+		reactions []GiveAction
+		label     string
+		expect    string // Response of the chosen reaction
+		expectNil bool
+	}{
+		{
+			name:      "none",
+			label:     "SPOON",
+			expectNil: true,
+		},
+		{
+			name: "label is used over tag and catch-all",
+			reactions: []GiveAction{
+				{Response: "any"},
+				{With: []string{"@CUTLERY"}, Response: "tag"},
+				{With: []string{"SPOON"}, Response: "label"},
+			},
+			label:  "SPOON",
+			expect: "label",
+		},
+		{
+			name: "tag is used over catch-all",
+			reactions: []GiveAction{
+				{Response: "any"},
+				{With: []string{"@CUTLERY"}, Response: "tag"},
+				{With: []string{"FORK"}, Response: "label"},
+			},
+			label:  "SPOON",
+			expect: "tag",
+		},
+		{
+			name: "first of equally specific ones",
+			reactions: []GiveAction{
+				{With: []string{"SPOON"}, Response: "first"},
+				{With: []string{"FORK", "SPOON"}, Response: "second"},
+			},
+			label:  "SPOON",
+			expect: "first",
+		},
+		{
+			name: "false if is skipped",
+			reactions: []GiveAction{
+				{Response: "any"},
+				{With: []string{"SPOON"}, If: tunascript.ReturnFalse, Response: "label"},
+			},
+			label:  "SPOON",
+			expect: "any",
+		},
+		{
+			name: "no label only matches catch-all",
+			reactions: []GiveAction{
+				{With: []string{"SPOON"}, Response: "label"},
+				{Response: "any"},
+			},
+			expect: "any",
+		},
+		{
+			name: "nothing matches",
+			reactions: []GiveAction{
+				{With: []string{"FORK"}, Response: "label"},
+			},
+			label:     "SPOON",
+			expectNil: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			world := testWorld()
+			world["KITCHEN"].Items[0].Tags = []string{"@CUTLERY"}
+			gs, err := New(world, "KITCHEN", nil, &nopIODevice{})
+			if !assert.NoError(err) {
+				return
+			}
+
+			actual := findReaction(gs, tc.reactions, tc.label)
+			if tc.expectNil {
+				assert.Nil(actual)
+				return
+			}
+			if assert.NotNil(actual) {
+				assert.Equal(tc.expect, actual.Response)
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/dekarrin/tunaq/internal/util"
 	"github.com/dekarrin/tunaq/tunascript"
 )
 
@@ -43,6 +44,18 @@ type Detail struct {
 	// to do so.
 	IfRaw string
 
+	// Lock is how the detail can be opened and locked. It is nil if it can't
+	// be.
+	Lock *Lock
+
+	// Container is the items inside the detail. It is nil if the detail can't
+	// hold items.
+	Container *Container
+
+	// OnVerb is how the detail reacts to the player using verbs that the world
+	// defines on it. The first most specific one that matches is used.
+	OnVerb []VerbAction
+
 	// tmplDescription is the precomputed template AST for the description text.
 	// It must generally be filled in with the game engine, and will not be
 	// present directly when loaded from disk.
@@ -65,6 +78,14 @@ func (d Detail) GetTags() []string {
 	return d.Tags
 }
 
+func (d Detail) GetLock() *Lock {
+	return d.Lock
+}
+
+func (d Detail) GetContainer() *Container {
+	return d.Container
+}
+
 func (d Detail) String() string {
 	return fmt.Sprintf("Detail<%s>", d.Aliases)
 }
@@ -78,12 +99,22 @@ func (d Detail) Copy() Detail {
 		Description:     d.Description,
 		IfRaw:           d.IfRaw,
 		If:              d.If,
+		OnVerb:          copyVerbActions(d.OnVerb),
 		tmplDescription: d.tmplDescription,
 	}
 
 	copy(dCopy.Aliases, d.Aliases)
 	copy(dCopy.Tags, d.Tags)
 
+	if d.Lock != nil {
+		lockCopy := d.Lock.Copy()
+		dCopy.Lock = &lockCopy
+	}
+	if d.Container != nil {
+		containerCopy := d.Container.Copy()
+		dCopy.Container = &containerCopy
+	}
+
 	return dCopy
 }
 
@@ -124,6 +155,10 @@ type Egress struct {
 	// to do so.
 	IfRaw string
 
+	// Lock is how the egress can be opened and locked. It is nil if it can't
+	// be, in which case the egress can always be gone through.
+	Lock *Lock
+
 	// tmplDescription is the precomputed template AST for the description text.
 	// It must generally be filled in with the game engine, and will not be
 	// present directly when loaded from disk.
@@ -157,6 +192,11 @@ func (egress Egress) Copy() Egress {
 	copy(eCopy.Aliases, egress.Aliases)
 	copy(eCopy.Tags, egress.Tags)
 
+	if egress.Lock != nil {
+		lockCopy := egress.Lock.Copy()
+		eCopy.Lock = &lockCopy
+	}
+
 	return eCopy
 }
 
@@ -176,6 +216,10 @@ func (egress Egress) GetTags() []string {
 	return egress.Tags
 }
 
+func (egress Egress) GetLock() *Lock {
+	return egress.Lock
+}
+
 // Room is a scene in the game. It contains a series of exits that lead to other
 // rooms and a description. They also contain a list of the interactables at
 // game start (or will in the future).
@@ -203,6 +247,36 @@ type Room struct {
 	// Details is the details that the player can look at in the room.
 	Details []*Detail
 
+	// OnEnter is the tunascript that is executed every time the player enters
+	// the room. It is executed after OnFirstEnter if this is the first time.
+	OnEnter tunascript.AST
+
+	// OnEnterRaw is the TunaScript source code that was parsed into OnEnter,
+	// one statement per element. It will be empty if there is no such code.
+	OnEnterRaw []string
+
+	// OnFirstEnter is the tunascript that is executed only the first time the
+	// player enters the room.
+	OnFirstEnter tunascript.AST
+
+	// OnFirstEnterRaw is the TunaScript source code that was parsed into
+	// OnFirstEnter, one statement per element. It will be empty if there is no
+	// such code.
+	OnFirstEnterRaw []string
+
+	// OnExit is the tunascript that is executed every time the player leaves
+	// the room.
+	OnExit tunascript.AST
+
+	// OnExitRaw is the TunaScript source code that was parsed into OnExit, one
+	// statement per element. It will be empty if there is no such code.
+	OnExitRaw []string
+
+	// OnVerb is how the room reacts to the player using verbs that the world
+	// defines without saying what to use them on, such as "JUMP". The first
+	// one that matches is used.
+	OnVerb []VerbAction
+
 	// tmplDescription is the precomputed template AST for the description text.
 	// It must generally be filled in with the game engine, and will not be
 	// present directly when loaded from disk.
@@ -220,9 +294,21 @@ func (room Room) Copy() Room {
 		NPCs:        make(map[string]*NPC, len(room.NPCs)),
 		Details:     make([]*Detail, len(room.Details)),
 
+		OnEnter:         room.OnEnter,
+		OnEnterRaw:      make([]string, len(room.OnEnterRaw)),
+		OnFirstEnter:    room.OnFirstEnter,
+		OnFirstEnterRaw: make([]string, len(room.OnFirstEnterRaw)),
+		OnExit:          room.OnExit,
+		OnExitRaw:       make([]string, len(room.OnExitRaw)),
+		OnVerb:          copyVerbActions(room.OnVerb),
+
 		tmplDescription: room.tmplDescription,
 	}
 
+	copy(rCopy.OnEnterRaw, room.OnEnterRaw)
+	copy(rCopy.OnFirstEnterRaw, room.OnFirstEnterRaw)
+	copy(rCopy.OnExitRaw, room.OnExitRaw)
+
 	for i := range room.Exits {
 		eggCopy := room.Exits[i].Copy()
 		rCopy.Exits[i] = &eggCopy
@@ -525,6 +611,25 @@ func (room Room) ItemsAvailable(asker string, tsEng *tunascript.Interpreter) []*
 	return avail
 }
 
+// AllItems returns every item in the room; those on the ground, those held by
+// NPCs in the room, and those inside of the Containers of items and details in
+// the room.
+func (room Room) AllItems() []*Item {
+	items := append([]*Item{}, room.Items...)
+	for _, det := range room.Details {
+		if det.Container != nil {
+			items = append(items, det.Container.Items...)
+		}
+	}
+	for _, npcLabel := range util.OrderedKeys(room.NPCs) {
+		npc := room.NPCs[npcLabel]
+		for _, itemLabel := range util.OrderedKeys(npc.Inventory) {
+			items = append(items, npc.Inventory[itemLabel])
+		}
+	}
+	return append(items, containedItems(items)...)
+}
+
 // RemoveItem removes the item of the given label from the room. If there is
 // already no item with that label in the room, this has no effect.
 func (room *Room) RemoveItem(label string) {
@@ -0,0 +1,59 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_State_ExecuteCommandGo_hooks(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		world := testWorld()
+		world["KITCHEN"].OnExit = mustParseScript("$OUTPUT(@The door creaks behind you.@)")
+		world["HALL"].OnFirstEnter = mustParseScript("$OUTPUT(@A dart flies past your head!@)")
+		world["HALL"].OnEnter = mustParseScript("$INC(HALL_ENTRIES)")
+		return New(world, "KITCHEN", nil, ioDev)
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:       "start room is visited",
+			cmd:        "look",
+			expectTrue: []string{"$VISITED(KITCHEN)", "$NOT($VISITED(HALL))", "$FLAG_IS(HALL_ENTRIES, 0)"},
+		},
+		{
+			name:         "exit",
+			cmd:          "go hall",
+			expectOutput: "The door creaks behind you.",
+		},
+		{
+			name:         "first entry",
+			cmd:          "go hall",
+			expectOutput: "A dart flies past your head!",
+			expectTrue:   []string{"$VISITED(HALL)", "$FLAG_IS(HALL_ENTRIES, 1)"},
+		},
+		{
+			name:            "later entry",
+			setup:           []string{"go hall", "go kitchen"},
+			cmd:             "go hall",
+			expectNotOutput: "A dart flies past your head!",
+			expectTrue:      []string{"$FLAG_IS(HALL_ENTRIES, 2)"},
+		},
+	})
+}
+
+func Test_State_movePlayer_hookLoop(t *testing.T) {
+	assert := assert.New(t)
+
+	world := testWorld()
+	world["KITCHEN"].OnEnter = mustParseScript("$MOVE(@PLAYER, HALL)")
+	world["HALL"].OnEnter = mustParseScript("$MOVE(@PLAYER, KITCHEN)")
+
+	gs, err := New(world, "KITCHEN", nil, &nopIODevice{})
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.True(gs.scripts.Exec(mustParseScript("$MOVE(@PLAYER, HALL)")).Bool())
+	assert.Equal(0, gs.hookDepth)
+}
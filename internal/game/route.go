@@ -9,6 +9,8 @@ const (
 	RouteStatic RouteAction = iota
 	RoutePatrol
 	RouteWander
+	RouteGoto
+	RouteFollow
 )
 
 func (ra RouteAction) String() string {
@@ -19,6 +21,10 @@ func (ra RouteAction) String() string {
 		return "PATROL"
 	case RouteWander:
 		return "WANDER"
+	case RouteGoto:
+		return "GOTO"
+	case RouteFollow:
+		return "FOLLOW"
 	default:
 		return fmt.Sprintf("RouteAction(%d)", int(ra))
 	}
@@ -30,6 +36,8 @@ var RouteActionsByString map[string]RouteAction = map[string]RouteAction{
 	RouteStatic.String(): RouteStatic,
 	RoutePatrol.String(): RoutePatrol,
 	RouteWander.String(): RouteWander,
+	RouteGoto.String():   RouteGoto,
+	RouteFollow.String(): RouteFollow,
 }
 
 // Route is a type of movement for an NPC to take
@@ -37,9 +45,15 @@ type Route struct {
 	// Action is the type of action the route has the NPC move. RouteStatic is
 	// not moving, RoutePatrol is follow the steps in 'Patrol', RouteWander is
 	// to wander about but stay within AllowedRooms (if defined) or out of
-	// ForbiddenRooms (if defined).
+	// ForbiddenRooms (if defined), RouteGoto is to walk toward Target one room
+	// at a time and stay there once reached, and RouteFollow is to walk toward
+	// the player one room at a time.
 	Action RouteAction
 
+	// Target is the label of the room that the route walks to. It is only used
+	// if Action is set to RouteGoto.
+	Target string
+
 	// Path is the steps that the route takes, by their room labels. It is
 	// only used if Action is set to RoutePatrol
 	Path []string
@@ -63,6 +77,7 @@ type Route struct {
 func (r Route) Copy() Route {
 	rCopy := Route{
 		Action:         r.Action,
+		Target:         r.Target,
 		Path:           make([]string, len(r.Path)),
 		AllowedRooms:   make([]string, len(r.AllowedRooms)),
 		ForbiddenRooms: make([]string, len(r.ForbiddenRooms)),
@@ -108,6 +123,10 @@ func (r Route) String() string {
 		}
 		str += "]>"
 		return str
+	case RouteGoto:
+		return str + fmt.Sprintf(" target=%q>", r.Target)
+	case RouteFollow:
+		return str + ">"
 	default:
 		return str + " (UNKNOWN TYPE)>"
 	}
@@ -0,0 +1,836 @@
+package game
+
+// File save.go contains symbols for serializing the progress of a game so that
+// it can be restored later against the same world.
+
+import (
+	"fmt"
+
+	"github.com/dekarrin/rezi"
+	"github.com/dekarrin/tunaq/internal/util"
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// SaveFormatVersion is the version of the binary format produced by
+// State.MarshalBinary. It is increased every time the format changes.
+const SaveFormatVersion = 8
+
+// savedState is every part of a State that can change during play. It does
+// not include any of the world definition itself, only where things are and
+// what has happened to them, so it is only meaningful when applied to a State
+// created from the same world it was taken from.
+type savedState struct {
+	// currentRoom is the label of the room the player is in.
+	currentRoom string
+
+	// inventory is the labels of all items in the player inventory, sorted.
+	inventory []string
+
+	// roomItems maps room labels to the labels of the items on the ground in
+	// them, in the order they appear in the room. Rooms with no items are not
+	// included.
+	roomItems map[string]labelList
+
+	// npcs maps NPC labels to their current progress.
+	npcs map[string]savedNPC
+
+	// flags is the value of every tunascript flag.
+	flags map[string]tunascript.Value
+
+	// hasRandom is whether the state of the random source was saved. It will
+	// be false for progress saved before random state was.
+	hasRandom bool
+
+	// seed is the value the random source was last seeded with.
+	seed int64
+
+	// randomState is the state of the random source.
+	randomState uint64
+
+	// hasVisited is whether the rooms the player has been in were saved. It
+	// will be false for progress saved before they were.
+	hasVisited bool
+
+	// visited is the labels of every room the player has been in, sorted.
+	visited []string
+
+	// hasLocks is whether the state of locks was saved. It will be false for
+	// progress saved before it was.
+	hasLocks bool
+
+	// openLocks is the labels of every lock that is open, sorted.
+	openLocks []string
+
+	// lockedLocks is the labels of every lock that is locked, sorted.
+	lockedLocks []string
+
+	// hasContainers is whether the items inside of containers were saved. It
+	// will be false for progress saved before they were.
+	hasContainers bool
+
+	// containerItems maps the labels of items and details with a container to
+	// the labels of the items inside of it, in the order they are in it.
+	// Containers with no items are not included.
+	containerItems map[string]labelList
+
+	// hasNPCItems is whether the items held by NPCs were saved. It will be
+	// false for progress saved before they were.
+	hasNPCItems bool
+
+	// npcItems maps NPC labels to the labels of the items they are holding,
+	// sorted. NPCs with no items are not included.
+	npcItems map[string]labelList
+
+	// hasTurn is whether the turn counter and the progress of events were
+	// saved. It will be false for progress saved before they were.
+	hasTurn bool
+
+	// turn is the number of turns that have passed.
+	turn int
+
+	// flaggedAt maps the labels of events scheduled by a flag to the turn
+	// that the flag was first found to be enabled.
+	flaggedAt map[string]int
+
+	// hasSentNPCs is whether the rooms that NPCs were sent to were saved. It
+	// will be false for progress saved before they were.
+	hasSentNPCs bool
+
+	// sentNPCs maps the labels of NPCs that were sent somewhere with $SEND()
+	// to the label of the room they were sent to.
+	sentNPCs map[string]savedLabel
+}
+
+// savedNPC is the progress of a single NPC.
+type savedNPC struct {
+	room string
+
+	// hasRoute is whether the route cursor has been initialized.
+	hasRoute bool
+	routeCur int
+
+	// inConvo is whether there is a conversation in progress with the NPC.
+	inConvo  bool
+	convoCur int
+}
+
+func (sn savedNPC) MarshalBinary() ([]byte, error) {
+	var data []byte
+
+	data = append(data, rezi.EncString(sn.room)...)
+	data = append(data, rezi.EncBool(sn.hasRoute)...)
+	data = append(data, rezi.EncInt(sn.routeCur)...)
+	data = append(data, rezi.EncBool(sn.inConvo)...)
+	data = append(data, rezi.EncInt(sn.convoCur)...)
+
+	return data, nil
+}
+
+func (sn *savedNPC) UnmarshalBinary(data []byte) error {
+	var decoded savedNPC
+	var n int
+	var err error
+
+	decoded.room, n, err = rezi.DecString(data)
+	if err != nil {
+		return fmt.Errorf("room: %w", err)
+	}
+	data = data[n:]
+
+	decoded.hasRoute, n, err = rezi.DecBool(data)
+	if err != nil {
+		return fmt.Errorf("route set: %w", err)
+	}
+	data = data[n:]
+
+	decoded.routeCur, n, err = rezi.DecInt(data)
+	if err != nil {
+		return fmt.Errorf("route position: %w", err)
+	}
+	data = data[n:]
+
+	decoded.inConvo, n, err = rezi.DecBool(data)
+	if err != nil {
+		return fmt.Errorf("conversation active: %w", err)
+	}
+	data = data[n:]
+
+	decoded.convoCur, _, err = rezi.DecInt(data)
+	if err != nil {
+		return fmt.Errorf("conversation position: %w", err)
+	}
+
+	*sn = decoded
+	return nil
+}
+
+// savedLabel is a single label that can be encoded as binary.
+type savedLabel string
+
+func (sl savedLabel) MarshalBinary() ([]byte, error) {
+	return rezi.EncString(string(sl)), nil
+}
+
+func (sl *savedLabel) UnmarshalBinary(data []byte) error {
+	decoded, _, err := rezi.DecString(data)
+	if err != nil {
+		return err
+	}
+
+	*sl = savedLabel(decoded)
+	return nil
+}
+
+// labelList is a list of labels that can be encoded as binary.
+type labelList []string
+
+func (ll labelList) MarshalBinary() ([]byte, error) {
+	return rezi.EncSliceString(ll), nil
+}
+
+func (ll *labelList) UnmarshalBinary(data []byte) error {
+	decoded, _, err := rezi.DecSliceString(data)
+	if err != nil {
+		return err
+	}
+	*ll = decoded
+	return nil
+}
+
+func (ss savedState) MarshalBinary() ([]byte, error) {
+	var data []byte
+
+	data = append(data, rezi.EncInt(SaveFormatVersion)...)
+	data = append(data, rezi.EncString(ss.currentRoom)...)
+	data = append(data, rezi.EncSliceString(ss.inventory)...)
+	data = append(data, rezi.EncMapStringToBinary(ss.roomItems)...)
+	data = append(data, rezi.EncMapStringToBinary(ss.npcs)...)
+	data = append(data, rezi.EncMapStringToBinary(ss.flags)...)
+	data = append(data, rezi.EncBool(ss.hasRandom)...)
+	data = append(data, rezi.EncInt(int(ss.seed))...)
+	data = append(data, rezi.EncInt(int(ss.randomState))...)
+	data = append(data, rezi.EncBool(ss.hasVisited)...)
+	data = append(data, rezi.EncSliceString(ss.visited)...)
+	data = append(data, rezi.EncBool(ss.hasLocks)...)
+	data = append(data, rezi.EncSliceString(ss.openLocks)...)
+	data = append(data, rezi.EncSliceString(ss.lockedLocks)...)
+	data = append(data, rezi.EncBool(ss.hasContainers)...)
+	data = append(data, rezi.EncMapStringToBinary(ss.containerItems)...)
+	data = append(data, rezi.EncBool(ss.hasNPCItems)...)
+	data = append(data, rezi.EncMapStringToBinary(ss.npcItems)...)
+	data = append(data, rezi.EncBool(ss.hasTurn)...)
+	data = append(data, rezi.EncInt(ss.turn)...)
+	data = append(data, rezi.EncMapStringToInt(ss.flaggedAt)...)
+	data = append(data, rezi.EncBool(ss.hasSentNPCs)...)
+	data = append(data, rezi.EncMapStringToBinary(ss.sentNPCs)...)
+
+	return data, nil
+}
+
+func (ss *savedState) UnmarshalBinary(data []byte) error {
+	var decoded savedState
+	var n int
+	var err error
+
+	var version int
+	version, n, err = rezi.DecInt(data)
+	if err != nil {
+		return fmt.Errorf("version: %w", err)
+	}
+	if version < 1 || version > SaveFormatVersion {
+		return fmt.Errorf("version: unsupported save format version %d", version)
+	}
+	data = data[n:]
+
+	decoded.currentRoom, n, err = rezi.DecString(data)
+	if err != nil {
+		return fmt.Errorf("current room: %w", err)
+	}
+	data = data[n:]
+
+	decoded.inventory, n, err = rezi.DecSliceString(data)
+	if err != nil {
+		return fmt.Errorf("inventory: %w", err)
+	}
+	data = data[n:]
+
+	var roomItems map[string]*labelList
+	roomItems, n, err = rezi.DecMapStringToBinary[*labelList](data)
+	if err != nil {
+		return fmt.Errorf("room items: %w", err)
+	}
+	data = data[n:]
+	decoded.roomItems = make(map[string]labelList, len(roomItems))
+	for k, v := range roomItems {
+		decoded.roomItems[k] = *v
+	}
+
+	var npcs map[string]*savedNPC
+	npcs, n, err = rezi.DecMapStringToBinary[*savedNPC](data)
+	if err != nil {
+		return fmt.Errorf("NPCs: %w", err)
+	}
+	data = data[n:]
+	decoded.npcs = make(map[string]savedNPC, len(npcs))
+	for k, v := range npcs {
+		decoded.npcs[k] = *v
+	}
+
+	var flags map[string]*tunascript.Value
+	flags, n, err = rezi.DecMapStringToBinary[*tunascript.Value](data)
+	if err != nil {
+		return fmt.Errorf("flags: %w", err)
+	}
+	data = data[n:]
+	decoded.flags = make(map[string]tunascript.Value, len(flags))
+	for k, v := range flags {
+		decoded.flags[k] = *v
+	}
+
+	if version >= 2 {
+		decoded.hasRandom, n, err = rezi.DecBool(data)
+		if err != nil {
+			return fmt.Errorf("random state set: %w", err)
+		}
+		data = data[n:]
+
+		var seed, randomState int
+		seed, n, err = rezi.DecInt(data)
+		if err != nil {
+			return fmt.Errorf("random seed: %w", err)
+		}
+		decoded.seed = int64(seed)
+		data = data[n:]
+
+		randomState, n, err = rezi.DecInt(data)
+		if err != nil {
+			return fmt.Errorf("random state: %w", err)
+		}
+		decoded.randomState = uint64(randomState)
+		data = data[n:]
+	}
+
+	if version >= 3 {
+		decoded.hasVisited, n, err = rezi.DecBool(data)
+		if err != nil {
+			return fmt.Errorf("visited rooms set: %w", err)
+		}
+		data = data[n:]
+
+		decoded.visited, n, err = rezi.DecSliceString(data)
+		if err != nil {
+			return fmt.Errorf("visited rooms: %w", err)
+		}
+		data = data[n:]
+	}
+
+	if version >= 4 {
+		decoded.hasLocks, n, err = rezi.DecBool(data)
+		if err != nil {
+			return fmt.Errorf("lock state set: %w", err)
+		}
+		data = data[n:]
+
+		decoded.openLocks, n, err = rezi.DecSliceString(data)
+		if err != nil {
+			return fmt.Errorf("open locks: %w", err)
+		}
+		data = data[n:]
+
+		decoded.lockedLocks, n, err = rezi.DecSliceString(data)
+		if err != nil {
+			return fmt.Errorf("locked locks: %w", err)
+		}
+		data = data[n:]
+	}
+
+	if version >= 5 {
+		decoded.hasContainers, n, err = rezi.DecBool(data)
+		if err != nil {
+			return fmt.Errorf("container items set: %w", err)
+		}
+		data = data[n:]
+
+		var containerItems map[string]*labelList
+		containerItems, n, err = rezi.DecMapStringToBinary[*labelList](data)
+		if err != nil {
+			return fmt.Errorf("container items: %w", err)
+		}
+		data = data[n:]
+		decoded.containerItems = make(map[string]labelList, len(containerItems))
+		for k, v := range containerItems {
+			decoded.containerItems[k] = *v
+		}
+	}
+
+	if version >= 6 {
+		decoded.hasNPCItems, n, err = rezi.DecBool(data)
+		if err != nil {
+			return fmt.Errorf("NPC items set: %w", err)
+		}
+		data = data[n:]
+
+		var npcItems map[string]*labelList
+		npcItems, n, err = rezi.DecMapStringToBinary[*labelList](data)
+		if err != nil {
+			return fmt.Errorf("NPC items: %w", err)
+		}
+		data = data[n:]
+		decoded.npcItems = make(map[string]labelList, len(npcItems))
+		for k, v := range npcItems {
+			decoded.npcItems[k] = *v
+		}
+	}
+
+	if version >= 7 {
+		decoded.hasTurn, n, err = rezi.DecBool(data)
+		if err != nil {
+			return fmt.Errorf("turn set: %w", err)
+		}
+		data = data[n:]
+
+		decoded.turn, n, err = rezi.DecInt(data)
+		if err != nil {
+			return fmt.Errorf("turn: %w", err)
+		}
+		data = data[n:]
+
+		decoded.flaggedAt, n, err = rezi.DecMapStringToInt(data)
+		if err != nil {
+			return fmt.Errorf("flagged events: %w", err)
+		}
+		data = data[n:]
+	}
+
+	if version >= 8 {
+		decoded.hasSentNPCs, n, err = rezi.DecBool(data)
+		if err != nil {
+			return fmt.Errorf("sent NPCs set: %w", err)
+		}
+		data = data[n:]
+
+		var sentNPCs map[string]*savedLabel
+		sentNPCs, n, err = rezi.DecMapStringToBinary[*savedLabel](data)
+		if err != nil {
+			return fmt.Errorf("sent NPCs: %w", err)
+		}
+		data = data[n:]
+		decoded.sentNPCs = make(map[string]savedLabel, len(sentNPCs))
+		for k, v := range sentNPCs {
+			decoded.sentNPCs[k] = *v
+		}
+	}
+
+	if len(data) > 0 {
+		return fmt.Errorf("%d extra bytes after end of saved state", len(data))
+	}
+
+	*ss = decoded
+	return nil
+}
+
+// snapshot gets the current progress of the game. The returned savedState
+// shares no memory with gs.
+func (gs *State) snapshot() savedState {
+	ss := savedState{
+		currentRoom: gs.CurrentRoom.Label,
+		inventory:   util.OrderedKeys(gs.Inventory),
+		roomItems:   make(map[string]labelList),
+		npcs:        make(map[string]savedNPC),
+		flags:       make(map[string]tunascript.Value),
+		hasRandom:   true,
+		seed:        gs.seed,
+		randomState: gs.randSrc.state,
+		hasVisited:  true,
+		visited:     util.OrderedKeys(gs.visited),
+		hasLocks:    true,
+
+		hasContainers:  true,
+		containerItems: make(map[string]labelList),
+		hasNPCItems:    true,
+		npcItems:       make(map[string]labelList),
+		hasTurn:        true,
+		turn:           gs.turn,
+		flaggedAt:      make(map[string]int),
+		hasSentNPCs:    true,
+		sentNPCs:       make(map[string]savedLabel),
+	}
+
+	for evLabel, turn := range gs.flaggedAt {
+		ss.flaggedAt[evLabel] = turn
+	}
+
+	for _, lockLabel := range util.OrderedKeys(gs.locks) {
+		if gs.locks[lockLabel].open {
+			ss.openLocks = append(ss.openLocks, lockLabel)
+		}
+		if gs.locks[lockLabel].locked {
+			ss.lockedLocks = append(ss.lockedLocks, lockLabel)
+		}
+	}
+
+	for roomLabel, r := range gs.World {
+		if len(r.Items) < 1 {
+			continue
+		}
+		items := make(labelList, len(r.Items))
+		for i := range r.Items {
+			items[i] = r.Items[i].Label
+		}
+		ss.roomItems[roomLabel] = items
+	}
+
+	for holderLabel, holder := range gs.containers {
+		c := holder.GetContainer()
+		if len(c.Items) < 1 {
+			continue
+		}
+		items := make(labelList, len(c.Items))
+		for i := range c.Items {
+			items[i] = c.Items[i].Label
+		}
+		ss.containerItems[holderLabel] = items
+	}
+
+	for npcLabel, roomLabel := range gs.npcLocations {
+		npc := gs.World[roomLabel].NPCs[npcLabel]
+
+		sn := savedNPC{room: roomLabel}
+		if npc.routeCur != nil {
+			sn.hasRoute = true
+			sn.routeCur = *npc.routeCur
+		}
+		if npc.Convo != nil {
+			sn.inConvo = true
+			sn.convoCur = npc.Convo.cur
+		}
+
+		ss.npcs[npcLabel] = sn
+
+		if npc.sentTo != "" {
+			ss.sentNPCs[npcLabel] = savedLabel(npc.sentTo)
+		}
+
+		if len(npc.Inventory) > 0 {
+			ss.npcItems[npcLabel] = util.OrderedKeys(npc.Inventory)
+		}
+	}
+
+	for _, fl := range gs.scripts.ListFlags() {
+		if IsEngineFlag(fl) {
+			continue
+		}
+		val, _ := gs.scripts.FlagValue(fl)
+		ss.flags[fl] = val
+	}
+
+	return ss
+}
+
+// restore sets the progress of the game to that in the given savedState. The
+// savedState must have been taken from a game using the same world as gs. If
+// it refers to anything that does not exist in the world of gs, or does not
+// give the location of something that does, a non-nil error is returned and gs
+// is not modified.
+func (gs *State) restore(ss savedState) error {
+	// gather every item and NPC so we can place them where the save says to
+	allItems := gs.allItems()
+	allNPCs := map[string]*NPC{}
+	for _, r := range gs.World {
+		for _, npc := range r.NPCs {
+			allNPCs[npc.Label] = npc
+		}
+	}
+
+	eventLabels := map[string]bool{}
+	for _, ev := range gs.events {
+		eventLabels[ev.Label] = true
+	}
+
+	// validate everything before touching gs
+	if _, ok := gs.World[ss.currentRoom]; !ok {
+		return fmt.Errorf("current room: no room with label %q exists in this world", ss.currentRoom)
+	}
+
+	for _, roomLabel := range ss.visited {
+		if _, ok := gs.World[roomLabel]; !ok {
+			return fmt.Errorf("visited rooms: no room with label %q exists in this world", roomLabel)
+		}
+	}
+
+	for _, lockLabel := range append(append([]string{}, ss.openLocks...), ss.lockedLocks...) {
+		if _, ok := gs.locks[lockLabel]; !ok {
+			return fmt.Errorf("locks: no lock with label %q exists in this world", lockLabel)
+		}
+	}
+
+	placedItems := map[string]bool{}
+	for _, itemLabel := range ss.inventory {
+		if _, ok := allItems[itemLabel]; !ok {
+			return fmt.Errorf("inventory: no item with label %q exists in this world", itemLabel)
+		}
+		placedItems[itemLabel] = true
+	}
+	for roomLabel, items := range ss.roomItems {
+		if _, ok := gs.World[roomLabel]; !ok {
+			return fmt.Errorf("items: no room with label %q exists in this world", roomLabel)
+		}
+		for _, itemLabel := range items {
+			if _, ok := allItems[itemLabel]; !ok {
+				return fmt.Errorf("room %q: no item with label %q exists in this world", roomLabel, itemLabel)
+			}
+			if placedItems[itemLabel] {
+				return fmt.Errorf("room %q: item %q is in more than one place", roomLabel, itemLabel)
+			}
+			placedItems[itemLabel] = true
+		}
+	}
+	for npcLabel, items := range ss.npcItems {
+		if _, ok := allNPCs[npcLabel]; !ok {
+			return fmt.Errorf("items: no NPC with label %q exists in this world", npcLabel)
+		}
+		for _, itemLabel := range items {
+			if _, ok := allItems[itemLabel]; !ok {
+				return fmt.Errorf("NPC %q: no item with label %q exists in this world", npcLabel, itemLabel)
+			}
+			if placedItems[itemLabel] {
+				return fmt.Errorf("NPC %q: item %q is in more than one place", npcLabel, itemLabel)
+			}
+			placedItems[itemLabel] = true
+		}
+	}
+	savedItemLocations := map[string]string{}
+	for holderLabel, items := range ss.containerItems {
+		if _, ok := gs.containers[holderLabel]; !ok {
+			return fmt.Errorf("items: no container with label %q exists in this world", holderLabel)
+		}
+		for _, itemLabel := range items {
+			if _, ok := allItems[itemLabel]; !ok {
+				return fmt.Errorf("container %q: no item with label %q exists in this world", holderLabel, itemLabel)
+			}
+			if placedItems[itemLabel] {
+				return fmt.Errorf("container %q: item %q is in more than one place", holderLabel, itemLabel)
+			}
+			placedItems[itemLabel] = true
+			savedItemLocations[itemLabel] = holderLabel
+		}
+	}
+	for _, itemLabel := range util.OrderedKeys(savedItemLocations) {
+		// follow the containers outward; if we get back to where we started,
+		// the item is inside of itself
+		loc := savedItemLocations[itemLabel]
+		for steps := 0; loc != ""; steps++ {
+			if loc == itemLabel || steps > len(savedItemLocations) {
+				return fmt.Errorf("container %q: item %q is inside of itself", savedItemLocations[itemLabel], itemLabel)
+			}
+			loc = savedItemLocations[loc]
+		}
+	}
+	if len(placedItems) != len(allItems) {
+		for _, itemLabel := range util.OrderedKeys(allItems) {
+			if !placedItems[itemLabel] {
+				return fmt.Errorf("items: item %q is not placed anywhere", itemLabel)
+			}
+		}
+	}
+
+	for npcLabel, sn := range ss.npcs {
+		npc, ok := allNPCs[npcLabel]
+		if !ok {
+			return fmt.Errorf("NPCs: no NPC with label %q exists in this world", npcLabel)
+		}
+		if _, ok := gs.World[sn.room]; !ok {
+			return fmt.Errorf("NPC %q: no room with label %q exists in this world", npcLabel, sn.room)
+		}
+		if sn.inConvo && (sn.convoCur < 0 || sn.convoCur > len(npc.Dialog)) {
+			return fmt.Errorf("NPC %q: conversation position %d is outside of dialog tree", npcLabel, sn.convoCur)
+		}
+	}
+	for npcLabel, dest := range ss.sentNPCs {
+		if _, ok := allNPCs[npcLabel]; !ok {
+			return fmt.Errorf("sent NPCs: no NPC with label %q exists in this world", npcLabel)
+		}
+		if _, ok := gs.World[string(dest)]; !ok {
+			return fmt.Errorf("NPC %q: sent to room %q, but no room with that label exists in this world", npcLabel, dest)
+		}
+	}
+	if ss.turn < 0 {
+		return fmt.Errorf("turn: turn %d is negative", ss.turn)
+	}
+	for evLabel := range ss.flaggedAt {
+		if _, ok := eventLabels[evLabel]; !ok {
+			return fmt.Errorf("events: no event with label %q exists in this world", evLabel)
+		}
+	}
+
+	if len(ss.npcs) != len(allNPCs) {
+		for _, npcLabel := range util.OrderedKeys(allNPCs) {
+			if _, ok := ss.npcs[npcLabel]; !ok {
+				return fmt.Errorf("NPCs: NPC %q is not placed anywhere", npcLabel)
+			}
+		}
+	}
+
+	// everything checks out, apply it
+	gs.CurrentRoom = gs.World[ss.currentRoom]
+
+	gs.visited = make(map[string]bool)
+	if ss.hasVisited {
+		for _, roomLabel := range ss.visited {
+			gs.visited[roomLabel] = true
+		}
+	}
+	// progress saved before visited rooms were only knows about the current
+	// one
+	gs.visited[ss.currentRoom] = true
+
+	// progress saved before locks were has them as they start out
+	gs.initLocks()
+	if ss.hasLocks {
+		for _, ls := range gs.locks {
+			ls.open = false
+			ls.locked = false
+		}
+		for _, lockLabel := range ss.openLocks {
+			gs.locks[lockLabel].open = true
+		}
+		for _, lockLabel := range ss.lockedLocks {
+			gs.locks[lockLabel].locked = true
+		}
+	}
+
+	gs.Inventory = make(Inventory)
+	gs.itemLocations = make(map[string]string)
+	for _, r := range gs.World {
+		r.Items = nil
+		r.NPCs = make(map[string]*NPC)
+	}
+	for _, holder := range gs.containers {
+		holder.GetContainer().Items = nil
+	}
+
+	for _, itemLabel := range ss.inventory {
+		gs.Inventory[itemLabel] = allItems[itemLabel]
+		gs.itemLocations[itemLabel] = "@INVEN"
+	}
+	for roomLabel, items := range ss.roomItems {
+		r := gs.World[roomLabel]
+		for _, itemLabel := range items {
+			r.Items = append(r.Items, allItems[itemLabel])
+			gs.itemLocations[itemLabel] = roomLabel
+		}
+	}
+	for holderLabel, items := range ss.containerItems {
+		c := gs.containers[holderLabel].GetContainer()
+		for _, itemLabel := range items {
+			c.Items = append(c.Items, allItems[itemLabel])
+			gs.itemLocations[itemLabel] = holderLabel
+		}
+	}
+
+	gs.npcLocations = make(map[string]string)
+	for npcLabel, sn := range ss.npcs {
+		npc := allNPCs[npcLabel]
+
+		npc.routeCur = nil
+		if sn.hasRoute {
+			npc.routeCur = new(int)
+			*npc.routeCur = sn.routeCur
+		}
+
+		npc.Convo = nil
+		if sn.inConvo {
+			npc.Convo = &Conversation{Dialog: npc.Dialog, cur: sn.convoCur}
+		}
+
+		npc.sentTo = string(ss.sentNPCs[npcLabel])
+
+		npc.Inventory = make(Inventory)
+		for _, itemLabel := range ss.npcItems[npcLabel] {
+			npc.Inventory[itemLabel] = allItems[itemLabel]
+			gs.itemLocations[itemLabel] = npcLabel
+		}
+
+		gs.World[sn.room].NPCs[npcLabel] = npc
+		gs.npcLocations[npcLabel] = sn.room
+	}
+
+	for _, fl := range gs.scripts.ListFlags() {
+		gs.scripts.RemoveFlag(fl)
+	}
+	for _, fl := range util.OrderedKeys(ss.flags) {
+		gs.scripts.SetFlagValue(fl, ss.flags[fl])
+	}
+
+	// progress saved before the turn counter was starts counting again from
+	// the beginning
+	gs.turn = ss.turn
+	gs.flaggedAt = make(map[string]int)
+	for evLabel, turn := range ss.flaggedAt {
+		gs.flaggedAt[evLabel] = turn
+	}
+
+	if ss.hasRandom {
+		gs.seed = ss.seed
+		gs.randSrc.state = ss.randomState
+	}
+
+	return nil
+}
+
+// MarshalBinary converts the progress of the game into a slice of bytes that
+// can be restored with UnmarshalBinary. Only things that change during play
+// are included, such as the current room, the inventory, where every item and
+// NPC is, NPC route and conversation positions and the rooms NPCs were sent
+// to, the value of every flag, the rooms the player has been in, which locks
+// are open and locked, what is in every container and held by every NPC, the
+// number of turns that have passed and the progress of scheduled events, and
+// the state of the random source; the definition of the world itself is not.
+//
+// If gs was not created with New but instead had progress decoded into it with
+// UnmarshalBinary, that progress is encoded as-is.
+func (gs *State) MarshalBinary() ([]byte, error) {
+	if gs.World == nil {
+		if gs.pendingRestore == nil {
+			return nil, fmt.Errorf("game state has no world loaded and no saved progress")
+		}
+		return gs.pendingRestore.MarshalBinary()
+	}
+
+	return gs.snapshot().MarshalBinary()
+}
+
+// UnmarshalBinary restores the progress of a game from a slice of bytes
+// created with MarshalBinary. If gs was created with New, the progress is
+// applied to it immediately; it must be for the same world that gs was created
+// with or an error is returned and gs is not modified.
+//
+// If gs was not created with New (for instance, if it is a zero-value State
+// being decoded from persistent storage), the progress is retained as-is until
+// it is given to Resume on a State that was.
+func (gs *State) UnmarshalBinary(data []byte) error {
+	var ss savedState
+	if err := ss.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	if gs.World == nil {
+		gs.pendingRestore = &ss
+		return nil
+	}
+
+	return gs.restore(ss)
+}
+
+// Resume applies the progress held by saved to gs. saved must be a State that
+// was not created with New but which had progress decoded into it with
+// UnmarshalBinary, and gs must have been created with New using the same world
+// that the progress was saved from. If the progress cannot be applied, an error
+// is returned and gs is not modified.
+func (gs *State) Resume(saved *State) error {
+	if saved.pendingRestore == nil {
+		return fmt.Errorf("there is no saved progress to resume")
+	}
+
+	return gs.restore(*saved.pendingRestore)
+}
@@ -0,0 +1,226 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_State_MarshalUnmarshalBinary(t *testing.T) {
+	testCases := []struct {
+		name     string
+		world    func() worldBuilder
+		start    string
+		flags    map[string]string
+		events   []*Event
+		seed     int64
+		commands []string
+
+		// progress makes any progress that can't be made with commands.
+		progress func(gs *State)
+
+		// expectTrue is TunaScript expressions that must be true in the
+		// restored game.
+		expectTrue []string
+	}{
+		{
+			name:  "new game",
+			world: testWorld,
+			start: "KITCHEN",
+		},
+		{
+			name:       "player, items, and flags",
+			world:      testWorld,
+			start:      "KITCHEN",
+			flags:      map[string]string{"SCORE": "2", "NAME": "true"},
+			commands:   []string{"take fork", "go hall", "debug exec $SCORE += 3"},
+			expectTrue: []string{"$IN_INVEN(FORK)", "$NOT($IN_INVEN(SPOON))", "$VISITED(HALL)", "$FLAG_IS(SCORE, 5)", "$NAME"},
+		},
+		{
+			name: "NPC routes and conversations",
+			world: func() worldBuilder {
+				world := testWorld()
+				world["KITCHEN"].NPCs["CHEF"].Movement = Route{Action: RoutePatrol, Path: []string{"HALL", "KITCHEN"}}
+				return world
+			},
+			start: "KITCHEN",
+			progress: func(gs *State) {
+				gs.MoveNPCs()
+				chef := gs.World["HALL"].NPCs["CHEF"]
+				chef.Convo = &Conversation{Dialog: chef.Dialog, cur: 1}
+			},
+		},
+		{
+			name:  "random source",
+			world: wanderWorld,
+			start: "NORTH",
+			seed:  612,
+			progress: func(gs *State) {
+				wanderPath(gs, 5)
+			},
+		},
+		{
+			name:       "locks",
+			world:      lockedDoorWorld,
+			start:      "KITCHEN",
+			commands:   []string{"take spoon", "unlock door with spoon", "open door", "go hall", "close door", "lock door"},
+			expectTrue: []string{"$IS_LOCKED(KITCHEN_TO_HALL)", "$NOT($IS_OPEN(HALL_DOOR))", "$VISITED(HALL)"},
+		},
+		{
+			name:       "containers",
+			world:      containerWorld,
+			start:      "KITCHEN",
+			commands:   []string{"open cupboard", "take spoon", "debug exec $MOVE(CUP, BAG)", "debug exec $MOVE(BAG, CUPBOARD)"},
+			expectTrue: []string{"$IS_OPEN(CUPBOARD)", "$IN_INVEN(SPOON)"},
+		},
+		{
+			name:       "NPC inventories",
+			world:      giveWorld,
+			start:      "KITCHEN",
+			commands:   []string{"take spoon", "give spoon to chef"},
+			expectTrue: []string{"$NPC_HAS(CHEF, SPOON)", "$NPC_HAS(CHEF, LADLE)", "$NOT($IN_INVEN(SPOON))"},
+		},
+		{
+			name:  "events",
+			world: testWorld,
+			start: "KITCHEN",
+			events: []*Event{
+				{Label: "COLLAPSE", AfterFlag: "CROSSED", Delay: 2, Do: mustParseScript("$INC(COLLAPSES)")},
+				{Label: "BELL", AtTurn: 2, EveryNTurns: 3, Do: mustParseScript("$INC(BELLS)")},
+			},
+			commands:   []string{"debug exec $ENABLE(CROSSED)", "wait", "wait"},
+			expectTrue: []string{"$FLAG_IS(BELLS, 1)"},
+		},
+		{
+			name:     "sent NPCs",
+			world:    func() worldBuilder { return squareWorld(Route{Action: RouteGoto, Target: "SOUTH"}, false) },
+			start:    "NORTH",
+			commands: []string{"debug exec $SEND(GUARD, @EAST@)"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			newGame := func() *State {
+				gs, err := New(tc.world(), tc.start, tc.flags, &nopIODevice{})
+				if !assert.NoError(err) {
+					t.FailNow()
+				}
+				gs.SetEvents(tc.events)
+				return gs
+			}
+
+			played := newGame()
+			if tc.seed != 0 {
+				played.SetSeed(tc.seed)
+			}
+			if !assert.NoError(playCommands(played, tc.commands...)) {
+				return
+			}
+			if tc.progress != nil {
+				tc.progress(played)
+			}
+
+			data, err := played.MarshalBinary()
+			if !assert.NoError(err) {
+				return
+			}
+
+			restored := newGame()
+			if !assert.NoError(restored.UnmarshalBinary(data)) {
+				return
+			}
+
+			reencoded, err := restored.MarshalBinary()
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(data, reencoded)
+			assert.Equal(played.CurrentRoom.Label, restored.CurrentRoom.Label)
+			assertScripts(assert, restored, tc.expectTrue)
+
+			// both games must carry on the same way
+			for i := 0; i < 5; i++ {
+				if !assert.NoError(playCommands(played, "wait")) {
+					return
+				}
+				if !assert.NoError(playCommands(restored, "wait")) {
+					return
+				}
+			}
+			playedData, err := played.MarshalBinary()
+			if !assert.NoError(err) {
+				return
+			}
+			restoredData, err := restored.MarshalBinary()
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(playedData, restoredData)
+		})
+	}
+}
+
+func Test_State_UnmarshalBinary_noWorld(t *testing.T) {
+	assert := assert.New(t)
+
+	played, err := New(testWorld(), "KITCHEN", nil, &nopIODevice{})
+	if !assert.NoError(err) {
+		return
+	}
+	played.CurrentRoom = played.World["HALL"]
+	data, err := played.MarshalBinary()
+	if !assert.NoError(err) {
+		return
+	}
+
+	// decoding into a zero State retains the progress without a world
+	var stored State
+	err = stored.UnmarshalBinary(data)
+	if !assert.NoError(err) {
+		return
+	}
+	reencoded, err := stored.MarshalBinary()
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(data, reencoded)
+
+	fresh, err := New(testWorld(), "KITCHEN", nil, &nopIODevice{})
+	if !assert.NoError(err) {
+		return
+	}
+	err = fresh.Resume(&stored)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal("HALL", fresh.CurrentRoom.Label)
+}
+
+func Test_State_UnmarshalBinary_differentWorld(t *testing.T) {
+	assert := assert.New(t)
+
+	played, err := New(testWorld(), "KITCHEN", nil, &nopIODevice{})
+	if !assert.NoError(err) {
+		return
+	}
+	data, err := played.MarshalBinary()
+	if !assert.NoError(err) {
+		return
+	}
+
+	otherWorld := testWorld()
+	otherWorld["KITCHEN"].Items = otherWorld["KITCHEN"].Items[:1]
+	other, err := New(otherWorld, "HALL", nil, &nopIODevice{})
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = other.UnmarshalBinary(data)
+	assert.Error(err)
+
+	// state must be unmodified
+	assert.Equal("HALL", other.CurrentRoom.Label)
+}
@@ -11,10 +11,58 @@ func (sb scriptBackend) InInventory(item string) bool {
 	return ok
 }
 
+func (sb scriptBackend) Visited(room string) bool {
+	return sb.game.visited[strings.ToUpper(room)]
+}
+
+func (sb scriptBackend) IsLocked(label string) bool {
+	ls := sb.game.lockStateOf(label)
+	return ls != nil && ls.locked
+}
+
+func (sb scriptBackend) IsOpen(label string) bool {
+	ls := sb.game.lockStateOf(label)
+	return ls != nil && ls.open
+}
+
+func (sb scriptBackend) NPCHas(npc, item string) bool {
+	n := sb.game.npcByLabel(strings.ToUpper(npc))
+	if n == nil {
+		return false
+	}
+
+	item = strings.ToUpper(item)
+	for itemLabel := range n.Inventory {
+		if itemLabel == item || (strings.HasPrefix(item, "@") && sb.game.HasTag(itemLabel, item)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sb scriptBackend) Turn() int {
+	return sb.game.turn
+}
+
 func (sb scriptBackend) Move(target, dest string) bool {
 	target = strings.ToUpper(target)
 	dest = strings.ToUpper(dest)
 
+	// item? they can also go in the inventory or in containers
+	if loc, ok := sb.game.itemLocations[target]; ok {
+		if !sb.game.isItemLocation(dest) {
+			// TODO: don't fail silently
+			return false
+		}
+		if loc == dest || dest == target || sb.game.containerHolds(target, dest) {
+			return false
+		}
+
+		item := sb.game.removeItem(target)
+		sb.game.placeItem(item, dest)
+		return true
+	}
+
 	if _, ok := sb.game.World[dest]; !ok {
 		// TODO: don't fail silently
 		return false
@@ -23,56 +71,40 @@ func (sb scriptBackend) Move(target, dest string) bool {
 		if sb.game.CurrentRoom.Label == dest {
 			return false
 		}
-		sb.game.CurrentRoom = sb.game.World[dest]
+		sb.game.movePlayer(sb.game.World[dest])
 		return true
-	} else {
-		// item?
-		if roomLabel, ok := sb.game.itemLocations[target]; ok {
-			if roomLabel == dest {
-				return false
-			}
-
-			var item *Item
-			if roomLabel == "@INVEN" {
-				// it DOES move from backpack
-				item = sb.game.Inventory[target]
-				delete(sb.game.Inventory, item.Label)
-			} else {
-				// get the item
-				for _, it := range sb.game.World[roomLabel].Items {
-					if it.Label == target {
-						item = it
-						break
-					}
-				}
-				sb.game.World[roomLabel].RemoveItem(target)
-			}
-
-			if dest == "@INVEN" {
-				sb.game.Inventory[target] = item
-			} else {
-				sb.game.World[dest].Items = append(sb.game.World[dest].Items, item)
-			}
-			sb.game.itemLocations[target] = dest
+	}
 
-			return true
-		}
+	// npc?
+	roomLabel, ok := sb.game.npcLocations[target]
+	if !ok {
+		return false
+	}
+	if roomLabel == dest {
+		return false
+	}
 
-		// npc?
-		roomLabel, ok := sb.game.npcLocations[target]
-		if !ok {
-			return false
-		}
-		if roomLabel == dest {
-			return false
-		}
+	npc := sb.game.World[roomLabel].NPCs[target]
+	delete(sb.game.World[roomLabel].NPCs, npc.Label)
+	sb.game.World[dest].NPCs[npc.Label] = npc
+	sb.game.npcLocations[target] = dest
+	return true
+}
 
-		npc := sb.game.World[roomLabel].NPCs[target]
-		delete(sb.game.World[roomLabel].NPCs, npc.Label)
-		sb.game.World[dest].NPCs[npc.Label] = npc
-		sb.game.npcLocations[target] = dest
-		return true
+func (sb scriptBackend) Send(npc, dest string) bool {
+	n := sb.game.npcByLabel(strings.ToUpper(npc))
+	if n == nil {
+		// TODO: don't fail silently
+		return false
 	}
+	dest = strings.ToUpper(dest)
+	if _, ok := sb.game.World[dest]; !ok {
+		// TODO: don't fail silently
+		return false
+	}
+
+	n.sentTo = dest
+	return true
 }
 
 func (sb scriptBackend) Output(s string) bool {
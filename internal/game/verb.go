@@ -0,0 +1,151 @@
+package game
+
+// File verb.go contains symbols for verbs that a world defines in addition to
+// the built-in ones.
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/rosed"
+	"github.com/dekarrin/tunaq/internal/command"
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// CustomVerb is a verb that a world defines, such as PUSH or READ. What it
+// does is given by the VerbActions for it on the rooms, items, NPCs, and
+// details in the world.
+type CustomVerb struct {
+	// Def is how commands that use the verb are parsed.
+	Def command.VerbDef
+
+	// Help is the description of the verb shown by HELP. If it is empty, the
+	// verb is not shown.
+	Help string
+
+	// Default is the message shown to the player when nothing they use the
+	// verb on reacts to it. If it is empty, a generic message is shown
+	// instead.
+	Default string
+
+	// tmplDefault is the precomputed template AST for the default text. It
+	// must generally be filled in with the game engine, and will not be
+	// present directly when loaded from disk.
+	tmplDefault *tunascript.Template
+}
+
+// SetVerbs sets the verbs that the world defines. It should be called after New
+// and before the first command is given. Commands that use them can only be
+// parsed with a command.Vocabulary that has had their definitions given to it.
+func (gs *State) SetVerbs(verbs []*CustomVerb) error {
+	gs.verbs = make(map[string]*CustomVerb, len(verbs))
+	for _, v := range verbs {
+		defaultComp, err := gs.preParseTemplate(v.Default)
+		if err != nil {
+			return fmt.Errorf("verb %q: default: %w", v.Def.Name, err)
+		}
+		v.tmplDefault = defaultComp
+		gs.verbs[v.Def.Name] = v
+	}
+
+	return nil
+}
+
+// verbActions returns the VerbActions of t. If t can't have any, nil is
+// returned.
+func verbActions(t Targetable) []VerbAction {
+	switch tgt := t.(type) {
+	case *Item:
+		return tgt.OnVerb
+	case *NPC:
+		return tgt.OnVerb
+	case *Detail:
+		return tgt.OnVerb
+	default:
+		return nil
+	}
+}
+
+// findVerbAction returns the VerbAction in actions that applies to using verb
+// with instrument, which is nil if there isn't one. It is chosen from the ones
+// for verb as described in findReaction. If none apply, nil is returned.
+func (gs *State) findVerbAction(actions []VerbAction, verb string, instrument Targetable) *VerbAction {
+	var forVerb []VerbAction
+	for _, act := range actions {
+		if act.Verb == verb {
+			forVerb = append(forVerb, act)
+		}
+	}
+
+	var label string
+	if instrument != nil {
+		label = instrument.GetLabel()
+	}
+	return findReaction(gs, forVerb, label)
+}
+
+// ExecuteCommandCustom executes a command that uses a verb defined by the
+// world and returns the output. The reaction of the recipient is used if there
+// is one, or the reaction of the current room if the command has no
+// recipient. If nothing reacts, a non-nil error giving the default message of
+// the verb is returned.
+func (gs *State) ExecuteCommandCustom(cmd command.Command) (string, error) {
+	verb, ok := gs.verbs[cmd.Verb]
+	if !ok {
+		return "", tqerrors.Interpreterf("I don't know how to %q", cmd.Verb)
+	}
+
+	var recipient, instrument Targetable
+	var err error
+	if cmd.Recipient != "" {
+		recipient, err = gs.findTarget(cmd.Recipient, true)
+		if err != nil {
+			return "", err
+		}
+		if recipient == nil {
+			return "", tqerrors.Interpreterf("I don't see any %q here", cmd.Recipient)
+		}
+	}
+	if cmd.Instrument != "" {
+		instrument, err = gs.findTarget(cmd.Instrument, true)
+		if err != nil {
+			return "", err
+		}
+		if instrument == nil {
+			return "", tqerrors.Interpreterf("I don't see any %q here", cmd.Instrument)
+		}
+	}
+
+	var act *VerbAction
+	if recipient != nil {
+		act = gs.findVerbAction(verbActions(recipient), cmd.Verb, instrument)
+	} else {
+		act = gs.findVerbAction(gs.CurrentRoom.OnVerb, cmd.Verb, instrument)
+	}
+
+	if act == nil {
+		if verb.tmplDefault != nil {
+			if msg := strings.TrimSpace(gs.Expand(verb.tmplDefault)); msg != "" {
+				return "", tqerrors.Interpreterf("%s", msg)
+			}
+		}
+		if recipient != nil {
+			return "", tqerrors.Interpreterf("You can't %s the %s", strings.ToLower(cmd.Verb), strings.ToLower(cmd.Recipient))
+		}
+		return "", tqerrors.Interpreterf("Nothing happens")
+	}
+
+	return gs.runVerbAction(act), nil
+}
+
+// runVerbAction executes the Do of act and returns output that gives the
+// response of act and anything that was output with $OUTPUT().
+func (gs *State) runVerbAction(act *VerbAction) string {
+	paragraphs := gs.runReaction(act)
+	if len(paragraphs) < 1 {
+		paragraphs = append(paragraphs, "Something happened!")
+	}
+
+	return rosed.Edit(strings.Join(paragraphs, "\n\n")).WithOptions(textFormatOptions).Wrap(gs.io.Width()).String()
+}
@@ -0,0 +1,64 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/dekarrin/tunaq/internal/command"
+)
+
+func Test_State_customVerbs(t *testing.T) {
+	newGame := func(ioDev IODevice) (*State, error) {
+		world := testWorld()
+		world["KITCHEN"].Items[0].OnVerb = []VerbAction{
+			{Verb: "POLISH", Response: "It shines."},
+			{Verb: "POLISH", With: []string{"FORK"}, Response: "That just scratches it.", Do: mustParseScript("$ENABLE(SCRATCHED)")},
+		}
+		world["KITCHEN"].OnVerb = []VerbAction{{Verb: "JUMP", Response: "You hit your head on a pan."}}
+
+		gs, err := New(world, "KITCHEN", nil, ioDev)
+		if err != nil {
+			return nil, err
+		}
+		err = gs.SetVerbs([]*CustomVerb{
+			{Def: command.VerbDef{Name: "POLISH", Recipient: command.ArgRequired, Preposition: "WITH", Instrument: command.ArgOptional}},
+			{Def: command.VerbDef{Name: "JUMP", Recipient: command.ArgNone}, Help: "jump up and down"},
+			{Def: command.VerbDef{Name: "EAT", Recipient: command.ArgRequired}, Default: "You aren't hungry."},
+		})
+		return gs, err
+	}
+
+	runCommandTests(t, newGame, []commandTest{
+		{
+			name:         "reaction with no instrument",
+			cmd:          "polish spoon",
+			expectOutput: "It shines.",
+			expectTrue:   []string{"$NOT($SCRATCHED)"},
+		},
+		{
+			name:         "instrument reaction is used over catch-all",
+			cmd:          "polish spoon with fork",
+			expectOutput: "That just scratches it.",
+			expectTrue:   []string{"$SCRATCHED"},
+		},
+		{
+			name:         "room handles verbs used on nothing",
+			cmd:          "jump",
+			expectOutput: "You hit your head on a pan.",
+		},
+		{
+			name:      "nothing reacts",
+			cmd:       "polish fork",
+			expectErr: "You can't polish the fork",
+		},
+		{
+			name:      "nothing reacts with a default",
+			cmd:       "eat spoon",
+			expectErr: "You aren't hungry.",
+		},
+		{
+			name:         "shown in help",
+			cmd:          "help",
+			expectOutput: "jump up and down",
+		},
+	})
+}
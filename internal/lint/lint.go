@@ -0,0 +1,544 @@
+// Package lint checks a loaded TunaQuest world for mistakes that are not
+// severe enough to stop it from loading, but which are almost certainly not
+// what the author intended, such as rooms that can never be reached or flags
+// that are checked but never set.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dekarrin/tunaq/internal/game"
+	"github.com/dekarrin/tunaq/internal/tqw"
+	"github.com/dekarrin/tunaq/internal/util"
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// Warning is a single problem found in a world.
+type Warning struct {
+	// Location is a description of where in the world the problem is, such
+	// as "room KITCHEN".
+	Location string
+
+	// Message describes the problem.
+	Message string
+}
+
+// String returns the Warning as a single line of human-readable text.
+func (w Warning) String() string {
+	return w.Location + ": " + w.Message
+}
+
+// builtInTags is the tags that are automatically given to things in the world.
+var builtInTags = []string{"@ITEM", "@NPC", "@DETAIL", "@EXIT", game.TagPlayer, game.TagSelf}
+
+// autoLabelPrefix is the start of the labels that tqw gives to exits and details
+// that do not have one.
+const autoLabelPrefix = "__TUNAQUEST_AUTO__"
+
+// flagUse is the flag usage of a single piece of tunascript in the world along
+// with where it is.
+type flagUse struct {
+	location string
+	usage    tunascript.FlagUsage
+}
+
+// checker holds everything gathered about a world while checking it.
+type checker struct {
+	world tqw.WorldData
+
+	warnings []Warning
+
+	// uses is every piece of tunascript in the world, including that in
+	// templates.
+	uses []flagUse
+
+	// written is the labels of every flag that is written to by a script.
+	written map[string]bool
+
+	// read is the labels of every flag that is read.
+	read map[string]bool
+}
+
+// Check examines the world for mistakes and returns a Warning for each one
+// found. Warnings are ordered by the kind of check that found them and then
+// by location.
+func Check(world tqw.WorldData) []Warning {
+	c := &checker{
+		world:   world,
+		written: map[string]bool{},
+		read:    map[string]bool{},
+	}
+
+	c.gatherFlagUses()
+
+	c.checkReachability()
+	c.checkConditions()
+	c.checkFlags()
+	c.checkDialog()
+	c.checkUseActions()
+
+	return c.warnings
+}
+
+func (c *checker) warn(location string, format string, a ...interface{}) {
+	c.warnings = append(c.warnings, Warning{Location: location, Message: fmt.Sprintf(format, a...)})
+}
+
+// gatherFlagUses finds every flag that is read or written to by any
+// tunascript in the world.
+func (c *checker) gatherFlagUses() {
+	var interp tunascript.Interpreter
+
+	addAST := func(loc string, ast tunascript.AST) {
+		c.uses = append(c.uses, flagUse{location: loc, usage: tunascript.Flags(ast)})
+	}
+	addTemplate := func(loc string, text string) {
+		tmpl, err := interp.ParseTemplate(text)
+		if err != nil {
+			// the world would not have loaded if this were a real problem;
+			// nothing to find here.
+			return
+		}
+		c.uses = append(c.uses, flagUse{location: loc, usage: tunascript.TemplateFlags(tmpl)})
+	}
+	addVerbActions := func(loc string, actions []game.VerbAction) {
+		for i, act := range actions {
+			actLoc := fmt.Sprintf("%s, on_verb[%d]", loc, i)
+			addAST(actLoc, act.If)
+			addAST(actLoc, act.Do)
+			addTemplate(actLoc, act.Response)
+		}
+	}
+
+	for _, roomLabel := range util.OrderedKeys(c.world.Rooms) {
+		r := c.world.Rooms[roomLabel]
+		roomLoc := "room " + r.Label
+
+		addTemplate(roomLoc, r.Description)
+		addAST(roomLoc+", on_enter", r.OnEnter)
+		addAST(roomLoc+", on_first_enter", r.OnFirstEnter)
+		addAST(roomLoc+", on_exit", r.OnExit)
+		addVerbActions(roomLoc, r.OnVerb)
+
+		for _, eg := range r.Exits {
+			loc := roomLoc + ", " + exitName(eg)
+			addAST(loc, eg.If)
+			addTemplate(loc, eg.Description)
+			addTemplate(loc, eg.TravelMessage)
+			if eg.Lock != nil {
+				addAST(loc+", lock", eg.Lock.If)
+			}
+		}
+		for _, det := range r.Details {
+			loc := roomLoc + ", " + detailName(det)
+			addAST(loc, det.If)
+			addTemplate(loc, det.Description)
+			if det.Lock != nil {
+				addAST(loc+", lock", det.Lock.If)
+			}
+			addVerbActions(loc, det.OnVerb)
+		}
+		for _, it := range r.AllItems() {
+			loc := "item " + it.Label
+			addAST(loc, it.If)
+			addTemplate(loc, it.Description)
+			for i, ua := range it.OnUse {
+				uaLoc := fmt.Sprintf("%s, on_use[%d]", loc, i)
+				addAST(uaLoc, ua.If)
+				addAST(uaLoc, ua.Do)
+			}
+			hooks := itemHooks(it)
+			for _, hookName := range util.OrderedKeys(hooks) {
+				hook := hooks[hookName]
+				hookLoc := loc + ", " + hookName
+				addAST(hookLoc, hook.If)
+				addAST(hookLoc, hook.Do)
+				addTemplate(hookLoc, hook.Refusal)
+			}
+			if it.Lock != nil {
+				addAST(loc+", lock", it.Lock.If)
+			}
+			addVerbActions(loc, it.OnVerb)
+		}
+		for _, npcLabel := range util.OrderedKeys(r.NPCs) {
+			npc := r.NPCs[npcLabel]
+			loc := "NPC " + npc.Label
+			addAST(loc, npc.If)
+			addTemplate(loc, npc.Description)
+			addTemplate(loc+", enter_message", npc.EnterMessage)
+			addTemplate(loc+", leave_message", npc.LeaveMessage)
+			for i, step := range npc.Dialog {
+				stepLoc := fmt.Sprintf("%s, line[%d]", loc, i)
+				addAST(stepLoc, step.If)
+				addAST(stepLoc, step.Do)
+				addTemplate(stepLoc, step.Content)
+				addTemplate(stepLoc, step.Response)
+				for _, ch := range step.Choices {
+					addTemplate(stepLoc, ch[0])
+				}
+				for j, cs := range step.ChoiceScripts {
+					chLoc := fmt.Sprintf("%s, choice[%d]", stepLoc, j)
+					addAST(chLoc, cs.If)
+					addAST(chLoc, cs.Do)
+				}
+			}
+			reactions := npcReactions(npc)
+			for _, key := range util.OrderedKeys(reactions) {
+				for i, act := range reactions[key] {
+					actLoc := fmt.Sprintf("%s, %s[%d]", loc, key, i)
+					addAST(actLoc, act.If)
+					addAST(actLoc, act.Do)
+					addTemplate(actLoc, act.Response)
+				}
+			}
+			addVerbActions(loc, npc.OnVerb)
+		}
+	}
+
+	for _, v := range c.world.Verbs {
+		addTemplate("verb "+v.Def.Name+", default", v.Default)
+	}
+
+	for _, ev := range c.world.Events {
+		loc := "event " + ev.Label
+		addAST(loc, ev.If)
+		addAST(loc, ev.Do)
+		if ev.AfterFlag != "" {
+			c.uses = append(c.uses, flagUse{location: loc + ", after_flag", usage: tunascript.FlagUsage{Read: []string{ev.AfterFlag}}})
+		}
+	}
+
+	for _, use := range c.uses {
+		for _, fl := range use.usage.Read {
+			c.read[fl] = true
+		}
+		for _, fl := range use.usage.Written {
+			c.written[fl] = true
+		}
+	}
+}
+
+// checkReachability warns about every room that the player can never get to
+// from the starting room.
+func (c *checker) checkReachability() {
+	pf := game.Pathfinder{World: c.world.Rooms}
+
+	for _, roomLabel := range util.OrderedKeys(c.world.Rooms) {
+		if roomLabel == c.world.Start {
+			continue
+		}
+		if len(pf.Dijkstra(c.world.Start, roomLabel)) < 1 {
+			c.warn("room "+roomLabel, "cannot be reached from the starting room %s", c.world.Start)
+		}
+	}
+}
+
+// checkConditions warns about every 'if' that can never be true because it
+// depends only on flags that no script ever changes and is false for their
+// starting values.
+func (c *checker) checkConditions() {
+	interp := tunascript.Interpreter{Target: noWorld{}}
+	for label, val := range c.world.Flags {
+		interp.AddFlag(label, val)
+	}
+
+	check := func(loc string, ast tunascript.AST) {
+		if len(ast.Nodes) < 1 {
+			return
+		}
+
+		usage := tunascript.Flags(ast)
+		if usage.QueriesWorld {
+			return
+		}
+		for _, fl := range usage.Read {
+			if c.written[fl] || game.IsEngineFlag(fl) {
+				return
+			}
+		}
+
+		if !interp.Exec(ast).Bool() {
+			c.warn(loc, "'if' can never be true; it is false at the start and depends only on flags that no script changes")
+		}
+	}
+
+	checkVerbActions := func(loc string, actions []game.VerbAction) {
+		for i, act := range actions {
+			check(fmt.Sprintf("%s, on_verb[%d]", loc, i), act.If)
+		}
+	}
+
+	for _, roomLabel := range util.OrderedKeys(c.world.Rooms) {
+		r := c.world.Rooms[roomLabel]
+		checkVerbActions("room "+r.Label, r.OnVerb)
+		for _, eg := range r.Exits {
+			check("room "+r.Label+", "+exitName(eg), eg.If)
+			if eg.Lock != nil {
+				check("room "+r.Label+", "+exitName(eg)+", lock", eg.Lock.If)
+			}
+		}
+		for _, det := range r.Details {
+			check("room "+r.Label+", "+detailName(det), det.If)
+			if det.Lock != nil {
+				check("room "+r.Label+", "+detailName(det)+", lock", det.Lock.If)
+			}
+			checkVerbActions("room "+r.Label+", "+detailName(det), det.OnVerb)
+		}
+		for _, it := range r.AllItems() {
+			check("item "+it.Label, it.If)
+			hooks := itemHooks(it)
+			for _, hookName := range util.OrderedKeys(hooks) {
+				check("item "+it.Label+", "+hookName, hooks[hookName].If)
+			}
+			if it.Lock != nil {
+				check("item "+it.Label+", lock", it.Lock.If)
+			}
+			checkVerbActions("item "+it.Label, it.OnVerb)
+		}
+		for _, npcLabel := range util.OrderedKeys(r.NPCs) {
+			npc := r.NPCs[npcLabel]
+			check("NPC "+npcLabel, npc.If)
+			for i, step := range npc.Dialog {
+				stepLoc := fmt.Sprintf("NPC %s, line[%d]", npcLabel, i)
+				check(stepLoc, step.If)
+				for j, cs := range step.ChoiceScripts {
+					check(fmt.Sprintf("%s, choice[%d]", stepLoc, j), cs.If)
+				}
+			}
+			reactions := npcReactions(npc)
+			for _, key := range util.OrderedKeys(reactions) {
+				for i, act := range reactions[key] {
+					check(fmt.Sprintf("NPC %s, %s[%d]", npcLabel, key, i), act.If)
+				}
+			}
+			checkVerbActions("NPC "+npcLabel, npc.OnVerb)
+		}
+	}
+
+	for _, ev := range c.world.Events {
+		check("event "+ev.Label, ev.If)
+	}
+}
+
+// checkFlags warns about flags that are read but never set by any script, and
+// about flags that are defined but never read.
+func (c *checker) checkFlags() {
+	for _, fl := range util.OrderedKeys(c.read) {
+		if c.written[fl] || game.IsEngineFlag(fl) {
+			continue
+		}
+
+		var where []string
+		for _, use := range c.uses {
+			if util.InSlice(fl, use.usage.Read) && !util.InSlice(use.location, where) {
+				where = append(where, use.location)
+			}
+		}
+		sort.Strings(where)
+
+		if _, defined := c.world.Flags[fl]; defined {
+			c.warn("flag $"+fl, "is read (in %s) but never set by any script, so it always has its default value", strings.Join(where, "; "))
+		} else {
+			c.warn("flag $"+fl, "is read (in %s) but is never defined or set by any script", strings.Join(where, "; "))
+		}
+	}
+
+	for _, fl := range util.OrderedKeys(c.world.Flags) {
+		if !c.read[strings.ToUpper(fl)] {
+			c.warn("flag $"+strings.ToUpper(fl), "is defined but never read")
+		}
+	}
+}
+
+// checkDialog warns about dialog steps that refer to labels that do not exist
+// and about dialog steps that can never be reached.
+func (c *checker) checkDialog() {
+	for _, roomLabel := range util.OrderedKeys(c.world.Rooms) {
+		r := c.world.Rooms[roomLabel]
+		for _, npcLabel := range util.OrderedKeys(r.NPCs) {
+			c.checkDialogTree("NPC "+npcLabel, r.NPCs[npcLabel].Dialog)
+		}
+	}
+}
+
+func (c *checker) checkDialogTree(loc string, dialog []*game.DialogStep) {
+	labels := map[string]bool{}
+	for _, step := range dialog {
+		if step.Label != "" {
+			labels[strings.ToUpper(step.Label)] = true
+		}
+	}
+
+	referenced := map[string]bool{}
+	for i, step := range dialog {
+		stepLoc := fmt.Sprintf("%s, line[%d]", loc, i)
+
+		for j, ch := range step.Choices {
+			target := strings.ToUpper(ch[1])
+			referenced[target] = true
+			if !labels[target] {
+				c.warn(stepLoc, "choices[%d] goes to %q, which is not the label of any line", j, ch[1])
+			}
+		}
+
+		if step.ResumeAt != "" {
+			target := strings.ToUpper(step.ResumeAt)
+			referenced[target] = true
+			if !labels[target] {
+				c.warn(stepLoc, "resumes at %q, which is not the label of any line", step.ResumeAt)
+			}
+		}
+	}
+
+	// a line can only be reached by following on from the one before it or
+	// by being jumped to
+	for i := 1; i < len(dialog); i++ {
+		prev := dialog[i-1]
+		if prev.Action != game.DialogEnd && prev.Action != game.DialogChoice {
+			continue
+		}
+		if dialog[i].Label != "" && referenced[strings.ToUpper(dialog[i].Label)] {
+			continue
+		}
+		c.warn(fmt.Sprintf("%s, line[%d]", loc, i), "can never be reached; the line before it has action %s and no choice or resume_at goes to it", prev.Action)
+	}
+}
+
+// checkUseActions warns about on_use, on_give, on_show, and on_verb actions
+// whose 'with' refers to something that does not exist.
+func (c *checker) checkUseActions() {
+	known := map[string]bool{}
+	for _, t := range builtInTags {
+		known[t] = true
+	}
+
+	var items []*game.Item
+	var npcs []*game.NPC
+	for _, r := range c.world.Rooms {
+		for _, eg := range r.Exits {
+			known[eg.Label] = true
+			addTags(known, eg.Tags)
+		}
+		for _, det := range r.Details {
+			known[det.Label] = true
+			addTags(known, det.Tags)
+		}
+		for _, it := range r.AllItems() {
+			known[it.Label] = true
+			addTags(known, it.Tags)
+			items = append(items, it)
+		}
+		for _, npc := range r.NPCs {
+			known[npc.Label] = true
+			addTags(known, npc.Tags)
+			npcs = append(npcs, npc)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	sort.Slice(npcs, func(i, j int) bool {
+		return npcs[i].Label < npcs[j].Label
+	})
+
+	warnUnknown := func(loc string, with []string) {
+		for _, latag := range with {
+			if known[strings.ToUpper(latag)] {
+				continue
+			}
+
+			kind := "label"
+			if strings.HasPrefix(latag, "@") {
+				kind = "tag"
+			}
+			c.warn(loc, "'with' refers to %s %q, but nothing has it", kind, latag)
+		}
+	}
+
+	warnUnknownVerbs := func(loc string, actions []game.VerbAction) {
+		for i, act := range actions {
+			warnUnknown(fmt.Sprintf("%s, on_verb[%d]", loc, i), act.With)
+		}
+	}
+
+	for _, roomLabel := range util.OrderedKeys(c.world.Rooms) {
+		r := c.world.Rooms[roomLabel]
+		warnUnknownVerbs("room "+r.Label, r.OnVerb)
+		for _, det := range r.Details {
+			warnUnknownVerbs("room "+r.Label+", "+detailName(det), det.OnVerb)
+		}
+	}
+	for _, it := range items {
+		for i, ua := range it.OnUse {
+			warnUnknown(fmt.Sprintf("item %s, on_use[%d]", it.Label, i), ua.With)
+		}
+		warnUnknownVerbs("item "+it.Label, it.OnVerb)
+	}
+	for _, npc := range npcs {
+		reactions := npcReactions(npc)
+		for _, key := range util.OrderedKeys(reactions) {
+			for i, act := range reactions[key] {
+				warnUnknown(fmt.Sprintf("NPC %s, %s[%d]", npc.Label, key, i), act.With)
+			}
+		}
+		warnUnknownVerbs("NPC "+npc.Label, npc.OnVerb)
+	}
+}
+
+// exitName gives a name for the exit to use in a Warning. Exits that were not
+// given a label in the world are named by their first alias instead of the
+// label that was generated for them.
+func exitName(eg *game.Egress) string {
+	if eg.Label == "" || strings.HasPrefix(eg.Label, autoLabelPrefix) {
+		if len(eg.Aliases) > 0 {
+			return "exit " + strings.ToUpper(eg.Aliases[0])
+		}
+		return "exit to " + eg.DestLabel
+	}
+	return "exit " + eg.Label
+}
+
+// detailName gives a name for the detail to use in a Warning. Details that were
+// not given a label in the world are named by their first alias instead of the
+// label that was generated for them.
+func detailName(det *game.Detail) string {
+	if (det.Label == "" || strings.HasPrefix(det.Label, autoLabelPrefix)) && len(det.Aliases) > 0 {
+		return "detail " + strings.ToUpper(det.Aliases[0])
+	}
+	return "detail " + det.Label
+}
+
+func addTags(known map[string]bool, tags []string) {
+	for _, t := range tags {
+		known[strings.ToUpper(t)] = true
+	}
+}
+
+// npcReactions returns the give and show actions of an NPC by the name of the
+// key they are given with in a world file.
+func npcReactions(npc *game.NPC) map[string][]game.GiveAction {
+	return map[string][]game.GiveAction{"on_give": npc.OnGive, "on_show": npc.OnShow}
+}
+
+// itemHooks returns the take, drop, and look hooks of an item by the name of
+// the key they are given with in a world file.
+func itemHooks(it *game.Item) map[string]game.ItemHook {
+	return map[string]game.ItemHook{"on_take": it.OnTake, "on_drop": it.OnDrop, "on_look": it.OnLook}
+}
+
+// noWorld is a tunascript.WorldInterface with nothing in it. It is used to
+// evaluate tunascript that does not query the world.
+type noWorld struct{}
+
+func (noWorld) InInventory(label string) bool { return false }
+func (noWorld) Visited(label string) bool     { return false }
+func (noWorld) IsLocked(label string) bool    { return false }
+func (noWorld) IsOpen(label string) bool      { return false }
+func (noWorld) NPCHas(npc, item string) bool  { return false }
+func (noWorld) Turn() int                     { return 0 }
+func (noWorld) Move(label, dest string) bool  { return false }
+func (noWorld) Send(npc, dest string) bool    { return false }
+func (noWorld) Output(s string) bool          { return true }
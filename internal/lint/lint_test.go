@@ -0,0 +1,69 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/dekarrin/tunaq/internal/game"
+	"github.com/dekarrin/tunaq/internal/tqw"
+	"github.com/dekarrin/tunaq/tunascript"
+	"github.com/stretchr/testify/assert"
+)
+
+func mustParse(code string) tunascript.AST {
+	var interp tunascript.Interpreter
+	ast, err := interp.Parse(code)
+	if err != nil {
+		panic(err)
+	}
+	return ast
+}
+
+func Test_Check(t *testing.T) {
+	assert := assert.New(t)
+
+	world := tqw.WorldData{
+		Start: "KITCHEN",
+		Flags: map[string]string{"DOOR_OPEN": "false", "UNUSED": "1"},
+		Rooms: map[string]*game.Room{
+			"KITCHEN": {
+				Label: "KITCHEN",
+				Exits: []*game.Egress{
+					{Label: "KITCHEN_TO_HALL", DestLabel: "HALL", Aliases: []string{"HALL"}, If: mustParse("$DOOR_OPEN")},
+					{Label: "KITCHEN_TO_CELLAR", DestLabel: "CELLAR", Aliases: []string{"CELLAR"}, If: mustParse("$IN_INVEN(KEY)")},
+				},
+				Items: []*game.Item{
+					{Label: "KEY", Aliases: []string{"KEY"}, If: tunascript.ReturnTrue, OnUse: []game.UseAction{
+						{With: []string{"CELLAR_DOOR", "@LOCK"}, Do: mustParse("$ENABLE(HAS_USED_KEY)")},
+					}},
+				},
+				NPCs: map[string]*game.NPC{
+					"CHEF": {
+						Label:       "CHEF",
+						Description: "The chef $[[IF $HAS_USED_KEY]]looks impressed$[[ELSE]]cooks$[[ENDIF]].",
+						If:          tunascript.ReturnTrue,
+						Dialog: []*game.DialogStep{
+							{Action: game.DialogLine, Content: "hello"},
+							{Action: game.DialogEnd},
+							{Action: game.DialogLine, Content: "never said"},
+						},
+					},
+				},
+			},
+			"HALL":   {Label: "HALL"},
+			"CELLAR": {Label: "CELLAR"},
+			"ATTIC":  {Label: "ATTIC"},
+		},
+	}
+
+	expect := []Warning{
+		{Location: "room ATTIC", Message: "cannot be reached from the starting room KITCHEN"},
+		{Location: "room KITCHEN, exit KITCHEN_TO_HALL", Message: "'if' can never be true; it is false at the start and depends only on flags that no script changes"},
+		{Location: "flag $DOOR_OPEN", Message: "is read (in room KITCHEN, exit KITCHEN_TO_HALL) but never set by any script, so it always has its default value"},
+		{Location: "flag $UNUSED", Message: "is defined but never read"},
+		{Location: "NPC CHEF, line[2]", Message: "can never be reached; the line before it has action END and no choice or resume_at goes to it"},
+		{Location: "item KEY, on_use[0]", Message: "'with' refers to label \"CELLAR_DOOR\", but nothing has it"},
+		{Location: "item KEY, on_use[0]", Message: "'with' refers to tag \"@LOCK\", but nothing has it"},
+	}
+
+	assert.Equal(expect, Check(world))
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/dekarrin/tunaq/internal/command"
 	"github.com/dekarrin/tunaq/internal/game"
 )
 
@@ -22,47 +23,67 @@ type flag struct {
 // topLevelWorldData is the top-level structure containing all keys in a complete TQW
 // 'DATA' type file.
 type topLevelWorldData struct {
-	Format   string       `toml:"format"`
-	Type     string       `toml:"type"`
-	Rooms    []room       `toml:"room"`
-	World    world        `toml:"world"`
-	NPCs     []npc        `toml:"npc"`
-	Pronouns []pronounSet `toml:"pronouns"`
-	Items    []item       `toml:"item"`
-	Flags    []flag       `toml:"flag"`
+	Format     string       `toml:"format"`
+	Type       string       `toml:"type"`
+	Rooms      []room       `toml:"room"`
+	World      world        `toml:"world"`
+	NPCs       []npc        `toml:"npc"`
+	Pronouns   []pronounSet `toml:"pronouns"`
+	Items      []item       `toml:"item"`
+	Flags      []flag       `toml:"flag"`
+	Events     []event      `toml:"event"`
+	Verbs      []verb       `toml:"verb"`
+	Vocabulary vocabulary   `toml:"vocabulary"`
 }
 
 type npc struct {
-	Tags        []string     `toml:"tags"`
-	Label       string       `toml:"label"`
-	Aliases     []string     `toml:"aliases"`
-	Name        string       `toml:"name"`
-	Pronouns    string       `toml:"pronouns"`
-	PronounSet  pronounSet   `toml:"custom_pronoun_set"`
-	Description string       `toml:"description"`
-	Start       string       `toml:"start"`
-	Movement    route        `toml:"movement"`
-	Dialogs     []dialogStep `toml:"line"`
-	If          string       `toml:"if"`
+	Tags         []string     `toml:"tags"`
+	Label        string       `toml:"label"`
+	Aliases      []string     `toml:"aliases"`
+	Name         string       `toml:"name"`
+	Pronouns     string       `toml:"pronouns"`
+	PronounSet   pronounSet   `toml:"custom_pronoun_set"`
+	Description  string       `toml:"description"`
+	Start        string       `toml:"start"`
+	Movement     route        `toml:"movement"`
+	Dialogs      []dialogStep `toml:"line"`
+	If           string       `toml:"if"`
+	OnGive       []giveAction `toml:"on_give"`
+	OnShow       []giveAction `toml:"on_show"`
+	OnVerb       []verbAction `toml:"on_verb"`
+	EnterMessage string       `toml:"enter_message"`
+	LeaveMessage string       `toml:"leave_message"`
 }
 
 func (tn npc) toGameNPC() game.NPC {
 	npc := game.NPC{
-		Label:       strings.ToUpper(tn.Label),
-		Name:        tn.Name,
-		Pronouns:    tn.PronounSet.toGamePronounSet(),
-		Description: tn.Description,
-		Start:       strings.ToUpper(tn.Start),
-		Movement:    tn.Movement.toGameRoute(),
-		Dialog:      make([]*game.DialogStep, len(tn.Dialogs)),
-		Aliases:     make([]string, len(tn.Aliases)),
-		Tags:        make([]string, len(tn.Tags)),
-		IfRaw:       tn.If,
+		Label:        strings.ToUpper(tn.Label),
+		Name:         tn.Name,
+		Pronouns:     tn.PronounSet.toGamePronounSet(),
+		Description:  tn.Description,
+		Start:        strings.ToUpper(tn.Start),
+		Movement:     tn.Movement.toGameRoute(),
+		Dialog:       make([]*game.DialogStep, len(tn.Dialogs)),
+		Aliases:      make([]string, len(tn.Aliases)),
+		Tags:         make([]string, len(tn.Tags)),
+		IfRaw:        tn.If,
+		Inventory:    make(game.Inventory),
+		OnGive:       make([]game.GiveAction, len(tn.OnGive)),
+		OnShow:       make([]game.GiveAction, len(tn.OnShow)),
+		OnVerb:       toGameVerbActions(tn.OnVerb),
+		EnterMessage: tn.EnterMessage,
+		LeaveMessage: tn.LeaveMessage,
 	}
 
 	for i := range tn.Dialogs {
 		npc.Dialog[i] = tn.Dialogs[i].toGameDialogStep()
 	}
+	for i := range tn.OnGive {
+		npc.OnGive[i] = tn.OnGive[i].toGameGiveAction()
+	}
+	for i := range tn.OnShow {
+		npc.OnShow[i] = tn.OnShow[i].toGameGiveAction()
+	}
 	for i := range tn.Aliases {
 		npc.Aliases[i] = strings.ToUpper(tn.Aliases[i])
 	}
@@ -82,6 +103,7 @@ type route struct {
 	Path      []string `toml:"path"`
 	Forbidden []string `toml:"forbidden"`
 	Allowed   []string `toml:"allowed"`
+	Target    string   `toml:"target"`
 }
 
 func (tr route) toGameRoute() game.Route {
@@ -92,6 +114,7 @@ func (tr route) toGameRoute() game.Route {
 
 	r := game.Route{
 		Action:         act,
+		Target:         strings.ToUpper(tr.Target),
 		Path:           make([]string, len(tr.Path)),
 		ForbiddenRooms: make([]string, len(tr.Forbidden)),
 		AllowedRooms:   make([]string, len(tr.Allowed)),
@@ -111,12 +134,22 @@ func (tr route) toGameRoute() game.Route {
 }
 
 type dialogStep struct {
-	Action   string     `toml:"action"`
-	Label    string     `toml:"label"`
-	Content  string     `toml:"content"`
-	Response string     `toml:"response"`
-	Choices  [][]string `toml:"choices"`
-	Continue string     `toml:"continue"`
+	Action   string         `toml:"action"`
+	Label    string         `toml:"label"`
+	Content  string         `toml:"content"`
+	Response string         `toml:"response"`
+	Choices  [][]string     `toml:"choices"`
+	Choice   []dialogChoice `toml:"choice"`
+	Continue string         `toml:"continue"`
+	If       string         `toml:"if"`
+	Do       []string       `toml:"do"`
+}
+
+type dialogChoice struct {
+	Text string   `toml:"text"`
+	Goto string   `toml:"goto"`
+	If   string   `toml:"if"`
+	Do   []string `toml:"do"`
 }
 
 func (tds dialogStep) toGameDialogStep() *game.DialogStep {
@@ -132,8 +165,12 @@ func (tds dialogStep) toGameDialogStep() *game.DialogStep {
 		Response: tds.Response,
 		Choices:  make([][2]string, len(tds.Choices)),
 		ResumeAt: strings.ToUpper(tds.Continue),
+		IfRaw:    tds.If,
+		DoRaw:    make([]string, len(tds.Do)),
 	}
 
+	copy(ds.DoRaw, tds.Do)
+
 	for i := range tds.Choices {
 		if len(tds.Choices[i]) < 2 {
 			continue
@@ -145,6 +182,21 @@ func (tds dialogStep) toGameDialogStep() *game.DialogStep {
 		ds.Choices[i] = [2]string{choice, dest}
 	}
 
+	// choices given as tables can have scripts attached to them
+	if len(tds.Choice) > 0 {
+		ds.Choices = make([][2]string, len(tds.Choice))
+		ds.ChoiceScripts = make([]game.DialogChoiceScript, len(tds.Choice))
+
+		for i, ch := range tds.Choice {
+			ds.Choices[i] = [2]string{ch.Text, strings.ToUpper(ch.Goto)}
+			ds.ChoiceScripts[i] = game.DialogChoiceScript{
+				IfRaw: ch.If,
+				DoRaw: make([]string, len(ch.Do)),
+			}
+			copy(ds.ChoiceScripts[i].DoRaw, ch.Do)
+		}
+	}
+
 	return &ds
 }
 
@@ -223,15 +275,172 @@ func (ua useAction) toGameUseAction() game.UseAction {
 	return gameAction
 }
 
+type giveAction struct {
+	With     []string `toml:"with"`
+	Refuse   bool     `toml:"refuse"`
+	If       string   `toml:"if"`
+	Do       []string `toml:"do"`
+	Response string   `toml:"response"`
+}
+
+func (ga giveAction) toGameGiveAction() game.GiveAction {
+	gameAction := game.GiveAction{
+		With:     make([]string, len(ga.With)),
+		Refuse:   ga.Refuse,
+		IfRaw:    ga.If,
+		DoRaw:    make([]string, len(ga.Do)),
+		Response: ga.Response,
+	}
+
+	for i := range ga.With {
+		gameAction.With[i] = strings.ToUpper(ga.With[i])
+	}
+
+	copy(gameAction.DoRaw, ga.Do)
+
+	return gameAction
+}
+
+type verbAction struct {
+	Verb     string   `toml:"verb"`
+	With     []string `toml:"with"`
+	If       string   `toml:"if"`
+	Do       []string `toml:"do"`
+	Response string   `toml:"response"`
+}
+
+func (va verbAction) toGameVerbAction() game.VerbAction {
+	gameAction := game.VerbAction{
+		Verb:     strings.ToUpper(va.Verb),
+		With:     make([]string, len(va.With)),
+		IfRaw:    va.If,
+		DoRaw:    make([]string, len(va.Do)),
+		Response: va.Response,
+	}
+
+	for i := range va.With {
+		gameAction.With[i] = strings.ToUpper(va.With[i])
+	}
+
+	copy(gameAction.DoRaw, va.Do)
+
+	return gameAction
+}
+
+// toGameVerbActions converts each of actions to a game.VerbAction. If there
+// are none, nil is returned.
+func toGameVerbActions(actions []verbAction) []game.VerbAction {
+	if len(actions) < 1 {
+		return nil
+	}
+	gameActions := make([]game.VerbAction, len(actions))
+	for i := range actions {
+		gameActions[i] = actions[i].toGameVerbAction()
+	}
+	return gameActions
+}
+
+type verb struct {
+	Name        string   `toml:"name"`
+	Aliases     []string `toml:"aliases"`
+	Object      string   `toml:"object"`
+	Preposition string   `toml:"preposition"`
+	Instrument  string   `toml:"instrument"`
+	Help        string   `toml:"help"`
+	Default     string   `toml:"default"`
+}
+
+func (tv verb) toGameCustomVerb() game.CustomVerb {
+	// an object is needed unless the world says otherwise, but an instrument
+	// is not
+	recipient, ok := command.ArgUsesByString[strings.ToUpper(tv.Object)]
+	if !ok {
+		recipient = command.ArgRequired
+	}
+	instrument, ok := command.ArgUsesByString[strings.ToUpper(tv.Instrument)]
+	if !ok {
+		instrument = command.ArgOptional
+	}
+
+	cv := game.CustomVerb{
+		Def: command.VerbDef{
+			Name:        strings.ToUpper(tv.Name),
+			Aliases:     make([]string, len(tv.Aliases)),
+			Recipient:   recipient,
+			Preposition: strings.ToUpper(tv.Preposition),
+			Instrument:  instrument,
+		},
+		Help:    tv.Help,
+		Default: tv.Default,
+	}
+
+	for i := range tv.Aliases {
+		cv.Def.Aliases[i] = strings.ToUpper(tv.Aliases[i])
+	}
+
+	return cv
+}
+
+type event struct {
+	Label       string   `toml:"label"`
+	AtTurn      int      `toml:"at_turn"`
+	EveryNTurns int      `toml:"every_n_turns"`
+	AfterFlag   string   `toml:"after_flag"`
+	Delay       int      `toml:"delay"`
+	If          string   `toml:"if"`
+	Do          []string `toml:"do"`
+}
+
+func (ev event) toGameEvent() game.Event {
+	gameEvent := game.Event{
+		Label:       strings.ToUpper(ev.Label),
+		AtTurn:      ev.AtTurn,
+		EveryNTurns: ev.EveryNTurns,
+		AfterFlag:   strings.ToUpper(ev.AfterFlag),
+		Delay:       ev.Delay,
+		IfRaw:       ev.If,
+		DoRaw:       make([]string, len(ev.Do)),
+	}
+
+	copy(gameEvent.DoRaw, ev.Do)
+
+	return gameEvent
+}
+
+type itemHook struct {
+	If      string   `toml:"if"`
+	Do      []string `toml:"do"`
+	Refusal string   `toml:"refusal"`
+}
+
+func (ih itemHook) toGameItemHook() game.ItemHook {
+	gameHook := game.ItemHook{
+		IfRaw:   ih.If,
+		DoRaw:   make([]string, len(ih.Do)),
+		Refusal: ih.Refusal,
+	}
+
+	copy(gameHook.DoRaw, ih.Do)
+
+	return gameHook
+}
+
 type item struct {
-	Tags        []string    `toml:"tags"`
-	Label       string      `toml:"label"`
-	Name        string      `toml:"name"`
-	Description string      `toml:"description"`
-	Aliases     []string    `toml:"aliases"`
-	Start       string      `toml:"start"`
-	If          string      `toml:"if"`
-	OnUse       []useAction `toml:"on_use"`
+	Tags        []string     `toml:"tags"`
+	Label       string       `toml:"label"`
+	Name        string       `toml:"name"`
+	Description string       `toml:"description"`
+	Aliases     []string     `toml:"aliases"`
+	Adjectives  []string     `toml:"adjectives"`
+	Start       string       `toml:"start"`
+	If          string       `toml:"if"`
+	OnUse       []useAction  `toml:"on_use"`
+	OnTake      itemHook     `toml:"on_take"`
+	OnDrop      itemHook     `toml:"on_drop"`
+	OnLook      itemHook     `toml:"on_look"`
+	OnVerb      []verbAction `toml:"on_verb"`
+	Lock        *lock        `toml:"lock"`
+	Container   *container   `toml:"container"`
 }
 
 func (ti item) toGameItem() game.Item {
@@ -240,14 +449,22 @@ func (ti item) toGameItem() game.Item {
 		Name:        ti.Name,
 		Description: ti.Description,
 		Aliases:     make([]string, len(ti.Aliases)),
+		Adjectives:  make([]string, len(ti.Adjectives)),
 		Tags:        make([]string, len(ti.Tags)),
 		IfRaw:       ti.If,
 		OnUse:       make([]game.UseAction, len(ti.OnUse)),
+		OnTake:      ti.OnTake.toGameItemHook(),
+		OnDrop:      ti.OnDrop.toGameItemHook(),
+		OnLook:      ti.OnLook.toGameItemHook(),
+		OnVerb:      toGameVerbActions(ti.OnVerb),
 	}
 
 	for i := range ti.Aliases {
 		gameItem.Aliases[i] = strings.ToUpper(ti.Aliases[i])
 	}
+	for i := range ti.Adjectives {
+		gameItem.Adjectives[i] = strings.ToUpper(ti.Adjectives[i])
+	}
 	for i := range ti.Tags {
 		tag := ti.Tags[i]
 		if !strings.HasPrefix(tag, "@") {
@@ -259,6 +476,15 @@ func (ti item) toGameItem() game.Item {
 		gameItem.OnUse[i] = ti.OnUse[i].toGameUseAction()
 	}
 
+	if ti.Lock != nil {
+		gameLock := ti.Lock.toGameLock(gameItem.Label)
+		gameItem.Lock = &gameLock
+	}
+	if ti.Container != nil {
+		gameContainer := ti.Container.toGameContainer()
+		gameItem.Container = &gameContainer
+	}
+
 	return gameItem
 }
 
@@ -270,10 +496,12 @@ type egress struct {
 	Message     string   `toml:"message"`
 	Aliases     []string `toml:"aliases"`
 	If          string   `toml:"if"`
+	Lock        *lock    `toml:"lock"`
 }
 
 func (te egress) toGameEgress() game.Egress {
 	eg := game.Egress{
+		Label:         strings.ToUpper(te.Label),
 		DestLabel:     strings.ToUpper(te.Dest),
 		Description:   te.Description,
 		TravelMessage: te.Message,
@@ -293,24 +521,78 @@ func (te egress) toGameEgress() game.Egress {
 		eg.Tags[i] = strings.ToUpper(tag)
 	}
 
+	if te.Lock != nil {
+		gameLock := te.Lock.toGameLock(eg.Label)
+		eg.Lock = &gameLock
+	}
+
 	return eg
 }
 
+type lock struct {
+	Label    string   `toml:"label"`
+	Openable bool     `toml:"openable"`
+	Lockable bool     `toml:"lockable"`
+	Open     bool     `toml:"open"`
+	Locked   bool     `toml:"locked"`
+	Keys     []string `toml:"keys"`
+	If       string   `toml:"if"`
+}
+
+// toGameLock converts tl to a game.Lock. If tl has no label, it is given
+// defaultLabel.
+func (tl lock) toGameLock(defaultLabel string) game.Lock {
+	gameLock := game.Lock{
+		Label:    strings.ToUpper(tl.Label),
+		Openable: tl.Openable,
+		Lockable: tl.Lockable,
+		Open:     tl.Open,
+		Locked:   tl.Locked,
+		Keys:     make([]string, len(tl.Keys)),
+		IfRaw:    tl.If,
+	}
+
+	if strings.TrimSpace(gameLock.Label) == "" {
+		gameLock.Label = defaultLabel
+	}
+	for i := range tl.Keys {
+		gameLock.Keys[i] = strings.ToUpper(tl.Keys[i])
+	}
+
+	return gameLock
+}
+
+type container struct {
+	Capacity int `toml:"capacity"`
+}
+
+// toGameContainer converts tc to an empty game.Container. Items are put in it
+// once they have all been read.
+func (tc container) toGameContainer() game.Container {
+	return game.Container{
+		Capacity: tc.Capacity,
+	}
+}
+
 type detail struct {
-	Tags        []string `toml:"tags"`
-	Label       string   `toml:"label"`
-	Aliases     []string `toml:"aliases"`
-	Description string   `toml:"description"`
-	If          string   `toml:"if"`
+	Tags        []string     `toml:"tags"`
+	Label       string       `toml:"label"`
+	Aliases     []string     `toml:"aliases"`
+	Description string       `toml:"description"`
+	If          string       `toml:"if"`
+	Lock        *lock        `toml:"lock"`
+	Container   *container   `toml:"container"`
+	OnVerb      []verbAction `toml:"on_verb"`
 }
 
 func (td detail) toGameDetail() game.Detail {
 	det := game.Detail{
-		Label:       td.Label,
+		Label:       strings.ToUpper(td.Label),
 		Aliases:     make([]string, len(td.Aliases)),
 		Tags:        make([]string, len(td.Tags)),
 		Description: td.Description,
 		IfRaw:       td.If,
+		OnVerb:      toGameVerbActions(td.OnVerb),
 	}
 
 	for i := range det.Aliases {
@@ -324,15 +606,28 @@ func (td detail) toGameDetail() game.Detail {
 		det.Tags[i] = strings.ToUpper(tag)
 	}
 
+	if td.Lock != nil {
+		gameLock := td.Lock.toGameLock(det.Label)
+		det.Lock = &gameLock
+	}
+	if td.Container != nil {
+		gameContainer := td.Container.toGameContainer()
+		det.Container = &gameContainer
+	}
+
 	return det
 }
 
 type room struct {
-	Label       string   `toml:"label"`
-	Name        string   `toml:"name"`
-	Description string   `toml:"description"`
-	Exits       []egress `toml:"exit"`
-	Details     []detail `toml:"detail"`
+	Label        string       `toml:"label"`
+	Name         string       `toml:"name"`
+	Description  string       `toml:"description"`
+	Exits        []egress     `toml:"exit"`
+	Details      []detail     `toml:"detail"`
+	OnEnter      []string     `toml:"on_enter"`
+	OnFirstEnter []string     `toml:"on_first_enter"`
+	OnExit       []string     `toml:"on_exit"`
+	OnVerb       []verbAction `toml:"on_verb"`
 }
 
 func (tr room) toGameRoom() game.Room {
@@ -343,8 +638,17 @@ func (tr room) toGameRoom() game.Room {
 		Exits:       make([]*game.Egress, len(tr.Exits)),
 		NPCs:        make(map[string]*game.NPC),
 		Details:     make([]*game.Detail, len(tr.Details)),
+
+		OnEnterRaw:      make([]string, len(tr.OnEnter)),
+		OnFirstEnterRaw: make([]string, len(tr.OnFirstEnter)),
+		OnExitRaw:       make([]string, len(tr.OnExit)),
+		OnVerb:          toGameVerbActions(tr.OnVerb),
 	}
 
+	copy(r.OnEnterRaw, tr.OnEnter)
+	copy(r.OnFirstEnterRaw, tr.OnFirstEnter)
+	copy(r.OnExitRaw, tr.OnExit)
+
 	for i := range tr.Exits {
 		eggCopy := tr.Exits[i].toGameEgress()
 		r.Exits[i] = &eggCopy
@@ -360,4 +664,16 @@ func (tr room) toGameRoom() game.Room {
 
 type world struct {
 	Start string `toml:"start"`
+	Seed  *int64 `toml:"seed"`
+}
+
+// vocabulary is the words that a world adds to or changes in the default ones
+// that commands are parsed with.
+type vocabulary struct {
+	// Aliases maps verb aliases to what they expand to. An empty expansion
+	// removes a default alias.
+	Aliases map[string]string `toml:"aliases"`
+
+	// Words maps words to the reserved words they can be typed instead of.
+	Words map[string]string `toml:"words"`
 }
@@ -3,8 +3,6 @@ package tqw
 import (
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -16,12 +14,10 @@ import (
 //
 // Returnes ErrManifestEmpty if and only if the first manifest in the stack is
 // empty, otherwise it is not an error.
-func recursiveUnmarshalResource(path string, manifStack []string) (data topLevelWorldData, err error) {
-	path = filepath.Clean(path)
-
-	fileData, loadErr := os.ReadFile(path)
+func recursiveUnmarshalResource(src resourceReader, path string, manifStack []string) (data topLevelWorldData, err error) {
+	fileData, loadErr := src.readFile(path)
 	if loadErr != nil {
-		return topLevelWorldData{}, fmt.Errorf("%q: reading from disk: %w", path, loadErr)
+		return topLevelWorldData{}, fmt.Errorf("%q: reading: %w", path, loadErr)
 	}
 
 	fileInfo, err := ScanFileInfo(fileData)
@@ -78,17 +74,18 @@ func recursiveUnmarshalResource(path string, manifStack []string) (data topLevel
 		copy(manifSubStack, manifStack)
 		manifSubStack[len(manifSubStack)-1] = path
 
-		manifDir := filepath.Dir(path)
-
 		// good to know an actual count of non-skipped files so we can error on
 		// the specific case of first file was manifest and referred only to
 		// unreadable files
 		processedFiles := 0
 
 		for _, manifRelPath := range manif.Files {
-			includedFilePath := filepath.Join(manifDir, manifRelPath)
+			includedFilePath, err := src.resolve(path, manifRelPath)
+			if err != nil {
+				return topLevelWorldData{}, fmt.Errorf("manifest file %q: %w", path, err)
+			}
 
-			unmarshaledFileData, err := recursiveUnmarshalResource(includedFilePath, manifSubStack)
+			unmarshaledFileData, err := recursiveUnmarshalResource(src, includedFilePath, manifSubStack)
 			if err != nil {
 				// if it's a circular reference, that's actually okay. we will
 				// just skip reading it and move on to the next entry.
@@ -106,6 +103,12 @@ func recursiveUnmarshalResource(path string, manifStack []string) (data topLevel
 				}
 				unmarshaled.World.Start = unmarshaledFileData.World.Start
 			}
+			if unmarshaledFileData.World.Seed != nil {
+				if unmarshaled.World.Seed != nil {
+					return unmarshaled, fmt.Errorf("world data file %q: duplicate seed; seed has already been defined as %d", path, *unmarshaled.World.Seed)
+				}
+				unmarshaled.World.Seed = unmarshaledFileData.World.Seed
+			}
 			if len(unmarshaledFileData.Pronouns) > 0 {
 				unmarshaled.Pronouns = append(unmarshaled.Pronouns, unmarshaledFileData.Pronouns...)
 			}
@@ -121,6 +124,30 @@ func recursiveUnmarshalResource(path string, manifStack []string) (data topLevel
 			if len(unmarshaledFileData.Flags) > 0 {
 				unmarshaled.Flags = append(unmarshaled.Flags, unmarshaledFileData.Flags...)
 			}
+			if len(unmarshaledFileData.Events) > 0 {
+				unmarshaled.Events = append(unmarshaled.Events, unmarshaledFileData.Events...)
+			}
+			if len(unmarshaledFileData.Verbs) > 0 {
+				unmarshaled.Verbs = append(unmarshaled.Verbs, unmarshaledFileData.Verbs...)
+			}
+			for alias, expansion := range unmarshaledFileData.Vocabulary.Aliases {
+				if _, ok := unmarshaled.Vocabulary.Aliases[alias]; ok {
+					return unmarshaled, fmt.Errorf("world data file %q: duplicate vocabulary alias %q", path, alias)
+				}
+				if unmarshaled.Vocabulary.Aliases == nil {
+					unmarshaled.Vocabulary.Aliases = make(map[string]string)
+				}
+				unmarshaled.Vocabulary.Aliases[alias] = expansion
+			}
+			for word, meaning := range unmarshaledFileData.Vocabulary.Words {
+				if _, ok := unmarshaled.Vocabulary.Words[word]; ok {
+					return unmarshaled, fmt.Errorf("world data file %q: duplicate vocabulary word %q", path, word)
+				}
+				if unmarshaled.Vocabulary.Words == nil {
+					unmarshaled.Vocabulary.Words = make(map[string]string)
+				}
+				unmarshaled.Vocabulary.Words[word] = meaning
+			}
 			processedFiles++
 		}
 
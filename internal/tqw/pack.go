@@ -0,0 +1,242 @@
+package tqw
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MaxResourcePackSize is the maximum number of bytes that the files in a
+// resource pack can take up once they are extracted.
+const MaxResourcePackSize = 64 * 1024 * 1024
+
+// RootManifestName is the name of the manifest file that is loaded from the
+// root of a resource pack. If a pack has no file with this name at its root,
+// the single manifest file at its root is used instead.
+const RootManifestName = "manifest.tqw"
+
+var (
+	// ErrPackTraversal is the error returned when a resource pack contains a
+	// file, or a manifest in one refers to a file, that is outside of the
+	// root of the pack.
+	ErrPackTraversal = errors.New("refers to a path outside of the resource pack")
+
+	// ErrPackTooLarge is the error returned when the files in a resource pack
+	// would take up more than MaxResourcePackSize bytes once extracted.
+	ErrPackTooLarge = fmt.Errorf("is larger than %d bytes when extracted", MaxResourcePackSize)
+
+	// ErrPackNoManifest is the error returned when a resource pack does not
+	// have exactly one manifest file at its root to start loading from.
+	ErrPackNoManifest = errors.New("does not have a single manifest file at its root")
+)
+
+// packFormat is the kind of archive that a resource pack is.
+type packFormat int
+
+const (
+	packNone packFormat = iota
+	packZip
+	packTarGz
+)
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// resourceReader reads the files that make up a world.
+type resourceReader interface {
+	// readFile returns the contents of the file at the given path.
+	readFile(path string) ([]byte, error)
+
+	// resolve returns the path of the file that ref refers to when it is
+	// listed in the manifest file at manifPath.
+	resolve(manifPath string, ref string) (string, error)
+}
+
+// diskReader is a resourceReader that reads files directly from disk.
+type diskReader struct{}
+
+func (diskReader) readFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+func (diskReader) resolve(manifPath string, ref string) (string, error) {
+	return filepath.Join(filepath.Dir(manifPath), ref), nil
+}
+
+// packReader is a resourceReader that reads files that were extracted from a
+// resource pack. Paths are always slash-separated and relative to the root of
+// the pack.
+type packReader struct {
+	files map[string][]byte
+}
+
+func (pr packReader) readFile(path string) ([]byte, error) {
+	data, ok := pr.files[path]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return data, nil
+}
+
+func (pr packReader) resolve(manifPath string, ref string) (string, error) {
+	ref = filepath.ToSlash(ref)
+	if path.IsAbs(ref) || filepath.IsAbs(ref) {
+		return "", fmt.Errorf("%q: %w", ref, ErrPackTraversal)
+	}
+
+	resolved := path.Join(path.Dir(manifPath), ref)
+	if !fs.ValidPath(resolved) {
+		return "", fmt.Errorf("%q: %w", ref, ErrPackTraversal)
+	}
+	return resolved, nil
+}
+
+// rootManifest returns the path of the manifest within the pack that loading
+// starts from.
+func (pr packReader) rootManifest() (string, error) {
+	if _, ok := pr.files[RootManifestName]; ok {
+		return RootManifestName, nil
+	}
+
+	var found []string
+	for name, data := range pr.files {
+		if strings.Contains(name, "/") {
+			continue
+		}
+		info, err := ScanFileInfo(data)
+		if err != nil {
+			continue
+		}
+		if strings.ToUpper(info.Format) == "TUNA" && strings.ToUpper(info.Type) == "MANIFEST" {
+			found = append(found, name)
+		}
+	}
+
+	if len(found) != 1 {
+		return "", ErrPackNoManifest
+	}
+	return found[0], nil
+}
+
+// detectPackFormat returns the kind of archive that the file at the given path
+// is, based on its first few bytes. If it is not an archive, packNone is
+// returned.
+func detectPackFormat(filename string) (packFormat, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return packNone, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(zipMagic))
+	n, err := io.ReadFull(f, magic)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return packNone, err
+	}
+	magic = magic[:n]
+
+	if bytes.HasPrefix(magic, zipMagic) {
+		return packZip, nil
+	}
+	if bytes.HasPrefix(magic, gzipMagic) {
+		return packTarGz, nil
+	}
+	return packNone, nil
+}
+
+// openPack extracts all regular files from the resource pack at the given
+// path.
+func openPack(filename string, format packFormat) (packReader, error) {
+	pr := packReader{files: map[string][]byte{}}
+	var total int64
+
+	// addFile reads all of r into the pack as the file called name, making
+	// sure that it stays within the pack and that the pack does not get too
+	// large.
+	addFile := func(name string, r io.Reader) error {
+		name = strings.TrimPrefix(path.Clean(filepath.ToSlash(name)), "./")
+		if !fs.ValidPath(name) {
+			return fmt.Errorf("%q: %w", name, ErrPackTraversal)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(r, MaxResourcePackSize-total+1))
+		if err != nil {
+			return fmt.Errorf("%q: %w", name, err)
+		}
+		total += int64(len(data))
+		if total > MaxResourcePackSize {
+			return ErrPackTooLarge
+		}
+
+		pr.files[name] = data
+		return nil
+	}
+
+	switch format {
+	case packZip:
+		zr, err := zip.OpenReader(filename)
+		if err != nil {
+			return pr, err
+		}
+		defer zr.Close()
+
+		for _, zf := range zr.File {
+			if !zf.Mode().IsRegular() {
+				continue
+			}
+			rc, err := zf.Open()
+			if err != nil {
+				return pr, fmt.Errorf("%q: %w", zf.Name, err)
+			}
+			err = addFile(zf.Name, rc)
+			rc.Close()
+			if err != nil {
+				return pr, err
+			}
+		}
+	case packTarGz:
+		f, err := os.Open(filename)
+		if err != nil {
+			return pr, err
+		}
+		defer f.Close()
+
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			return pr, err
+		}
+		defer gr.Close()
+
+		tr := tar.NewReader(gr)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return pr, err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if err := addFile(hdr.Name, tr); err != nil {
+				return pr, err
+			}
+		}
+	default:
+		panic(fmt.Sprintf("not a resource pack format: %v", format))
+	}
+
+	return pr, nil
+}
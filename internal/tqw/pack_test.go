@@ -0,0 +1,149 @@
+package tqw
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testPackWorld = `format = "TUNA"
+type = "DATA"
+
+[world]
+start = "KITCHEN"
+
+[[room]]
+label = "KITCHEN"
+name = "the kitchen"
+description = "A kitchen."
+`
+
+func writeTestZip(t *testing.T, files map[string]string) string {
+	filename := filepath.Join(t.TempDir(), "world.tqz")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	zw := zip.NewWriter(f)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func writeTestTarGz(t *testing.T, files map[string]string) string {
+	filename := filepath.Join(t.TempDir(), "world.tar.gz")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for name, content := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func Test_LoadResourceBundle_pack(t *testing.T) {
+	testCases := []struct {
+		name      string
+		write     func(t *testing.T, files map[string]string) string
+		files     map[string]string
+		expectErr error
+	}{
+		{
+			name:  "zip",
+			write: writeTestZip,
+			files: map[string]string{
+				"manifest.tqw":      "format = \"TUNA\"\ntype = \"MANIFEST\"\nfiles = [\"rooms/kitchen.tqw\"]\n",
+				"rooms/kitchen.tqw": testPackWorld,
+			},
+		},
+		{
+			name:  "tar.gz with differently-named root manifest",
+			write: writeTestTarGz,
+			files: map[string]string{
+				"./game.tqw":        "format = \"TUNA\"\ntype = \"MANIFEST\"\nfiles = [\"rooms/kitchen.tqw\"]\n",
+				"rooms/kitchen.tqw": testPackWorld,
+			},
+		},
+		{
+			name:  "manifest refers outside of pack",
+			write: writeTestZip,
+			files: map[string]string{
+				"manifest.tqw":      "format = \"TUNA\"\ntype = \"MANIFEST\"\nfiles = [\"../kitchen.tqw\"]\n",
+				"rooms/kitchen.tqw": testPackWorld,
+			},
+			expectErr: ErrPackTraversal,
+		},
+		{
+			name:  "file in archive is outside of pack",
+			write: writeTestTarGz,
+			files: map[string]string{
+				"manifest.tqw":   "format = \"TUNA\"\ntype = \"MANIFEST\"\nfiles = [\"kitchen.tqw\"]\n",
+				"../kitchen.tqw": testPackWorld,
+			},
+			expectErr: ErrPackTraversal,
+		},
+		{
+			name:  "no root manifest",
+			write: writeTestZip,
+			files: map[string]string{
+				"rooms/manifest.tqw": "format = \"TUNA\"\ntype = \"MANIFEST\"\nfiles = [\"kitchen.tqw\"]\n",
+				"rooms/kitchen.tqw":  testPackWorld,
+			},
+			expectErr: ErrPackNoManifest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			filename := tc.write(t, tc.files)
+
+			world, err := LoadResourceBundle(filename)
+			if tc.expectErr != nil {
+				assert.ErrorIs(err, tc.expectErr)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+
+			assert.Equal("KITCHEN", world.Start)
+			assert.Contains(world.Rooms, "KITCHEN")
+		})
+	}
+}
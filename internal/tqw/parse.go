@@ -7,6 +7,7 @@ import (
 
 	"github.com/dekarrin/tunaq/internal/command"
 	"github.com/dekarrin/tunaq/internal/game"
+	"github.com/dekarrin/tunaq/internal/util"
 	"github.com/dekarrin/tunaq/tunascript"
 )
 
@@ -46,6 +47,8 @@ type worldSymbols struct {
 	npcLabels     stringSet
 	npcAliases    stringSet
 	flagLabels    stringSet
+	eventLabels   stringSet
+	verbNames     stringSet
 }
 
 // raw is what to set raw to, parsed is the parsed code to set, err is any error
@@ -109,9 +112,16 @@ func parseWorldData(tqw topLevelWorldData) (WorldData, error) {
 		tqw.Rooms[roomIdx] = r
 	}
 
+	// the words that commands are parsed with decide which words can't be
+	// used in symbols, so they come before anything else.
+	vocab, err := parseVocabulary(tqw.Vocabulary, tqw.Verbs)
+	if err != nil {
+		return world, fmt.Errorf("vocabulary: %w", err)
+	}
+
 	// next, get all of our game symbols so we can immediately check validity
 	// of every reference as we go through it.
-	symbols, err := scanSymbols(tqw)
+	symbols, err := scanSymbols(tqw, vocab)
 	if err != nil {
 		return world, err
 	}
@@ -125,6 +135,7 @@ func parseWorldData(tqw topLevelWorldData) (WorldData, error) {
 		return world, fmt.Errorf("world: start: no room with label %q exists", tqw.World.Start)
 	}
 	world.Start = strings.ToUpper(tqw.World.Start)
+	world.Seed = tqw.World.Seed
 
 	// validate rooms
 	for _, r := range tqw.Rooms {
@@ -142,6 +153,10 @@ func parseWorldData(tqw topLevelWorldData) (WorldData, error) {
 			}
 			room.Exits[i].IfRaw = raw
 			room.Exits[i].If = tsAST
+
+			if err := parseLockTunascript(room.Exits[i].Lock); err != nil {
+				return world, fmt.Errorf("rooms[%q]: exits[%d]: lock: %w", r.Label, i, err)
+			}
 		}
 
 		// run a parse on the tunascript and set the If of each detail
@@ -152,12 +167,38 @@ func parseWorldData(tqw topLevelWorldData) (WorldData, error) {
 			}
 			room.Details[i].IfRaw = raw
 			room.Details[i].If = tsAST
+
+			if err := parseLockTunascript(room.Details[i].Lock); err != nil {
+				return world, fmt.Errorf("rooms[%q]: detail[%d]: lock: %w", r.Label, i, err)
+			}
+			if err := parseVerbActionsTunascript(room.Details[i].OnVerb); err != nil {
+				return world, fmt.Errorf("rooms[%q]: detail[%d]: %w", r.Label, i, err)
+			}
+		}
+
+		// run a parse on the tunascript of the room's hooks
+		var err error
+		room.OnEnter, err = parseTunascriptStatements(room.OnEnterRaw)
+		if err != nil {
+			return world, fmt.Errorf("rooms[%q]: on_enter: %w", r.Label, err)
+		}
+		room.OnFirstEnter, err = parseTunascriptStatements(room.OnFirstEnterRaw)
+		if err != nil {
+			return world, fmt.Errorf("rooms[%q]: on_first_enter: %w", r.Label, err)
+		}
+		room.OnExit, err = parseTunascriptStatements(room.OnExitRaw)
+		if err != nil {
+			return world, fmt.Errorf("rooms[%q]: on_exit: %w", r.Label, err)
+		}
+		if err := parseVerbActionsTunascript(room.OnVerb); err != nil {
+			return world, fmt.Errorf("rooms[%q]: %w", r.Label, err)
 		}
 
 		world.Rooms[r.Label] = &room
 	}
 
 	// validate items
+	var gameItems []*game.Item
 	for _, it := range tqw.Items {
 		itemErr := validateItemDef(it, symbols)
 		if itemErr != nil {
@@ -187,22 +228,30 @@ func parseWorldData(tqw topLevelWorldData) (WorldData, error) {
 			ou.If = tsAST
 
 			// next, check the Do's
-			var doAST tunascript.AST
-			for j := range ou.DoRaw {
-				stmtRaw, stmtAST, stmtErr := parseTunascript(ou.DoRaw[j], true)
-				if stmtErr != nil {
-					return world, fmt.Errorf("items[%q]: on_use[%d]: do[%d]: %w", it.Label, i, j, stmtErr)
-				}
-				ou.DoRaw[j] = stmtRaw
-				doAST.Nodes = append(doAST.Nodes, stmtAST.Nodes[0])
+			ou.Do, err = parseTunascriptStatements(ou.DoRaw)
+			if err != nil {
+				return world, fmt.Errorf("items[%q]: on_use[%d]: %w", it.Label, i, err)
 			}
-			ou.Do = doAST
 
 			gameItem.OnUse[i] = ou
 		}
 
-		r := world.Rooms[strings.ToUpper(it.Start)]
-		r.Items = append(r.Items, &gameItem)
+		// and of the take, drop, and look hooks
+		hooks := map[string]*game.ItemHook{"on_take": &gameItem.OnTake, "on_drop": &gameItem.OnDrop, "on_look": &gameItem.OnLook}
+		for _, hookName := range util.OrderedKeys(hooks) {
+			if err := parseItemHookTunascript(hooks[hookName]); err != nil {
+				return world, fmt.Errorf("items[%q]: %s: %w", it.Label, hookName, err)
+			}
+		}
+
+		if err := parseLockTunascript(gameItem.Lock); err != nil {
+			return world, fmt.Errorf("items[%q]: lock: %w", it.Label, err)
+		}
+		if err := parseVerbActionsTunascript(gameItem.OnVerb); err != nil {
+			return world, fmt.Errorf("items[%q]: %w", it.Label, err)
+		}
+
+		gameItems = append(gameItems, &gameItem)
 	}
 
 	// validate pronouns and gather them into a map for later conversion of NPC
@@ -260,17 +309,371 @@ func parseWorldData(tqw topLevelWorldData) (WorldData, error) {
 		gameNPC.IfRaw = raw
 		gameNPC.If = tsAST
 
+		for i := range gameNPC.Dialog {
+			if err := parseDialogStepTunascript(gameNPC.Dialog[i]); err != nil {
+				return world, fmt.Errorf("npcs[%q]: dialogs[%d]: %w", npc.Label, i, err)
+			}
+		}
+
+		reactions := map[string][]game.GiveAction{"on_give": gameNPC.OnGive, "on_show": gameNPC.OnShow}
+		for _, reactionName := range util.OrderedKeys(reactions) {
+			for i := range reactions[reactionName] {
+				if err := parseGiveActionTunascript(&reactions[reactionName][i]); err != nil {
+					return world, fmt.Errorf("npcs[%q]: %s[%d]: %w", npc.Label, reactionName, i, err)
+				}
+			}
+		}
+		if err := parseVerbActionsTunascript(gameNPC.OnVerb); err != nil {
+			return world, fmt.Errorf("npcs[%q]: %w", npc.Label, err)
+		}
+
 		world.Rooms[gameNPC.Start].NPCs[gameNPC.Label] = &gameNPC
 	}
 
+	// only now that every item, detail, and NPC exists can items be put where
+	// they start
+	if err := placeItems(world.Rooms, gameItems, tqw.Items); err != nil {
+		return world, err
+	}
+
+	if err := validateSharedLocks(world.Rooms); err != nil {
+		return world, err
+	}
+
 	// Flags were already checked in the symbol scan. Add them to world data
 	for _, fl := range tqw.Flags {
 		world.Flags[strings.ToUpper(fl.Label)] = fl.Default
 	}
 
+	// validate events
+	for _, ev := range tqw.Events {
+		if err := validateEventDef(ev); err != nil {
+			return world, fmt.Errorf("events[%q]: %w", ev.Label, err)
+		}
+
+		gameEvent := ev.toGameEvent()
+
+		raw, tsAST, err := parseTunascript(gameEvent.IfRaw, false)
+		if err != nil {
+			return world, fmt.Errorf("events[%q]: if: %w", ev.Label, err)
+		}
+		gameEvent.IfRaw = raw
+		gameEvent.If = tsAST
+
+		gameEvent.Do, err = parseTunascriptStatements(gameEvent.DoRaw)
+		if err != nil {
+			return world, fmt.Errorf("events[%q]: do: %w", ev.Label, err)
+		}
+
+		world.Events = append(world.Events, &gameEvent)
+	}
+
+	// validate verbs
+	var verbDefs []command.VerbDef
+	for _, v := range tqw.Verbs {
+		if err := validateVerbDef(v, vocab); err != nil {
+			return world, fmt.Errorf("verbs[%q]: %w", v.Name, err)
+		}
+
+		gameVerb := v.toGameCustomVerb()
+		gameVerb.Def.Preposition = vocab.Canonical(gameVerb.Def.Preposition)
+		world.Verbs = append(world.Verbs, &gameVerb)
+		verbDefs = append(verbDefs, gameVerb.Def)
+	}
+	vocab.SetCustomVerbs(verbDefs)
+	world.Vocabulary = vocab
+
+	world.Fingerprint = fingerprint(world)
+
 	return world, nil
 }
 
+// parseItemHookTunascript parses the 'if' and 'do' tunascript of an item hook
+// and sets the ASTs in it. Unlike other ifs, an empty 'if' on a hook is left
+// empty, which allows the action.
+func parseItemHookTunascript(hook *game.ItemHook) error {
+	if strings.TrimSpace(hook.IfRaw) == "" {
+		hook.IfRaw = ""
+	} else {
+		var err error
+		hook.IfRaw, hook.If, err = parseTunascript(hook.IfRaw, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	hook.Do, err = parseTunascriptStatements(hook.DoRaw)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// parseGiveActionTunascript parses the 'if' and 'do' tunascript of an NPC's
+// reaction to being given or shown an item and sets the ASTs in it.
+func parseGiveActionTunascript(act *game.GiveAction) error {
+	var err error
+
+	act.IfRaw, act.If, err = parseTunascript(act.IfRaw, false)
+	if err != nil {
+		return fmt.Errorf("if: %w", err)
+	}
+	act.Do, err = parseTunascriptStatements(act.DoRaw)
+	return err
+}
+
+// parseVerbActionsTunascript parses the 'if' and 'do' tunascript of each of
+// actions and sets the ASTs in them.
+func parseVerbActionsTunascript(actions []game.VerbAction) error {
+	for i := range actions {
+		var err error
+		actions[i].IfRaw, actions[i].If, err = parseTunascript(actions[i].IfRaw, false)
+		if err != nil {
+			return fmt.Errorf("on_verb[%d]: if: %w", i, err)
+		}
+		actions[i].Do, err = parseTunascriptStatements(actions[i].DoRaw)
+		if err != nil {
+			return fmt.Errorf("on_verb[%d]: do: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// parseLockTunascript parses the 'if' tunascript of lock and sets the AST in
+// it. If lock is nil, this has no effect.
+func parseLockTunascript(lock *game.Lock) error {
+	if lock == nil {
+		return nil
+	}
+
+	var err error
+	lock.IfRaw, lock.If, err = parseTunascript(lock.IfRaw, false)
+	return err
+}
+
+// placeItems puts each of the given items where it starts; on the ground in a
+// room, held by an NPC, or inside of an item or detail with a container. The
+// unconverted items must be given in defs in the same order as items.
+func placeItems(rooms map[string]*game.Room, items []*game.Item, defs []item) error {
+	itemsByLabel := map[string]*game.Item{}
+	for _, it := range items {
+		itemsByLabel[it.Label] = it
+	}
+	details := map[string]*game.Detail{}
+	npcs := map[string]*game.NPC{}
+	for _, r := range rooms {
+		for _, det := range r.Details {
+			details[det.Label] = det
+		}
+		for _, npc := range r.NPCs {
+			npcs[npc.Label] = npc
+		}
+	}
+
+	starts := map[string]string{}
+	for i, it := range items {
+		starts[it.Label] = strings.ToUpper(defs[i].Start)
+	}
+
+	for i, it := range items {
+		start := starts[it.Label]
+
+		if r, ok := rooms[start]; ok {
+			r.Items = append(r.Items, it)
+			continue
+		}
+		if npc, ok := npcs[start]; ok {
+			npc.Inventory[it.Label] = it
+			continue
+		}
+
+		var c *game.Container
+		if holder, ok := itemsByLabel[start]; ok {
+			// make sure it doesn't end up inside of itself. if there is a loop
+			// that it isn't part of, it will be caught when one of the items
+			// in the loop is placed
+			for loc, steps := start, 0; itemsByLabel[loc] != nil && steps <= len(items); loc, steps = starts[loc], steps+1 {
+				if loc == it.Label {
+					return fmt.Errorf("items[%q]: start: item would be inside of itself", defs[i].Label)
+				}
+			}
+			c = holder.Container
+		} else if det, ok := details[start]; ok {
+			c = det.Container
+		}
+		if c == nil {
+			return fmt.Errorf("items[%q]: start: %q does not have a 'container' to put items in", defs[i].Label, start)
+		}
+		c.Items = append(c.Items, it)
+	}
+
+	return nil
+}
+
+// validateSharedLocks checks that every exit, detail, and item whose locks
+// share a label agree on how it starts out, and that no lock's label is the
+// same as that of an exit, detail, or item with a different lock.
+func validateSharedLocks(rooms map[string]*game.Room) error {
+	locks := map[string]*game.Lock{}
+	lockOf := map[string]string{}
+	where := map[string]string{}
+
+	checkLock := func(loc string, thingLabel string, l *game.Lock) error {
+		if l == nil {
+			return nil
+		}
+
+		if other, ok := locks[l.Label]; ok {
+			if other.Openable != l.Openable || other.Lockable != l.Lockable || other.Open != l.Open || other.Locked != l.Locked {
+				return fmt.Errorf("%s: lock: shares label %q with the lock of %s but starts differently", loc, l.Label, where[l.Label])
+			}
+		} else {
+			locks[l.Label] = l
+			where[l.Label] = loc
+		}
+
+		for _, label := range []string{thingLabel, l.Label} {
+			if existing, ok := lockOf[label]; ok && existing != l.Label {
+				return fmt.Errorf("%s: lock: label %q is already used by another lock", loc, label)
+			}
+			lockOf[label] = l.Label
+		}
+		return nil
+	}
+
+	for _, roomLabel := range util.OrderedKeys(rooms) {
+		r := rooms[roomLabel]
+		for i, eg := range r.Exits {
+			if err := checkLock(fmt.Sprintf("rooms[%q]: exits[%d]", roomLabel, i), eg.Label, eg.Lock); err != nil {
+				return err
+			}
+		}
+		for i, det := range r.Details {
+			if err := checkLock(fmt.Sprintf("rooms[%q]: detail[%d]", roomLabel, i), det.Label, det.Lock); err != nil {
+				return err
+			}
+		}
+		for _, it := range r.AllItems() {
+			if err := checkLock(fmt.Sprintf("items[%q]", it.Label), it.Label, it.Lock); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseTunascriptStatements parses each of the given tunascript statements,
+// which may have side effects, and returns them combined into one AST that
+// executes each in order. Each element of raws is updated to what
+// parseTunascript gives for it.
+func parseTunascriptStatements(raws []string) (tunascript.AST, error) {
+	var ast tunascript.AST
+	for i := range raws {
+		stmtRaw, stmtAST, err := parseTunascript(raws[i], true)
+		if err != nil {
+			return tunascript.AST{}, fmt.Errorf("do[%d]: %w", i, err)
+		}
+		raws[i] = stmtRaw
+		ast.Nodes = append(ast.Nodes, stmtAST.Nodes[0])
+	}
+	return ast, nil
+}
+
+// parseDialogStepTunascript parses the 'if' and 'do' of the given dialog step
+// and of each of its choices and sets the ASTs of them in it.
+func parseDialogStepTunascript(ds *game.DialogStep) error {
+	var err error
+
+	ds.IfRaw, ds.If, err = parseTunascript(ds.IfRaw, false)
+	if err != nil {
+		return fmt.Errorf("if: %w", err)
+	}
+	ds.Do, err = parseTunascriptStatements(ds.DoRaw)
+	if err != nil {
+		return err
+	}
+
+	for i := range ds.ChoiceScripts {
+		cs := ds.ChoiceScripts[i]
+
+		cs.IfRaw, cs.If, err = parseTunascript(cs.IfRaw, false)
+		if err != nil {
+			return fmt.Errorf("choice[%d]: if: %w", i, err)
+		}
+		cs.Do, err = parseTunascriptStatements(cs.DoRaw)
+		if err != nil {
+			return fmt.Errorf("choice[%d]: %w", i, err)
+		}
+
+		ds.ChoiceScripts[i] = cs
+	}
+
+	return nil
+}
+
+// parseVocabulary checks the vocabulary section of a world and returns the
+// default Vocabulary with its aliases and words added. Aliases can expand to
+// built-in verbs or to the verbs in verbDefs.
+func parseVocabulary(voc vocabulary, verbDefs []verb) (*command.Vocabulary, error) {
+	vocab := command.NewVocabulary()
+
+	// words first, as aliases can't contain them
+	for _, word := range util.OrderedKeys(voc.Words) {
+		wordUpper := strings.ToUpper(word)
+		meaningUpper := strings.ToUpper(voc.Words[word])
+
+		if command.IsReservedWord(wordUpper) {
+			return nil, fmt.Errorf("words[%q]: %q is already a reserved word", word, wordUpper)
+		}
+		if err := checkAlias(wordUpper, make(stringSet), vocab); err != nil {
+			return nil, fmt.Errorf("words[%q]: %w", word, err)
+		}
+		if strings.Contains(wordUpper, " ") {
+			return nil, fmt.Errorf("words[%q]: must be a single word", word)
+		}
+		if !command.IsReservedWord(meaningUpper) {
+			reserved := command.NewVocabulary().ReservedWords()
+			return nil, fmt.Errorf("words[%q]: must be one of %s", word, strings.Join(reserved, ", "))
+		}
+		vocab.SetWord(wordUpper, meaningUpper)
+	}
+
+	verbNames := make(stringSet)
+	for _, v := range verbDefs {
+		verbNames[strings.ToUpper(v.Name)] = true
+	}
+
+	seen := make(stringSet)
+	for _, alias := range util.OrderedKeys(voc.Aliases) {
+		aliasUpper := strings.ToUpper(alias)
+		expansion := strings.Fields(strings.ToUpper(voc.Aliases[alias]))
+
+		if aliasUpper == "" {
+			return nil, fmt.Errorf("aliases: alias must not be blank")
+		}
+		if err := checkAlias(aliasUpper, seen, vocab); err != nil {
+			return nil, fmt.Errorf("aliases[%q]: %w", alias, err)
+		}
+		if len(strings.Fields(aliasUpper)) > command.MaxAliasWords {
+			return nil, fmt.Errorf("aliases[%q]: must be at most %d words long", alias, command.MaxAliasWords)
+		}
+		if command.IsVerb(aliasUpper) {
+			return nil, fmt.Errorf("aliases[%q]: %q is a built-in verb and can't be an alias", alias, aliasUpper)
+		}
+		if len(expansion) > 0 && !command.IsVerb(expansion[0]) && !verbNames[expansion[0]] {
+			return nil, fmt.Errorf("aliases[%q]: %q is not a verb", alias, expansion[0])
+		}
+		seen[aliasUpper] = true
+
+		vocab.SetAlias(aliasUpper, strings.Join(expansion, " "))
+	}
+
+	return vocab, nil
+}
+
 // this builds up a pre-list of 'seen' labels and aliases so we can check for
 // pointers later. All of them will be checked for conflicts within their own
 // class of objects and all of them will be checked for validity as either a
@@ -288,7 +691,7 @@ func parseWorldData(tqw topLevelWorldData) (WorldData, error) {
 // if any of them conflicts with another. Otherwise, global symbols are returned
 // so that they can be used to check references to them. The global symbols
 // returned will all be converted to upper case already.
-func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
+func scanSymbols(top topLevelWorldData, vocab *command.Vocabulary) (symbols worldSymbols, err error) {
 	syms := worldSymbols{
 		roomLabels:   make(stringSet),
 		detailLabels: make(stringSet),
@@ -304,9 +707,11 @@ func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
 			"IT/ITS":    true,
 		},
 
-		npcLabels:  make(stringSet),
-		npcAliases: make(stringSet),
-		flagLabels: make(stringSet),
+		npcLabels:   make(stringSet),
+		npcAliases:  make(stringSet),
+		flagLabels:  make(stringSet),
+		eventLabels: make(stringSet),
+		verbNames:   make(stringSet),
 	}
 
 	// not doing egressAliases because that is not something that other things
@@ -318,14 +723,14 @@ func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
 	// But do make sure we pick up egress and detail labels here
 	for _, r := range top.Rooms {
 		rLabelUpper := strings.ToUpper(r.Label)
-		if err := checkLabel(rLabelUpper, syms.roomLabels, "a room"); err != nil {
+		if err := checkLabel(rLabelUpper, syms.roomLabels, "a room", vocab); err != nil {
 			return syms, fmt.Errorf("room %q: %w", r.Label, err)
 		}
 		syms.roomLabels[rLabelUpper] = true
 
 		for i, eg := range r.Exits {
 			egLabelUpper := strings.ToUpper(eg.Label)
-			if err := checkLabel(egLabelUpper, syms.egressLabels, "an exit"); err != nil {
+			if err := checkLabel(egLabelUpper, syms.egressLabels, "an exit", vocab); err != nil {
 				return syms, fmt.Errorf("room %q: exit %d: %w", r.Label, i, err)
 			}
 			syms.egressLabels[egLabelUpper] = true
@@ -333,7 +738,7 @@ func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
 
 		for i, det := range r.Details {
 			detLabelUpper := strings.ToUpper(det.Label)
-			if err := checkLabel(detLabelUpper, syms.detailLabels, "a detail"); err != nil {
+			if err := checkLabel(detLabelUpper, syms.detailLabels, "a detail", vocab); err != nil {
 				return syms, fmt.Errorf("room %q: detail %d: %w", r.Label, i, err)
 			}
 			syms.detailLabels[detLabelUpper] = true
@@ -343,24 +748,34 @@ func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
 	// scan items
 	for _, it := range top.Items {
 		itLabelUpper := strings.ToUpper(it.Label)
-		if err := checkLabel(itLabelUpper, syms.itemLabels, "an item"); err != nil {
+		if err := checkLabel(itLabelUpper, syms.itemLabels, "an item", vocab); err != nil {
 			return syms, fmt.Errorf("item %q: %w", it.Label, err)
 		}
 		syms.itemLabels[itLabelUpper] = true
 
 		for _, alias := range it.Aliases {
 			aliasUpper := strings.ToUpper(alias)
-			if err := checkAlias(aliasUpper, syms.itemAliases); err != nil {
+			if err := checkAlias(aliasUpper, syms.itemAliases, vocab); err != nil {
 				return syms, fmt.Errorf("item %q: alias %q: %w", it.Label, alias, err)
 			}
 			syms.itemLabels[itLabelUpper] = true
 		}
+
+		for _, adj := range it.Adjectives {
+			adjUpper := strings.ToUpper(adj)
+			if strings.Contains(adjUpper, " ") {
+				return syms, fmt.Errorf("item %q: adjective %q: adjectives must be a single word", it.Label, adj)
+			}
+			if err := checkAlias(adjUpper, make(stringSet), vocab); err != nil {
+				return syms, fmt.Errorf("item %q: adjective %q: %w", it.Label, adj, err)
+			}
+		}
 	}
 
 	// scan pronouns
 	for _, ps := range top.Pronouns {
 		psLabelUpper := strings.ToUpper(ps.Label)
-		if err := checkLabel(psLabelUpper, syms.pronounLabels, "pronouns"); err != nil {
+		if err := checkLabel(psLabelUpper, syms.pronounLabels, "pronouns", vocab); err != nil {
 			return syms, fmt.Errorf("pronouns %q: %w", ps.Label, err)
 		}
 		syms.pronounLabels[psLabelUpper] = true
@@ -369,14 +784,14 @@ func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
 	// scan npc labels and aliases
 	for _, npc := range top.NPCs {
 		npcLabelUpper := strings.ToUpper(npc.Label)
-		if err := checkLabel(npcLabelUpper, syms.npcLabels, "an NPC"); err != nil {
+		if err := checkLabel(npcLabelUpper, syms.npcLabels, "an NPC", vocab); err != nil {
 			return syms, fmt.Errorf("npc %q: %w", npc.Label, err)
 		}
 		syms.npcLabels[npcLabelUpper] = true
 
 		for _, alias := range npc.Aliases {
 			aliasUpper := strings.ToUpper(alias)
-			if err := checkAlias(aliasUpper, syms.npcAliases); err != nil {
+			if err := checkAlias(aliasUpper, syms.npcAliases, vocab); err != nil {
 				return syms, fmt.Errorf("npc %q: alias %q: %w", npc.Label, alias, err)
 			}
 			syms.npcAliases[aliasUpper] = true
@@ -385,12 +800,42 @@ func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
 
 	for _, fl := range top.Flags {
 		flUpper := strings.ToUpper(fl.Label)
-		if err := checkLabel(flUpper, symbols.flagLabels, "a flag"); err != nil {
+		if err := checkLabel(flUpper, symbols.flagLabels, "a flag", vocab); err != nil {
 			return syms, fmt.Errorf("flag: %w", err)
 		}
 		syms.flagLabels[flUpper] = true
 	}
 
+	for _, ev := range top.Events {
+		evUpper := strings.ToUpper(ev.Label)
+		if err := checkLabel(evUpper, syms.eventLabels, "an event", vocab); err != nil {
+			return syms, fmt.Errorf("event %q: %w", ev.Label, err)
+		}
+		syms.eventLabels[evUpper] = true
+	}
+
+	// verb names and aliases can't be used for more than one verb
+	verbWords := make(stringSet)
+	for _, v := range top.Verbs {
+		nameUpper := strings.ToUpper(v.Name)
+		if err := checkVerbWord(nameUpper, verbWords, vocab); err != nil {
+			return syms, fmt.Errorf("verb %q: %w", v.Name, err)
+		}
+		if strings.Contains(nameUpper, " ") {
+			return syms, fmt.Errorf("verb %q: name must be a single word", v.Name)
+		}
+		verbWords[nameUpper] = true
+		syms.verbNames[nameUpper] = true
+
+		for _, alias := range v.Aliases {
+			aliasUpper := strings.ToUpper(alias)
+			if err := checkVerbWord(aliasUpper, verbWords, vocab); err != nil {
+				return syms, fmt.Errorf("verb %q: alias %q: %w", v.Name, alias, err)
+			}
+			verbWords[aliasUpper] = true
+		}
+	}
+
 	// end of getting global symbols
 	// now check the non-global ones
 
@@ -402,19 +847,19 @@ func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
 				aliasUpper := strings.ToUpper(alias)
 
 				// check against other room aliases
-				if err := checkAlias(aliasUpper, detailAliasesInRoom); err != nil {
+				if err := checkAlias(aliasUpper, detailAliasesInRoom, vocab); err != nil {
 					return syms, fmt.Errorf("room %q: detail %d: alias %q: %w", r.Label, detIdx, alias, err)
 				}
 
 				// check against item aliases
-				if err := checkAlias(aliasUpper, syms.itemLabels); err != nil {
+				if err := checkAlias(aliasUpper, syms.itemLabels, vocab); err != nil {
 					// first check alias check would have caught invalid label,
 					// so if this failed it MUST be due to matching the conflict set
 					return syms, fmt.Errorf("room %q: detail %d: alias %q conflicts with item alias", r.Label, detIdx, alias)
 				}
 
 				// check against NPC aliases
-				if err := checkAlias(aliasUpper, syms.npcLabels); err != nil {
+				if err := checkAlias(aliasUpper, syms.npcLabels, vocab); err != nil {
 					// first alias check would have caught invalid label,
 					// so if this failed it MUST be due to matching the conflict set
 					return syms, fmt.Errorf("room %q: detail %d: alias %q conflicts with NPC alias", r.Label, detIdx, alias)
@@ -433,19 +878,19 @@ func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
 				aliasUpper := strings.ToUpper(alias)
 
 				// check against other room aliases
-				if err := checkAlias(aliasUpper, exitAliasesInRoom); err != nil {
+				if err := checkAlias(aliasUpper, exitAliasesInRoom, vocab); err != nil {
 					return syms, fmt.Errorf("room %q: exit %d: alias %q: %w", r.Label, exitIdx, alias, err)
 				}
 
 				// check against item aliases
-				if err := checkAlias(aliasUpper, syms.itemLabels); err != nil {
+				if err := checkAlias(aliasUpper, syms.itemLabels, vocab); err != nil {
 					// first check alias check would have caught invalid label,
 					// so if this failed it MUST be due to matching the conflict set
 					return syms, fmt.Errorf("room %q: exit %d: alias %q conflicts with item alias", r.Label, exitIdx, alias)
 				}
 
 				// check against NPC aliases
-				if err := checkAlias(aliasUpper, syms.npcLabels); err != nil {
+				if err := checkAlias(aliasUpper, syms.npcLabels, vocab); err != nil {
 					// first alias check would have caught invalid label,
 					// so if this failed it MUST be due to matching the conflict set
 					return syms, fmt.Errorf("room %q: exit %d: alias %q conflicts with NPC alias", r.Label, exitIdx, alias)
@@ -469,7 +914,7 @@ func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
 		for detIdx, det := range r.Details {
 			for _, alias := range det.Aliases {
 				alUpper := strings.ToUpper(alias)
-				if err := checkAlias(alUpper, exitAliasesInRoom); err != nil {
+				if err := checkAlias(alUpper, exitAliasesInRoom, vocab); err != nil {
 					// first alias check would have caught invalid label,
 					// so if this failed it MUST be due to matching the conflict set
 					return syms, fmt.Errorf("room %q: detail %d: alias %q conflicts with exit alias", r.Label, detIdx, alias)
@@ -489,7 +934,7 @@ func scanSymbols(top topLevelWorldData) (symbols worldSymbols, err error) {
 				diaLabelUpper = fmt.Sprintf("%d", idx)
 			}
 
-			if err := checkLabel(diaLabelUpper, diaLabelsInTree, "a step in this NPC's dialog tree"); err != nil {
+			if err := checkLabel(diaLabelUpper, diaLabelsInTree, "a step in this NPC's dialog tree", vocab); err != nil {
 				return syms, fmt.Errorf("npc %q: dialogs[%q]: %w", npc.Label, idx, err)
 			}
 			diaLabelsInTree[diaLabelUpper] = true
@@ -559,7 +1004,18 @@ func validateNPCDef(npc npc, topLevelPronouns map[string]pronounSet, parsedRooms
 		}
 	}
 
-	return nil
+	for i := range npc.OnGive {
+		if err := validateGiveActionDef(npc.OnGive[i], syms); err != nil {
+			return fmt.Errorf("on_give[%d]: %w", i, err)
+		}
+	}
+	for i := range npc.OnShow {
+		if err := validateGiveActionDef(npc.OnShow[i], syms); err != nil {
+			return fmt.Errorf("on_show[%d]: %w", i, err)
+		}
+	}
+
+	return validateVerbActionDefs(npc.OnVerb, syms)
 }
 
 func validateDialogStepDef(ds dialogStep, allDiaLabels stringSet) error {
@@ -575,6 +1031,9 @@ func validateDialogStepDef(ds dialogStep, allDiaLabels stringSet) error {
 		if len(ds.Choices) > 0 {
 			return fmt.Errorf("'LINE' dialog step type does not use 'choices' key")
 		}
+		if len(ds.Choice) > 0 {
+			return fmt.Errorf("'LINE' dialog step type does not use 'choice' sub-sections")
+		}
 		if ds.Content == "" {
 			return fmt.Errorf("'LINE' dialog step type requires a string as value of 'content' property")
 		}
@@ -582,8 +1041,11 @@ func validateDialogStepDef(ds dialogStep, allDiaLabels stringSet) error {
 			return fmt.Errorf("'LINE' dialog step type does not use 'continue' key")
 		}
 	case game.DialogChoice:
-		if len(ds.Choices) < 2 {
-			return fmt.Errorf("'CHOICE' dialog step type must have a list with at least 2 choices as value of 'choices' property")
+		if len(ds.Choices) > 0 && len(ds.Choice) > 0 {
+			return fmt.Errorf("'CHOICE' dialog step type must give choices with either the 'choices' property or 'choice' sub-sections, not both")
+		}
+		if len(ds.Choices) < 2 && len(ds.Choice) < 2 {
+			return fmt.Errorf("'CHOICE' dialog step type must have a list with at least 2 choices as value of 'choices' property or at least 2 'choice' sub-sections")
 		}
 		if ds.Response != "" {
 			return fmt.Errorf("'CHOICE' dialog step type does not use 'response' property")
@@ -607,12 +1069,26 @@ func validateDialogStepDef(ds dialogStep, allDiaLabels stringSet) error {
 				return fmt.Errorf("choices[%d]: %q is not the label of any step in this NPC's dialog tree", idx, ch[1])
 			}
 		}
+		for idx, ch := range ds.Choice {
+			if ch.Text == "" {
+				return fmt.Errorf("choice[%d]: text: cannot be blank", idx)
+			}
+			if _, ok := allDiaLabels[strings.ToUpper(ch.Goto)]; !ok {
+				return fmt.Errorf("choice[%d]: goto: %q is not the label of any step in this NPC's dialog tree", idx, ch.Goto)
+			}
+		}
 	case game.DialogEnd:
 		if ds.Response != "" {
 			return fmt.Errorf("'END' dialog step type does not use 'response' property")
 		}
-		if len(ds.Choices) > 0 {
-			return fmt.Errorf("'END' dialog step type does not use 'choices' property")
+		if len(ds.Choices) > 0 || len(ds.Choice) > 0 {
+			return fmt.Errorf("'END' dialog step type does not use 'choices' property or 'choice' sub-sections")
+		}
+		if ds.If != "" {
+			return fmt.Errorf("'END' dialog step type does not use 'if' property")
+		}
+		if len(ds.Do) > 0 {
+			return fmt.Errorf("'END' dialog step type does not use 'do' property")
 		}
 		if ds.Content != "" {
 			return fmt.Errorf("'END' dialog step does not use 'content' property")
@@ -624,8 +1100,14 @@ func validateDialogStepDef(ds dialogStep, allDiaLabels stringSet) error {
 		if ds.Response != "" {
 			return fmt.Errorf("'PAUSE' dialog step type does not use 'response' property")
 		}
-		if len(ds.Choices) > 0 {
-			return fmt.Errorf("'PAUSE' dialog step type does not use 'choices' property")
+		if len(ds.Choices) > 0 || len(ds.Choice) > 0 {
+			return fmt.Errorf("'PAUSE' dialog step type does not use 'choices' property or 'choice' sub-sections")
+		}
+		if ds.If != "" {
+			return fmt.Errorf("'PAUSE' dialog step type does not use 'if' property")
+		}
+		if len(ds.Do) > 0 {
+			return fmt.Errorf("'PAUSE' dialog step type does not use 'do' property")
 		}
 		if ds.Content != "" {
 			return fmt.Errorf("'PAUSE' dialog step does not use 'content' property")
@@ -650,7 +1132,11 @@ func validateRouteDef(ps route, parsedRooms map[string]*game.Room, npcStart stri
 	act, ok := game.RouteActionsByString[actUpper]
 
 	if !ok {
-		return fmt.Errorf("action: must be one of 'STATIC', 'PATROL', or 'WANDER', not %q", actUpper)
+		return fmt.Errorf("action: must be one of 'STATIC', 'PATROL', 'WANDER', 'GOTO', or 'FOLLOW', not %q", actUpper)
+	}
+
+	if ps.Target != "" && act != game.RouteGoto {
+		return fmt.Errorf("'%s' route type does not use 'target' property", act)
 	}
 
 	pf := game.Pathfinder{World: parsedRooms}
@@ -721,15 +1207,37 @@ func validateRouteDef(ps route, parsedRooms map[string]*game.Room, npcStart stri
 				}
 			}
 		}
-	case game.RouteStatic:
+	case game.RouteGoto:
+		if ps.Target == "" {
+			return fmt.Errorf("'GOTO' route type must have a room label as value of 'target' property")
+		}
 		if len(ps.Path) > 0 {
-			return fmt.Errorf("'STATIC' route type does not use 'path' property")
+			return fmt.Errorf("'GOTO' route type does not use 'path' property")
 		}
 		if len(ps.Allowed) > 0 {
-			return fmt.Errorf("'STATIC' route type does not use 'allowed' property")
+			return fmt.Errorf("'GOTO' route type does not use 'allowed' property")
 		}
 		if len(ps.Forbidden) > 0 {
-			return fmt.Errorf("'STATIC' route type does not use 'forbidden' property")
+			return fmt.Errorf("'GOTO' route type does not use 'forbidden' property")
+		}
+
+		targetUpper := strings.ToUpper(ps.Target)
+		if _, ok := syms.roomLabels[targetUpper]; !ok {
+			return fmt.Errorf("target: no room with label %q exists", ps.Target)
+		}
+		source := strings.ToUpper(npcStart)
+		if source != targetUpper && len(pf.Dijkstra(source, targetUpper)) < 1 {
+			return fmt.Errorf("target: %q is not reachable from start", ps.Target)
+		}
+	case game.RouteStatic, game.RouteFollow:
+		if len(ps.Path) > 0 {
+			return fmt.Errorf("'%s' route type does not use 'path' property", act)
+		}
+		if len(ps.Allowed) > 0 {
+			return fmt.Errorf("'%s' route type does not use 'allowed' property", act)
+		}
+		if len(ps.Forbidden) > 0 {
+			return fmt.Errorf("'%s' route type does not use 'forbidden' property", act)
 		}
 	default:
 		// should never happen but you never know
@@ -776,16 +1284,16 @@ func validateRoomDef(r room, syms worldSymbols) error {
 
 	// validate details
 	for idx, det := range r.Details {
-		detErr := validateDetailDef(det)
+		detErr := validateDetailDef(det, syms)
 		if detErr != nil {
 			return fmt.Errorf("detail[%q]: %w", idx, detErr)
 		}
 	}
 
-	return nil
+	return validateVerbActionDefs(r.OnVerb, syms)
 }
 
-func validateDetailDef(det detail) error {
+func validateDetailDef(det detail, syms worldSymbols) error {
 	if det.Description == "" {
 		return fmt.Errorf("must have non-blank 'description' field")
 	}
@@ -797,6 +1305,154 @@ func validateDetailDef(det detail) error {
 		return err
 	}
 
+	if det.Lock != nil {
+		if err := validateLockDef(*det.Lock, syms); err != nil {
+			return fmt.Errorf("lock: %w", err)
+		}
+	}
+	if det.Container != nil {
+		if err := validateContainerDef(*det.Container); err != nil {
+			return fmt.Errorf("container: %w", err)
+		}
+	}
+
+	return validateVerbActionDefs(det.OnVerb, syms)
+}
+
+func validateContainerDef(c container) error {
+	if c.Capacity < 0 {
+		return fmt.Errorf("capacity: must not be negative")
+	}
+	return nil
+}
+
+func validateGiveActionDef(ga giveAction, syms worldSymbols) error {
+	if ga.Refuse && len(ga.Do) > 0 {
+		return fmt.Errorf("'do' cannot be given when it refuses the item")
+	}
+
+	for idx, with := range ga.With {
+		if strings.HasPrefix(with, "@") {
+			continue
+		}
+		if _, ok := syms.itemLabels[strings.ToUpper(with)]; !ok {
+			return fmt.Errorf("with[%d]: no item with label %q exists", idx, with)
+		}
+	}
+
+	return nil
+}
+
+func validateVerbDef(v verb, vocab *command.Vocabulary) error {
+	if _, ok := command.ArgUsesByString[strings.ToUpper(v.Object)]; v.Object != "" && !ok {
+		return fmt.Errorf("object: must be one of \"none\", \"optional\", or \"required\"")
+	}
+	if _, ok := command.ArgUsesByString[strings.ToUpper(v.Instrument)]; v.Instrument != "" && !ok {
+		return fmt.Errorf("instrument: must be one of \"none\", \"optional\", or \"required\"")
+	}
+
+	if v.Preposition == "" {
+		if v.Instrument != "" {
+			return fmt.Errorf("'instrument' can only be given with 'preposition'")
+		}
+	} else if !util.InSlice(strings.ToUpper(v.Preposition), vocab.ReservedWords()) {
+		return fmt.Errorf("preposition: must be one of %s", strings.Join(vocab.ReservedWords(), ", "))
+	}
+
+	return nil
+}
+
+func validateVerbActionDef(va verbAction, syms worldSymbols) error {
+	if va.Verb == "" {
+		return fmt.Errorf("must have non-blank 'verb' field")
+	}
+	if !syms.verbNames[strings.ToUpper(va.Verb)] {
+		return fmt.Errorf("verb: no verb with name %q is defined", va.Verb)
+	}
+
+	for idx, with := range va.With {
+		if strings.HasPrefix(with, "@") {
+			continue
+		}
+		withUpper := strings.ToUpper(with)
+		if !syms.itemLabels[withUpper] && !syms.detailLabels[withUpper] && !syms.npcLabels[withUpper] && !syms.egressLabels[withUpper] {
+			return fmt.Errorf("with[%d]: no item, detail, NPC, or exit with label %q exists", idx, with)
+		}
+	}
+
+	return nil
+}
+
+// validateVerbActionDefs validates each of actions, which are given with the
+// 'on_verb' key.
+func validateVerbActionDefs(actions []verbAction, syms worldSymbols) error {
+	for idx, va := range actions {
+		if err := validateVerbActionDef(va, syms); err != nil {
+			return fmt.Errorf("on_verb[%d]: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+func validateEventDef(ev event) error {
+	if ev.Label == "" {
+		return fmt.Errorf("must have non-blank 'label' field")
+	}
+	if ev.AtTurn < 0 {
+		return fmt.Errorf("at_turn: must not be negative")
+	}
+	if ev.EveryNTurns < 0 {
+		return fmt.Errorf("every_n_turns: must not be negative")
+	}
+	if ev.Delay < 0 {
+		return fmt.Errorf("delay: must not be negative")
+	}
+
+	if ev.AfterFlag != "" {
+		if ev.AtTurn > 0 || ev.EveryNTurns > 0 {
+			return fmt.Errorf("'after_flag' cannot be given with 'at_turn' or 'every_n_turns'")
+		}
+	} else {
+		if ev.AtTurn == 0 && ev.EveryNTurns == 0 {
+			return fmt.Errorf("must have one of 'at_turn', 'every_n_turns', or 'after_flag'")
+		}
+		if ev.Delay > 0 {
+			return fmt.Errorf("'delay' can only be given with 'after_flag'")
+		}
+	}
+
+	return nil
+}
+
+func validateLockDef(l lock, syms worldSymbols) error {
+	if l.Label != "" && !labelRegexp.MatchString(strings.ToUpper(l.Label)) {
+		return fmt.Errorf("label: must only contain letters, numbers, and underscores")
+	}
+	if !l.Openable && !l.Lockable {
+		return fmt.Errorf("must be at least one of 'openable' or 'lockable'")
+	}
+	if l.Open && !l.Openable {
+		return fmt.Errorf("open: cannot be open when it is not openable")
+	}
+	if l.Locked && !l.Lockable {
+		return fmt.Errorf("locked: cannot be locked when it is not lockable")
+	}
+	if l.Open && l.Locked {
+		return fmt.Errorf("cannot be both open and locked")
+	}
+	if !l.Lockable && (len(l.Keys) > 0 || strings.TrimSpace(l.If) != "") {
+		return fmt.Errorf("'keys' and 'if' can only be given when it is lockable")
+	}
+
+	for idx, key := range l.Keys {
+		if strings.HasPrefix(key, "@") {
+			continue
+		}
+		if _, ok := syms.itemLabels[strings.ToUpper(key)]; !ok {
+			return fmt.Errorf("keys[%d]: no item with label %q exists", idx, key)
+		}
+	}
+
 	return nil
 }
 
@@ -822,6 +1478,12 @@ func validateEgressDef(eg egress, syms worldSymbols) error {
 		return fmt.Errorf("dest: no room has label %q", strings.ToUpper(eg.Dest))
 	}
 
+	if eg.Lock != nil {
+		if err := validateLockDef(*eg.Lock, syms); err != nil {
+			return fmt.Errorf("lock: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -849,8 +1511,32 @@ func validateItemDef(item item, syms worldSymbols) error {
 	if item.Start == "" {
 		return fmt.Errorf("must have non-blank 'start' field")
 	}
-	if _, ok := syms.roomLabels[item.Start]; !ok {
-		return fmt.Errorf("start: no room with label %q exists", item.Start)
+	startUpper := strings.ToUpper(item.Start)
+	if !syms.roomLabels[startUpper] && !syms.itemLabels[startUpper] && !syms.detailLabels[startUpper] && !syms.npcLabels[startUpper] {
+		return fmt.Errorf("start: no room, item, detail, or NPC with label %q exists", item.Start)
+	}
+
+	if item.Lock != nil {
+		if err := validateLockDef(*item.Lock, syms); err != nil {
+			return fmt.Errorf("lock: %w", err)
+		}
+	}
+	if item.Container != nil {
+		if err := validateContainerDef(*item.Container); err != nil {
+			return fmt.Errorf("container: %w", err)
+		}
+	}
+
+	hooks := map[string]itemHook{"on_take": item.OnTake, "on_drop": item.OnDrop, "on_look": item.OnLook}
+	for _, hookName := range util.OrderedKeys(hooks) {
+		hook := hooks[hookName]
+		if hook.Refusal != "" && strings.TrimSpace(hook.If) == "" {
+			return fmt.Errorf("%s: 'refusal' is given but there is no 'if' to refuse with", hookName)
+		}
+	}
+
+	if err := validateVerbActionDefs(item.OnVerb, syms); err != nil {
+		return err
 	}
 
 	// do not check alias naming rules and uniqueness here, that has already
@@ -859,14 +1545,14 @@ func validateItemDef(item item, syms worldSymbols) error {
 	return nil
 }
 
-func checkAlias(alias string, conflictSet stringSet) error {
+func checkAlias(alias string, conflictSet stringSet, vocab *command.Vocabulary) error {
 	if _, ok := conflictSet[alias]; ok {
 		return fmt.Errorf("alias conflicts with another alias")
 	}
 
-	firstReservedWord := command.FindFirstReserved(alias)
+	firstReservedWord := vocab.FindFirstReserved(alias)
 	if firstReservedWord != "" {
-		return fmt.Errorf("alias cannot contain reserved word %q", firstReservedWord)
+		return fmt.Errorf("alias %q cannot contain reserved word %q", alias, firstReservedWord)
 	}
 
 	if !aliasRegexp.MatchString(alias) {
@@ -891,12 +1577,28 @@ func checkAlias(alias string, conflictSet stringSet) error {
 	return nil
 }
 
-func checkLabel(label string, conflictSet stringSet, labeled string) error {
+// checkVerbWord checks that word can be used as the name or an alias of a verb
+// that a world defines. It must follow the rules for aliases, and cannot be
+// the same as any built-in verb or any in conflictSet.
+func checkVerbWord(word string, conflictSet stringSet, vocab *command.Vocabulary) error {
+	if vocab.IsBuiltInVerb(word) {
+		return fmt.Errorf("%q is already a built-in verb or verb alias", word)
+	}
+	if _, ok := conflictSet[word]; ok {
+		return fmt.Errorf("%q has already been used for a verb", word)
+	}
+	if word == "" {
+		return fmt.Errorf("must not be blank")
+	}
+	return checkAlias(word, make(stringSet), vocab)
+}
+
+func checkLabel(label string, conflictSet stringSet, labeled string, vocab *command.Vocabulary) error {
 	if _, ok := conflictSet[label]; ok {
 		return fmt.Errorf("label %q has already been used for %s", label, labeled)
 	}
 
-	firstReservedWord := command.FindFirstReserved(label)
+	firstReservedWord := vocab.FindFirstReserved(label)
 	if firstReservedWord != "" {
 		return fmt.Errorf("label %q cannot contain reserved word %q", label, firstReservedWord)
 	}
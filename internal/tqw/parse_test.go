@@ -0,0 +1,52 @@
+package tqw
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LoadWorldDataFile_reservedWords(t *testing.T) {
+	testCases := []struct {
+		name      string
+		item      string
+		expectErr string
+	}{
+		{
+			name:      "alias with reserved word",
+			item:      "[[item]]\nlabel = \"SALT\"\nname = \"salt and pepper\"\naliases = [\"SALT AND PEPPER\"]\ndescription = \"Seasoning.\"\nstart = \"KITCHEN\"\n",
+			expectErr: `alias "SALT AND PEPPER" cannot contain reserved word "AND"`,
+		},
+		{
+			name:      "label that is a reserved word",
+			item:      "[[item]]\nlabel = \"ALL\"\nname = \"everything\"\naliases = [\"EVERYTHING\"]\ndescription = \"Everything.\"\nstart = \"KITCHEN\"\n",
+			expectErr: `label "ALL" cannot contain reserved word "ALL"`,
+		},
+		{
+			name: "reserved word joined to other words",
+			item: "[[item]]\nlabel = \"SALT_AND_PEPPER\"\nname = \"salt and pepper\"\naliases = [\"SALT PEPPER\"]\ndescription = \"Seasoning.\"\nstart = \"KITCHEN\"\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			filename := filepath.Join(t.TempDir(), "world.tqw")
+			if err := os.WriteFile(filename, []byte(testPackWorld+"\n"+tc.item), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadWorldDataFile(filename)
+			if tc.expectErr != "" {
+				if assert.Error(err) {
+					assert.Contains(err.Error(), tc.expectErr)
+				}
+				return
+			}
+			assert.NoError(err)
+		})
+	}
+}
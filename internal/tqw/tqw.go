@@ -4,12 +4,19 @@
 package tqw
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
 	"unicode"
 
 	"github.com/BurntSushi/toml"
+	"github.com/dekarrin/tunaq/internal/command"
 	"github.com/dekarrin/tunaq/internal/game"
+	"github.com/dekarrin/tunaq/internal/util"
 )
 
 const MaxManifestRecursionDepth = 32
@@ -46,6 +53,31 @@ type WorldData struct {
 
 	// Flags is the flags that the game starts with.
 	Flags map[string]string
+
+	// Seed is the seed that the world asks for the game's random source to be
+	// seeded with. It will be nil if the world does not give one.
+	Seed *int64
+
+	// Events is every event that the world schedules, in the order they were
+	// defined.
+	Events []*game.Event
+
+	// Verbs is every verb that the world defines in addition to the built-in
+	// ones, in the order they were defined.
+	Verbs []*game.CustomVerb
+
+	// Vocabulary is the words that commands in the world are parsed with. It
+	// has the default words along with any that the world adds or changes,
+	// and the verbs in Verbs.
+	Vocabulary *command.Vocabulary
+
+	// Fingerprint identifies the structure of the world. Two worlds with the
+	// same Fingerprint have the same rooms, exits, items, NPCs, dialog trees,
+	// flags, and events, and so progress saved in one can be restored in the
+	// other.
+	// Changes that do not affect saved progress, such as edits to descriptions
+	// or dialog text, do not change the Fingerprint.
+	Fingerprint string
 }
 
 // FileInfo contains the essential information all TQW format files must
@@ -63,13 +95,38 @@ type FileInfo struct {
 // combined into one single set of data before being checked, and if a manifest
 // is encountered, all files in it are recursively included.
 //
-// In the future, once 'resource packs' are available (honestly just tarball or
-// zip files containing at least one manifest file at the root), setting path to
-// it will result in reading the entire archive starting with the root manifest.
+// The path can also be to a 'resource pack', which is a zip or gzipped tar
+// archive that contains a manifest file at its root, either named
+// RootManifestName or as the only manifest at the root. The entire world is
+// then read from the archive starting with that manifest, and the files listed
+// in manifests within it are relative to them within the archive. Files in the
+// archive and in its manifests cannot refer to anything outside of it. The
+// kind of archive is detected from its contents, so it can have any extension.
 func LoadResourceBundle(path string) (WorldData, error) {
-	unmarshaled, err := recursiveUnmarshalResource(path, nil)
+	format, err := detectPackFormat(path)
 	if err != nil {
-		return WorldData{}, err
+		return WorldData{}, fmt.Errorf("%q: reading: %w", path, err)
+	}
+
+	var unmarshaled topLevelWorldData
+	if format == packNone {
+		unmarshaled, err = recursiveUnmarshalResource(diskReader{}, filepath.Clean(path), nil)
+		if err != nil {
+			return WorldData{}, err
+		}
+	} else {
+		pack, err := openPack(path, format)
+		if err != nil {
+			return WorldData{}, fmt.Errorf("resource pack %q: %w", path, err)
+		}
+		root, err := pack.rootManifest()
+		if err != nil {
+			return WorldData{}, fmt.Errorf("resource pack %q: %w", path, err)
+		}
+		unmarshaled, err = recursiveUnmarshalResource(pack, root, nil)
+		if err != nil {
+			return WorldData{}, fmt.Errorf("resource pack %q: %w", path, err)
+		}
 	}
 
 	world, err := parseWorldData(unmarshaled)
@@ -80,6 +137,53 @@ func LoadResourceBundle(path string) (WorldData, error) {
 	return world, nil
 }
 
+// fingerprint computes the Fingerprint of the given world data. It must be
+// called before the world is used in a game, as it relies on the world's rooms
+// still containing exactly what they were defined with.
+func fingerprint(world WorldData) string {
+	h := sha256.New()
+
+	for _, roomLabel := range util.OrderedKeys(world.Rooms) {
+		r := world.Rooms[roomLabel]
+		fmt.Fprintf(h, "ROOM %s\n", r.Label)
+
+		for _, eg := range r.Exits {
+			fmt.Fprintf(h, "EXIT %s %s\n", eg.Label, eg.DestLabel)
+		}
+		for _, det := range r.Details {
+			fmt.Fprintf(h, "DETAIL %s\n", det.Label)
+		}
+
+		allItems := r.AllItems()
+		itemLabels := make([]string, len(allItems))
+		for i := range allItems {
+			itemLabels[i] = allItems[i].Label
+		}
+		sort.Strings(itemLabels)
+		for _, itemLabel := range itemLabels {
+			fmt.Fprintf(h, "ITEM %s\n", itemLabel)
+		}
+
+		for _, npcLabel := range util.OrderedKeys(r.NPCs) {
+			npc := r.NPCs[npcLabel]
+			fmt.Fprintf(h, "NPC %s %s %d\n", npc.Label, npc.Movement.Action, len(npc.Movement.Path))
+			for _, step := range npc.Dialog {
+				fmt.Fprintf(h, "STEP %s %s\n", step.Label, step.Action)
+			}
+		}
+	}
+
+	for _, fl := range util.OrderedKeys(world.Flags) {
+		fmt.Fprintf(h, "FLAG %s\n", fl)
+	}
+
+	for _, ev := range world.Events {
+		fmt.Fprintf(h, "EVENT %s\n", ev.Label)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // LoadManifestFile loads manifest data from a TQW file.
 func LoadManifestFile(path string) (manif Manifest, err error) {
 	manifestData, loadErr := os.ReadFile(path)
@@ -565,3 +565,79 @@ func HasPrefix[T comparable](sl []T, prefix []T) bool {
 
 	return true
 }
+
+// JoinOr joins items into a list of alternatives, such as "a, b, or c". If
+// there is only one item, it is returned as-is.
+func JoinOr(items []string) string {
+	switch len(items) {
+	case 0:
+		return ""
+	case 1:
+		return items[0]
+	case 2:
+		return items[0] + " or " + items[1]
+	default:
+		return strings.Join(items[:len(items)-1], ", ") + ", or " + items[len(items)-1]
+	}
+}
+
+// EditDistance returns the number of single-character insertions, deletions,
+// substitutions, and swaps of two adjacent characters that it takes to turn s1
+// into s2.
+func EditDistance(s1, s2 string) int {
+	r1 := []rune(s1)
+	r2 := []rune(s2)
+
+	// dist[i][j] is the distance between the first i runes of r1 and the
+	// first j runes of r2
+	dist := make([][]int, len(r1)+1)
+	for i := range dist {
+		dist[i] = make([]int, len(r2)+1)
+		dist[i][0] = i
+	}
+	for j := range dist[0] {
+		dist[0][j] = j
+	}
+
+	for i := 1; i <= len(r1); i++ {
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+
+			d := dist[i-1][j] + 1
+			if dist[i][j-1]+1 < d {
+				d = dist[i][j-1] + 1
+			}
+			if dist[i-1][j-1]+cost < d {
+				d = dist[i-1][j-1] + cost
+			}
+			if i > 1 && j > 1 && r1[i-1] == r2[j-2] && r1[i-2] == r2[j-1] && dist[i-2][j-2]+1 < d {
+				d = dist[i-2][j-2] + 1
+			}
+			dist[i][j] = d
+		}
+	}
+
+	return dist[len(r1)][len(r2)]
+}
+
+// IsCloseMatch returns whether typed is close enough to word that it was
+// probably meant to be word, either because it is the start of word or because
+// it is only a typo or two away from it. Anything shorter than three characters
+// is never a close match, as too many words would be close to it.
+func IsCloseMatch(typed, word string) bool {
+	if len([]rune(typed)) < 3 || typed == word {
+		return false
+	}
+	if strings.HasPrefix(word, typed) {
+		return true
+	}
+
+	maxTypos := 1
+	if len([]rune(typed)) > 4 {
+		maxTypos = 2
+	}
+	return EditDistance(typed, word) <= maxTypos
+}
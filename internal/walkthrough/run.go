@@ -0,0 +1,196 @@
+package walkthrough
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dekarrin/tunaq/internal/command"
+	"github.com/dekarrin/tunaq/internal/game"
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+	"github.com/dekarrin/tunaq/internal/tqw"
+	"github.com/dekarrin/tunaq/internal/util"
+)
+
+// outputWidth is the width that output is formatted to during a walkthrough.
+// It is the same as that of the interactive engine so that output matches
+// what a player would see.
+const outputWidth = 80
+
+// StepResult is the result of running a single Step of a Walkthrough.
+type StepResult struct {
+	// Command is the command that was run.
+	Command string
+
+	// Output is everything the game output while running the command. If the
+	// command was rejected, this includes the message explaining why.
+	Output string
+
+	// Failures is a description of each expectation of the step that was not
+	// met. It will be empty if the step passed.
+	Failures []string
+}
+
+// Passed returns whether all expectations of the step were met.
+func (sr StepResult) Passed() bool {
+	return len(sr.Failures) == 0
+}
+
+// Run plays through the given walkthrough in a new game in the given world. It
+// returns the result of every step. A non-nil error is returned only if the
+// game could not be created; errors that occur while running a command are
+// treated as output of that command, just as they would be shown to a player.
+func Run(wt Walkthrough, world tqw.WorldData) ([]StepResult, error) {
+	dev := &scriptedDevice{width: outputWidth}
+
+	state, err := game.New(world.Rooms, world.Start, world.Flags, dev)
+	if err != nil {
+		return nil, fmt.Errorf("initializing game engine: %w", err)
+	}
+	state.SetEvents(world.Events)
+	if err := state.SetVerbs(world.Verbs); err != nil {
+		return nil, fmt.Errorf("initializing game engine: %w", err)
+	}
+
+	if wt.Seed != nil {
+		state.SetSeed(*wt.Seed)
+	} else if world.Seed != nil {
+		state.SetSeed(*world.Seed)
+	}
+
+	vocab := world.Vocabulary
+	if vocab == nil {
+		vocab = command.NewVocabulary()
+	}
+
+	var last command.Command
+	results := make([]StepResult, len(wt.Steps))
+	for i, st := range wt.Steps {
+		results[i] = runStep(state, vocab, &last, dev, st)
+	}
+
+	return results, nil
+}
+
+func runStep(state *game.State, vocab *command.Vocabulary, last *command.Command, dev *scriptedDevice, st Step) StepResult {
+	res := StepResult{Command: st.Command}
+
+	dev.output.Reset()
+	dev.input = append([]string{}, st.Input...)
+
+	cmds, err := vocab.ParseAll(st.Command)
+	if err == nil {
+		cmds, err = command.ResolveAgain(cmds, *last)
+	}
+	if err == nil {
+		for _, cmd := range cmds {
+			*last = cmd
+			if err = state.Advance(cmd); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		// errors are shown to the player the same as any other output, so they
+		// are checked the same way.
+		dev.output.WriteString("\n" + tqerrors.GameMessage(err) + "\n\n")
+	}
+
+	res.Output = dev.output.String()
+
+	if len(dev.input) > 0 {
+		res.Failures = append(res.Failures, fmt.Sprintf("%d input(s) were never asked for", len(dev.input)))
+	}
+	if dev.inputExhausted {
+		res.Failures = append(res.Failures, "game asked for more input than was given")
+		dev.inputExhausted = false
+	}
+
+	for _, s := range st.Output {
+		if !strings.Contains(res.Output, s) {
+			res.Failures = append(res.Failures, fmt.Sprintf("output does not contain %q", s))
+		}
+	}
+	for _, re := range st.OutputRegex {
+		if !re.MatchString(res.Output) {
+			res.Failures = append(res.Failures, fmt.Sprintf("output does not match /%s/", re.String()))
+		}
+	}
+
+	if st.Room != "" && state.CurrentRoom.Label != st.Room {
+		res.Failures = append(res.Failures, fmt.Sprintf("player is in room %q, not %q", state.CurrentRoom.Label, st.Room))
+	}
+
+	for _, itemLabel := range st.Inventory {
+		if _, ok := state.Inventory[itemLabel]; !ok {
+			res.Failures = append(res.Failures, fmt.Sprintf("inventory does not contain %q", itemLabel))
+		}
+	}
+
+	for _, label := range util.OrderedKeys(st.Flags) {
+		expected := st.Flags[label]
+		actual, ok := state.FlagValue(label)
+		if !ok {
+			res.Failures = append(res.Failures, fmt.Sprintf("flag $%s is not defined", label))
+		} else if !actual.EqualTo(expected).Bool() {
+			res.Failures = append(res.Failures, fmt.Sprintf("flag $%s is %s, not %s", label, actual.Quoted(), expected.Quoted()))
+		}
+	}
+
+	return res
+}
+
+// scriptedDevice is a game.IODevice that records all output and answers all
+// prompts for input from a list of answers.
+type scriptedDevice struct {
+	width  int
+	output strings.Builder
+
+	// input is the answers that have not yet been given.
+	input []string
+
+	// inputExhausted is set when input is asked for after all answers have
+	// been given.
+	inputExhausted bool
+}
+
+func (sd *scriptedDevice) Width() int {
+	return sd.width
+}
+
+func (sd *scriptedDevice) SetWidth(w int) {
+	sd.width = w
+}
+
+func (sd *scriptedDevice) Output(s string, a ...interface{}) error {
+	sd.output.WriteString(fmt.Sprintf(s, a...))
+	return nil
+}
+
+func (sd *scriptedDevice) Input(prompt string) (string, error) {
+	sd.output.WriteString(prompt)
+
+	if len(sd.input) < 1 {
+		sd.inputExhausted = true
+		return "", tqerrors.Interpreterf("(the walkthrough gave no more input)")
+	}
+
+	answer := sd.input[0]
+	sd.input = sd.input[1:]
+	sd.output.WriteString(answer + "\n")
+	return answer, nil
+}
+
+func (sd *scriptedDevice) InputInt(prompt string) (int, error) {
+	for {
+		answer, err := sd.Input(prompt)
+		if err != nil {
+			return 0, err
+		}
+		intVal, err := strconv.Atoi(answer)
+		if err == nil {
+			return intVal, nil
+		}
+		sd.output.WriteString("Please enter a number\n")
+	}
+}
@@ -0,0 +1,120 @@
+package walkthrough
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/dekarrin/tunaq/internal/game"
+	"github.com/dekarrin/tunaq/internal/tqw"
+	"github.com/dekarrin/tunaq/tunascript"
+	"github.com/stretchr/testify/assert"
+)
+
+func testWorld() tqw.WorldData {
+	return tqw.WorldData{
+		Start: "KITCHEN",
+		Flags: map[string]string{"SCORE": "0"},
+		Rooms: map[string]*game.Room{
+			"KITCHEN": {
+				Label:       "KITCHEN",
+				Name:        "the kitchen",
+				Description: "A kitchen.",
+				Exits: []*game.Egress{
+					{Label: "KITCHEN_TO_HALL", DestLabel: "HALL", Aliases: []string{"HALL"}, If: tunascript.ReturnTrue, Description: "a door", TravelMessage: "You walk to the hall."},
+				},
+				Items: []*game.Item{
+					{Label: "SPOON", Name: "spoon", Aliases: []string{"SPOON"}, Description: "A spoon.", If: tunascript.ReturnTrue},
+				},
+				NPCs: map[string]*game.NPC{
+					"CHEF": {
+						Label:       "CHEF",
+						Name:        "the chef",
+						Aliases:     []string{"CHEF"},
+						Description: "A chef.",
+						If:          tunascript.ReturnTrue,
+						Movement:    game.Route{Action: game.RouteStatic},
+						Dialog: []*game.DialogStep{
+							{Action: game.DialogChoice, Content: "Hungry?", Choices: [][2]string{{"Yes", "YES"}, {"No", "NO"}}},
+							{Label: "YES", Action: game.DialogLine, Content: "Have some soup."},
+							{Action: game.DialogEnd},
+							{Label: "NO", Action: game.DialogLine, Content: "Suit yourself."},
+							{Action: game.DialogEnd},
+						},
+					},
+				},
+			},
+			"HALL": {
+				Label:       "HALL",
+				Name:        "the hall",
+				Description: "A hall.",
+				Exits: []*game.Egress{
+					{Label: "HALL_TO_KITCHEN", DestLabel: "KITCHEN", Aliases: []string{"KITCHEN"}, If: tunascript.ReturnTrue, Description: "a door", TravelMessage: "You walk to the kitchen."},
+				},
+				NPCs: map[string]*game.NPC{},
+			},
+		},
+	}
+}
+
+func Test_Run(t *testing.T) {
+	testCases := []struct {
+		name         string
+		steps        []Step
+		expectPassed []bool
+	}{
+		{
+			name: "room and inventory",
+			steps: []Step{
+				{Command: "take spoon", Inventory: []string{"SPOON"}, Room: "KITCHEN"},
+				{Command: "go hall", Room: "HALL", Output: []string{"You walk to the hall."}},
+				{Command: "go hall", Room: "KITCHEN"},
+			},
+			expectPassed: []bool{true, true, false},
+		},
+		{
+			name: "dialog choices are answered from input",
+			steps: []Step{
+				{Command: "talk to chef", Input: []string{"2", ""}, Output: []string{"Suit yourself."}},
+				{Command: "talk to chef", Input: []string{"1"}, OutputRegex: []*regexp.Regexp{regexp.MustCompile(`(?i)some\s+soup`)}},
+			},
+			expectPassed: []bool{true, false},
+		},
+		{
+			name: "flags",
+			steps: []Step{
+				{Command: "look", Flags: map[string]tunascript.Value{"SCORE": tunascript.ParseValue("0")}},
+				{Command: "look", Flags: map[string]tunascript.Value{"SCORE": tunascript.ParseValue("1")}},
+				{Command: "look", Flags: map[string]tunascript.Value{"MISSING": tunascript.ParseValue("1")}},
+			},
+			expectPassed: []bool{true, false, false},
+		},
+		{
+			name: "several commands on a line",
+			steps: []Step{
+				{Command: "take spoon. go hall", Inventory: []string{"SPOON"}, Room: "HALL"},
+				{Command: "go kitchen then go hall; go hall; go kitchen", Room: "HALL"},
+				{Command: "go kitchen. again", Room: "KITCHEN"},
+				{Command: "again", Room: "HALL"},
+			},
+			expectPassed: []bool{true, true, true, false},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			results, err := Run(Walkthrough{Steps: tc.steps}, testWorld())
+			if !assert.NoError(err) {
+				return
+			}
+
+			actualPassed := make([]bool, len(results))
+			for i := range results {
+				actualPassed[i] = results[i].Passed()
+			}
+
+			assert.Equal(tc.expectPassed, actualPassed)
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Package walkthrough loads scripted walkthroughs of a TunaQuest world and runs
+// them headlessly, checking the state of the game after each command. It is
+// used by world authors to test that their worlds can be played the way they
+// intend.
+//
+// A walkthrough is a TOML file with the same "format" header as TQW files and
+// a "type" of "WALKTHROUGH". It may name the world it is for with the "world"
+// key, which is relative to the walkthrough file, and may give a seed for the
+// random source of the game with the "seed" key so that random events such as
+// NPC wandering happen the same way every time. It then contains one "step"
+// table per command:
+//
+//	format = "TUNA"
+//	type = "WALKTHROUGH"
+//	world = "manifest.tqw"
+//	seed = 413
+//
+//	[[step]]
+//	command = "TALK TO JOEY"
+//	input = ["1", "2"]
+//	output = ["gumshoe"]
+//	output_regex = ["(?i)joey \\w+ claire"]
+//	room = "HALLWAY"
+//	inventory = ["FORK"]
+//	flags = { TALKED_TO_JOEY = true, SCORE = 2 }
+//
+// "input" is the answers given, in order, to any prompts the game shows while
+// running the command, such as dialog choices. All of the other keys are
+// optional assertions about what the command results in; "inventory" lists
+// labels of items that must be in the inventory.
+package walkthrough
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// Walkthrough is a scripted playthrough of a world.
+type Walkthrough struct {
+	// World is the path to the TQW resource file of the world the walkthrough
+	// is for. It will be empty if the walkthrough does not name one.
+	World string
+
+	// Seed is the seed to give the random source of the game. If nil, the
+	// seed given by the world is used, or one based on the current time if
+	// the world does not give one.
+	Seed *int64
+
+	// Steps is each command to run, in order.
+	Steps []Step
+}
+
+// Step is a single command in a walkthrough, along with what is expected to
+// happen as a result of running it.
+type Step struct {
+	// Command is the text of the command, as the player would type it. Like
+	// anything the player types, it may have several commands on it, which
+	// are run in order until one fails.
+	Command string
+
+	// Input is the answers to give to each prompt for input that running the
+	// command results in, in order.
+	Input []string
+
+	// Output is text that must be in the output of the command.
+	Output []string
+
+	// OutputRegex is patterns that must match the output of the command.
+	OutputRegex []*regexp.Regexp
+
+	// Room is the label of the room the player must be in after the command.
+	// If empty, the room is not checked.
+	Room string
+
+	// Inventory is the labels of items that must be in the player's inventory
+	// after the command.
+	Inventory []string
+
+	// Flags is the values that tunascript flags must be equal to after the
+	// command.
+	Flags map[string]tunascript.Value
+}
+
+type topLevelWalkthrough struct {
+	Format string `toml:"format"`
+	Type   string `toml:"type"`
+	World  string `toml:"world"`
+	Seed   *int64 `toml:"seed"`
+	Steps  []step `toml:"step"`
+}
+
+type step struct {
+	Command     string                    `toml:"command"`
+	Input       []string                  `toml:"input"`
+	Output      []string                  `toml:"output"`
+	OutputRegex []string                  `toml:"output_regex"`
+	Room        string                    `toml:"room"`
+	Inventory   []string                  `toml:"inventory"`
+	Flags       map[string]toml.Primitive `toml:"flags"`
+}
+
+// Load loads a walkthrough from the file at the given path. If the walkthrough
+// names a world, the World of the returned Walkthrough will be the path to it
+// relative to the current working directory.
+func Load(path string) (Walkthrough, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Walkthrough{}, err
+	}
+
+	var top topLevelWalkthrough
+	md, err := toml.Decode(string(data), &top)
+	if err != nil {
+		return Walkthrough{}, err
+	}
+
+	if strings.ToUpper(top.Format) != "TUNA" {
+		return Walkthrough{}, fmt.Errorf("in header: 'format' key must exist and be set to 'TUNA'")
+	}
+	if strings.ToUpper(top.Type) != "WALKTHROUGH" {
+		return Walkthrough{}, fmt.Errorf("in header: 'type' key must exist and be set to 'WALKTHROUGH'")
+	}
+
+	wt := Walkthrough{
+		Seed:  top.Seed,
+		Steps: make([]Step, len(top.Steps)),
+	}
+
+	if top.World != "" {
+		wt.World = top.World
+		if !filepath.IsAbs(wt.World) {
+			wt.World = filepath.Join(filepath.Dir(path), wt.World)
+		}
+	}
+
+	for i, st := range top.Steps {
+		parsed, err := st.parse(md)
+		if err != nil {
+			return Walkthrough{}, fmt.Errorf("step %d: %w", i+1, err)
+		}
+		wt.Steps[i] = parsed
+	}
+
+	return wt, nil
+}
+
+func (st step) parse(md toml.MetaData) (Step, error) {
+	if strings.TrimSpace(st.Command) == "" {
+		return Step{}, fmt.Errorf("'command' must exist and be non-empty")
+	}
+
+	parsed := Step{
+		Command:     st.Command,
+		Input:       st.Input,
+		Output:      st.Output,
+		OutputRegex: make([]*regexp.Regexp, len(st.OutputRegex)),
+		Room:        strings.ToUpper(st.Room),
+		Inventory:   make([]string, len(st.Inventory)),
+		Flags:       make(map[string]tunascript.Value, len(st.Flags)),
+	}
+
+	for i := range st.OutputRegex {
+		re, err := regexp.Compile(st.OutputRegex[i])
+		if err != nil {
+			return Step{}, fmt.Errorf("output_regex: %w", err)
+		}
+		parsed.OutputRegex[i] = re
+	}
+
+	for i := range st.Inventory {
+		parsed.Inventory[i] = strings.ToUpper(st.Inventory[i])
+	}
+
+	for label, prim := range st.Flags {
+		var boolVal bool
+		var intVal int
+		var strVal string
+
+		var val string
+		if boolErr := md.PrimitiveDecode(prim, &boolVal); boolErr == nil {
+			val = fmt.Sprintf("%t", boolVal)
+		} else if intErr := md.PrimitiveDecode(prim, &intVal); intErr == nil {
+			val = fmt.Sprintf("%d", intVal)
+		} else if strErr := md.PrimitiveDecode(prim, &strVal); strErr == nil {
+			val = strVal
+		} else {
+			return Step{}, fmt.Errorf("flags: %q: must be a double-quoted string, true, false, or a number", label)
+		}
+
+		parsed.Flags[strings.ToUpper(label)] = tunascript.ParseValue(val)
+	}
+
+	return parsed, nil
+}
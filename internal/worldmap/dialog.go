@@ -0,0 +1,170 @@
+package worldmap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/tunaq/internal/game"
+	"github.com/dekarrin/tunaq/internal/tqw"
+	"github.com/dekarrin/tunaq/internal/util"
+	"github.com/dekarrin/tunaq/tunascript"
+)
+
+// DrawDialog returns a flowchart of the dialog tree of the NPC with the given
+// label in the given world, in the given format. If npcLabel is empty, the
+// dialog trees of every NPC in the world are drawn, each in its own group.
+//
+// Every step of the dialog is a node. LINE steps are connected to the step
+// after them by an edge labeled with the player's response, CHOICE steps are
+// connected to the step each choice goes to by an edge labeled with the text
+// of the choice, and PAUSE steps are connected to the step the conversation
+// resumes at by a dashed edge. Text in the dialog is expanded using the values
+// that flags have at the start of the game.
+func DrawDialog(world tqw.WorldData, npcLabel string, f Format) (string, error) {
+	npcs := map[string]*game.NPC{}
+	for _, r := range world.Rooms {
+		for label, npc := range r.NPCs {
+			npcs[label] = npc
+		}
+	}
+
+	labels := util.OrderedKeys(npcs)
+	if npcLabel != "" {
+		npcLabel = strings.ToUpper(npcLabel)
+		if _, ok := npcs[npcLabel]; !ok {
+			return "", fmt.Errorf("no NPC with label %q exists", npcLabel)
+		}
+		labels = []string{npcLabel}
+	}
+
+	interp := tunascript.Interpreter{Target: noWorld{}}
+	for label, val := range world.Flags {
+		if err := interp.AddFlag(label, val); err != nil {
+			return "", fmt.Errorf("flag %q: %w", label, err)
+		}
+	}
+
+	var g graph
+	for _, label := range labels {
+		addDialogTree(&g, &interp, npcs[label])
+	}
+
+	return g.render(f), nil
+}
+
+// addDialogTree adds the nodes and edges of the dialog tree of npc to g.
+func addDialogTree(g *graph, interp *tunascript.Interpreter, npc *game.NPC) {
+	stepID := func(i int) string {
+		return fmt.Sprintf("%s__%d", npc.Label, i)
+	}
+	endID := npc.Label + "__END"
+
+	// labels in the dialog tree go to the index of the step they label
+	labelIdx := map[string]int{}
+	for i, step := range npc.Dialog {
+		if step.Label != "" {
+			labelIdx[strings.ToUpper(step.Label)] = i
+		}
+	}
+	target := func(label string) string {
+		if idx, ok := labelIdx[strings.ToUpper(label)]; ok {
+			return stepID(idx)
+		}
+		// the game ends the conversation when jumping to a label that does
+		// not exist
+		return endID
+	}
+	next := func(i int) string {
+		if i+1 < len(npc.Dialog) {
+			return stepID(i + 1)
+		}
+		return endID
+	}
+
+	expand := func(text string) string {
+		expanded, err := interp.Expand(text)
+		if err != nil {
+			return strings.TrimSpace(text)
+		}
+		return strings.TrimSpace(expanded)
+	}
+
+	g.nodes = append(g.nodes, node{id: npc.Label, label: npc.Label, kind: nodeNPC, group: npc.Label})
+	if len(npc.Dialog) > 0 {
+		g.edges = append(g.edges, edge{from: npc.Label, to: stepID(0), kind: edgeStart})
+	} else {
+		g.edges = append(g.edges, edge{from: npc.Label, to: endID, kind: edgeStart})
+	}
+
+	var endsReached bool
+	for i, step := range npc.Dialog {
+		id := stepID(i)
+
+		header := fmt.Sprintf("%d", i)
+		if step.Label != "" {
+			header += " " + step.Label
+		}
+		scripts := scriptLines(step.IfRaw, step.DoRaw)
+
+		switch step.Action {
+		case game.DialogLine:
+			g.nodes = append(g.nodes, node{id: id, label: header + "\n" + expand(step.Content) + scripts, kind: nodeLine, group: npc.Label})
+			g.edges = append(g.edges, edge{from: id, to: next(i), label: expand(step.Response), kind: edgeNext})
+			endsReached = endsReached || next(i) == endID
+		case game.DialogChoice:
+			g.nodes = append(g.nodes, node{id: id, label: header + "\n" + expand(step.Content) + scripts, kind: nodeChoice, group: npc.Label})
+			for j, ch := range step.Choices {
+				dest := target(ch[1])
+				label := fmt.Sprintf("%d) %s", j+1, expand(ch[0]))
+				if j < len(step.ChoiceScripts) {
+					label += scriptLines(step.ChoiceScripts[j].IfRaw, step.ChoiceScripts[j].DoRaw)
+				}
+				g.edges = append(g.edges, edge{from: id, to: dest, label: label, kind: edgeChoice})
+				endsReached = endsReached || dest == endID
+			}
+		case game.DialogPause:
+			g.nodes = append(g.nodes, node{id: id, label: header + "\nPAUSE", kind: nodePause, group: npc.Label})
+			dest := next(i)
+			if step.ResumeAt != "" {
+				dest = target(step.ResumeAt)
+			}
+			g.edges = append(g.edges, edge{from: id, to: dest, label: "resumes", kind: edgeResume})
+			endsReached = endsReached || dest == endID
+		case game.DialogEnd:
+			g.nodes = append(g.nodes, node{id: id, label: header + "\nEND", kind: nodeEnd, group: npc.Label})
+		}
+	}
+
+	// only give the implicit end after the last step if something goes to it
+	if endsReached || len(npc.Dialog) == 0 {
+		g.nodes = append(g.nodes, node{id: endID, label: "END", kind: nodeEnd, group: npc.Label})
+	}
+}
+
+// scriptLines gives the 'if' and 'do' tunascript of a dialog step or choice as
+// lines to add to the end of its label. If it has neither, an empty string is
+// returned.
+func scriptLines(ifRaw string, doRaw []string) string {
+	var lines string
+	if strings.TrimSpace(ifRaw) != "" {
+		lines += "\nif " + strings.TrimSpace(ifRaw)
+	}
+	if len(doRaw) > 0 {
+		lines += "\ndo " + strings.Join(doRaw, "; ")
+	}
+	return lines
+}
+
+// noWorld is a tunascript.WorldInterface with nothing in it. It is used to
+// expand text without a game running.
+type noWorld struct{}
+
+func (noWorld) InInventory(label string) bool { return false }
+func (noWorld) Visited(label string) bool     { return false }
+func (noWorld) IsLocked(label string) bool    { return false }
+func (noWorld) IsOpen(label string) bool      { return false }
+func (noWorld) NPCHas(npc, item string) bool  { return false }
+func (noWorld) Turn() int                     { return 0 }
+func (noWorld) Move(label, dest string) bool  { return false }
+func (noWorld) Send(npc, dest string) bool    { return false }
+func (noWorld) Output(s string) bool          { return true }
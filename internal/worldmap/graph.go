@@ -0,0 +1,365 @@
+package worldmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/dekarrin/rosed"
+)
+
+// Format is the language that a graph is written in.
+type Format int
+
+const (
+	// DOT is the graph description language used by Graphviz.
+	DOT Format = iota
+
+	// Mermaid is the flowchart syntax used by Mermaid.
+	Mermaid
+
+	// JSON is a JSON object with a "nodes" array and an "edges" array, for
+	// use by other tools.
+	JSON
+)
+
+func (f Format) String() string {
+	switch f {
+	case DOT:
+		return "DOT"
+	case Mermaid:
+		return "MERMAID"
+	case JSON:
+		return "JSON"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+// FormatsByString is a map indexing string values to their corresponding
+// Format.
+var FormatsByString map[string]Format = map[string]Format{
+	DOT.String():     DOT,
+	Mermaid.String(): Mermaid,
+	JSON.String():    JSON,
+}
+
+// labelWidth is the width that labels are wrapped to in DOT and Mermaid
+// output.
+const labelWidth = 40
+
+type nodeKind int
+
+const (
+	nodeRoom nodeKind = iota
+	nodeNPC
+	nodeLine
+	nodeChoice
+	nodePause
+	nodeEnd
+)
+
+func (nk nodeKind) String() string {
+	switch nk {
+	case nodeRoom:
+		return "room"
+	case nodeNPC:
+		return "npc"
+	case nodeLine:
+		return "line"
+	case nodeChoice:
+		return "choice"
+	case nodePause:
+		return "pause"
+	case nodeEnd:
+		return "end"
+	default:
+		return fmt.Sprintf("nodeKind(%d)", int(nk))
+	}
+}
+
+type edgeKind int
+
+const (
+	edgeExit edgeKind = iota
+	edgeStart
+	edgePatrol
+	edgeAllowed
+	edgeForbidden
+	edgeNext
+	edgeChoice
+	edgeResume
+)
+
+func (ek edgeKind) String() string {
+	switch ek {
+	case edgeExit:
+		return "exit"
+	case edgeStart:
+		return "start"
+	case edgePatrol:
+		return "patrol"
+	case edgeAllowed:
+		return "allowed"
+	case edgeForbidden:
+		return "forbidden"
+	case edgeNext:
+		return "next"
+	case edgeChoice:
+		return "choice"
+	case edgeResume:
+		return "resume"
+	default:
+		return fmt.Sprintf("edgeKind(%d)", int(ek))
+	}
+}
+
+// node is a single room, NPC, or dialog step in a graph.
+type node struct {
+	id    string
+	label string
+	kind  nodeKind
+
+	// start is whether the node is where things begin, such as the starting
+	// room of the world.
+	start bool
+
+	// group is the ID of the subgraph that the node is drawn in. If empty, it
+	// is not drawn in one.
+	group string
+}
+
+// edge is a single connection in a graph.
+type edge struct {
+	from, to string
+	label    string
+	kind     edgeKind
+}
+
+// graph is a set of nodes and the edges between them.
+type graph struct {
+	nodes []node
+	edges []edge
+}
+
+func (g graph) render(f Format) string {
+	switch f {
+	case DOT:
+		return g.renderDOT()
+	case Mermaid:
+		return g.renderMermaid()
+	case JSON:
+		return g.renderJSON()
+	default:
+		panic(fmt.Sprintf("unknown graph format: %v", f))
+	}
+}
+
+// groups gives the IDs of every group in g in the order they first appear.
+func (g graph) groups() []string {
+	var groups []string
+	seen := map[string]bool{}
+	for _, n := range g.nodes {
+		if n.group != "" && !seen[n.group] {
+			groups = append(groups, n.group)
+			seen[n.group] = true
+		}
+	}
+	return groups
+}
+
+func (g graph) renderDOT() string {
+	var sb strings.Builder
+
+	sb.WriteString("digraph world {\n")
+	sb.WriteString("\tnode [shape=box];\n")
+
+	writeNode := func(indent string, n node) {
+		attrs := []string{"label=" + dotQuote(wrapLabel(n.label))}
+		if n.start {
+			attrs = append(attrs, "peripheries=2")
+		}
+		switch n.kind {
+		case nodeNPC:
+			attrs = append(attrs, "shape=ellipse", "style=filled", "fillcolor=lightyellow")
+		case nodeChoice:
+			attrs = append(attrs, "shape=diamond")
+		case nodePause:
+			attrs = append(attrs, "shape=octagon")
+		case nodeEnd:
+			attrs = append(attrs, "shape=doublecircle")
+		}
+		sb.WriteString(fmt.Sprintf("%s%s [%s];\n", indent, dotQuote(n.id), strings.Join(attrs, ", ")))
+	}
+
+	for _, n := range g.nodes {
+		if n.group == "" {
+			writeNode("\t", n)
+		}
+	}
+	for _, grp := range g.groups() {
+		sb.WriteString(fmt.Sprintf("\tsubgraph %s {\n", dotQuote("cluster_"+grp)))
+		sb.WriteString(fmt.Sprintf("\t\tlabel=%s;\n", dotQuote(grp)))
+		for _, n := range g.nodes {
+			if n.group == grp {
+				writeNode("\t\t", n)
+			}
+		}
+		sb.WriteString("\t}\n")
+	}
+
+	for _, e := range g.edges {
+		attrs := []string{"label=" + dotQuote(wrapLabel(e.label))}
+		switch e.kind {
+		case edgeStart:
+			attrs = append(attrs, "style=dotted")
+		case edgePatrol:
+			attrs = append(attrs, "style=dashed", "color=blue", "fontcolor=blue")
+		case edgeAllowed:
+			attrs = append(attrs, "style=dashed", "color=darkgreen", "fontcolor=darkgreen")
+		case edgeForbidden:
+			attrs = append(attrs, "style=dashed", "color=red", "fontcolor=red", "arrowhead=tee")
+		case edgeResume:
+			attrs = append(attrs, "style=dashed")
+		}
+		sb.WriteString(fmt.Sprintf("\t%s -> %s [%s];\n", dotQuote(e.from), dotQuote(e.to), strings.Join(attrs, ", ")))
+	}
+
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func (g graph) renderMermaid() string {
+	var sb strings.Builder
+
+	sb.WriteString("flowchart LR\n")
+
+	writeNode := func(indent string, n node) {
+		open, close := "[", "]"
+		if n.start {
+			open, close = "[[", "]]"
+		}
+		switch n.kind {
+		case nodeNPC:
+			open, close = "([", "])"
+		case nodeChoice:
+			open, close = "{", "}"
+		case nodePause:
+			open, close = "{{", "}}"
+		case nodeEnd:
+			open, close = "(((", ")))"
+		}
+		sb.WriteString(fmt.Sprintf("%s%s%s%s%s\n", indent, mermaidID(n.id), open, mermaidQuote(wrapLabel(n.label)), close))
+	}
+
+	for _, n := range g.nodes {
+		if n.group == "" {
+			writeNode("\t", n)
+		}
+	}
+	for _, grp := range g.groups() {
+		sb.WriteString(fmt.Sprintf("\tsubgraph %s[%s]\n", mermaidID("group_"+grp), mermaidQuote(grp)))
+		for _, n := range g.nodes {
+			if n.group == grp {
+				writeNode("\t\t", n)
+			}
+		}
+		sb.WriteString("\tend\n")
+	}
+
+	for _, e := range g.edges {
+		arrow := "-->"
+		switch e.kind {
+		case edgeStart, edgePatrol, edgeAllowed, edgeResume:
+			arrow = "-.->"
+		case edgeForbidden:
+			arrow = "-.-x"
+		}
+
+		if e.label == "" {
+			sb.WriteString(fmt.Sprintf("\t%s %s %s\n", mermaidID(e.from), arrow, mermaidID(e.to)))
+		} else {
+			sb.WriteString(fmt.Sprintf("\t%s %s|%s| %s\n", mermaidID(e.from), arrow, mermaidQuote(wrapLabel(e.label)), mermaidID(e.to)))
+		}
+	}
+
+	return sb.String()
+}
+
+type jsonNode struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Kind  string `json:"kind"`
+	Start bool   `json:"start,omitempty"`
+	Group string `json:"group,omitempty"`
+}
+
+type jsonEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Label string `json:"label,omitempty"`
+	Kind  string `json:"kind"`
+}
+
+type jsonGraph struct {
+	Nodes []jsonNode `json:"nodes"`
+	Edges []jsonEdge `json:"edges"`
+}
+
+func (g graph) renderJSON() string {
+	jg := jsonGraph{
+		Nodes: make([]jsonNode, len(g.nodes)),
+		Edges: make([]jsonEdge, len(g.edges)),
+	}
+
+	for i, n := range g.nodes {
+		jg.Nodes[i] = jsonNode{ID: n.id, Label: n.label, Kind: n.kind.String(), Start: n.start, Group: n.group}
+	}
+	for i, e := range g.edges {
+		jg.Edges[i] = jsonEdge{From: e.from, To: e.to, Label: e.label, Kind: e.kind.String()}
+	}
+
+	data, err := json.MarshalIndent(jg, "", "\t")
+	if err != nil {
+		// should never happen; everything in a jsonGraph can be marshaled
+		panic(fmt.Sprintf("marshal graph: %v", err))
+	}
+
+	return string(data) + "\n"
+}
+
+// wrapLabel wraps each line of s so that long text does not make for an
+// overly wide node or edge.
+func wrapLabel(s string) string {
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		if len(lines[i]) > labelWidth {
+			lines[i] = rosed.Edit(lines[i]).Wrap(labelWidth).String()
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// dotQuote gives s as a double-quoted DOT string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
+
+var mermaidIDInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// mermaidID gives a version of id that can be used as the ID of a node in a
+// Mermaid flowchart.
+func mermaidID(id string) string {
+	return mermaidIDInvalidChars.ReplaceAllString(id, "_")
+}
+
+// mermaidQuote gives s as a double-quoted Mermaid string.
+func mermaidQuote(s string) string {
+	s = strings.ReplaceAll(s, `"`, "#quot;")
+	s = strings.ReplaceAll(s, "\n", "<br/>")
+	return `"` + s + `"`
+}
@@ -0,0 +1,100 @@
+// Package worldmap draws graphs of the structure of TunaQuest worlds, both maps
+// of rooms connected by their exits and the dialog trees of NPCs. Graphs can be
+// produced in the DOT language used by Graphviz, as Mermaid flowcharts, or as
+// JSON for use by other tools.
+package worldmap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dekarrin/tunaq/internal/game"
+	"github.com/dekarrin/tunaq/internal/tqw"
+	"github.com/dekarrin/tunaq/internal/util"
+)
+
+// Options is the options for drawing a map.
+type Options struct {
+	// Routes is whether to include the movement of NPCs in the map. Each NPC
+	// is drawn connected to the room it starts in. For NPCs that patrol, the
+	// steps of their patrol are drawn between rooms; for NPCs that wander, the
+	// rooms they are allowed in and forbidden from are drawn connected to the
+	// NPC.
+	Routes bool
+}
+
+// Draw returns a map of the rooms of the given world in the given format.
+// Every room is a node of the map, and every exit is an edge from the room it
+// is in to the room it goes to, labeled with the aliases that the player can
+// use to go through it along with the 'if' that must be true for it to be
+// visible, if it has one.
+func Draw(world tqw.WorldData, f Format, opts Options) string {
+	return mapGraph(world, opts).render(f)
+}
+
+// mapGraph gives the graph of the map of the world.
+func mapGraph(world tqw.WorldData, opts Options) graph {
+	var g graph
+	var npcs []*game.NPC
+
+	for _, roomLabel := range util.OrderedKeys(world.Rooms) {
+		r := world.Rooms[roomLabel]
+
+		label := r.Label
+		if r.Name != "" {
+			label += "\n" + r.Name
+		}
+		g.nodes = append(g.nodes, node{id: r.Label, label: label, kind: nodeRoom, start: r.Label == world.Start})
+
+		for _, eg := range r.Exits {
+			var aliases []string
+			for _, a := range eg.Aliases {
+				aliases = append(aliases, strings.ToUpper(a))
+			}
+			label := strings.Join(aliases, ", ")
+			if ifRaw := strings.TrimSpace(eg.IfRaw); ifRaw != "" {
+				label += "\nif " + ifRaw
+			}
+			g.edges = append(g.edges, edge{from: r.Label, to: eg.DestLabel, label: label, kind: edgeExit})
+		}
+
+		for _, npcLabel := range util.OrderedKeys(r.NPCs) {
+			npcs = append(npcs, r.NPCs[npcLabel])
+		}
+	}
+
+	if !opts.Routes {
+		return g
+	}
+
+	for _, npc := range npcs {
+		npcID := "NPC__" + npc.Label
+		g.nodes = append(g.nodes, node{id: npcID, label: npc.Label, kind: nodeNPC})
+		g.edges = append(g.edges, edge{from: npcID, to: npc.Start, label: "starts", kind: edgeStart})
+
+		switch npc.Movement.Action {
+		case game.RoutePatrol:
+			path := npc.Movement.Path
+			for i := range path {
+				next := path[(i+1)%len(path)]
+				g.edges = append(g.edges, edge{from: path[i], to: next, label: fmt.Sprintf("%s patrol %d", npc.Label, i+1), kind: edgePatrol})
+			}
+		case game.RouteWander:
+			for _, allowed := range npc.Movement.AllowedRooms {
+				g.edges = append(g.edges, edge{from: npcID, to: allowed, label: "may wander", kind: edgeAllowed})
+			}
+			for _, forbidden := range npc.Movement.ForbiddenRooms {
+				g.edges = append(g.edges, edge{from: npcID, to: forbidden, label: "forbidden", kind: edgeForbidden})
+			}
+			if len(npc.Movement.AllowedRooms) == 0 && len(npc.Movement.ForbiddenRooms) == 0 {
+				g.nodes[len(g.nodes)-1].label += "\nwanders anywhere"
+			}
+		case game.RouteGoto:
+			g.edges = append(g.edges, edge{from: npcID, to: npc.Movement.Target, label: "goes to", kind: edgeAllowed})
+		case game.RouteFollow:
+			g.nodes[len(g.nodes)-1].label += "\nfollows the player"
+		}
+	}
+
+	return g
+}
@@ -0,0 +1,150 @@
+package worldmap
+
+import (
+	"testing"
+
+	"github.com/dekarrin/tunaq/internal/game"
+	"github.com/dekarrin/tunaq/internal/tqw"
+	"github.com/stretchr/testify/assert"
+)
+
+func testWorld() tqw.WorldData {
+	return tqw.WorldData{
+		Start: "KITCHEN",
+		Rooms: map[string]*game.Room{
+			"KITCHEN": {
+				Label: "KITCHEN",
+				Name:  "the kitchen",
+				Exits: []*game.Egress{
+					{DestLabel: "HALL", Aliases: []string{"hall", "door"}, IfRaw: `$DOOR_NAME == "big \"door\""`},
+				},
+				NPCs: map[string]*game.NPC{
+					"CHEF": {
+						Label:    "CHEF",
+						Start:    "KITCHEN",
+						Movement: game.Route{Action: game.RoutePatrol, Path: []string{"HALL", "KITCHEN"}},
+					},
+				},
+			},
+			"HALL": {
+				Label: "HALL",
+				Exits: []*game.Egress{
+					{DestLabel: "KITCHEN", Aliases: []string{"KITCHEN"}},
+				},
+			},
+		},
+	}
+}
+
+func Test_Draw(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format Format
+		opts   Options
+		expect string
+	}{
+		{
+			name:   "dot",
+			format: DOT,
+			expect: `digraph world {
+	node [shape=box];
+	"HALL" [label="HALL"];
+	"KITCHEN" [label="KITCHEN\nthe kitchen", peripheries=2];
+	"HALL" -> "KITCHEN" [label="KITCHEN"];
+	"KITCHEN" -> "HALL" [label="HALL, DOOR\nif $DOOR_NAME == \"big \\\"door\\\"\""];
+}
+`,
+		},
+		{
+			name:   "dot with routes",
+			format: DOT,
+			opts:   Options{Routes: true},
+			expect: `digraph world {
+	node [shape=box];
+	"HALL" [label="HALL"];
+	"KITCHEN" [label="KITCHEN\nthe kitchen", peripheries=2];
+	"NPC__CHEF" [label="CHEF", shape=ellipse, style=filled, fillcolor=lightyellow];
+	"HALL" -> "KITCHEN" [label="KITCHEN"];
+	"KITCHEN" -> "HALL" [label="HALL, DOOR\nif $DOOR_NAME == \"big \\\"door\\\"\""];
+	"NPC__CHEF" -> "KITCHEN" [label="starts", style=dotted];
+	"HALL" -> "KITCHEN" [label="CHEF patrol 1", style=dashed, color=blue, fontcolor=blue];
+	"KITCHEN" -> "HALL" [label="CHEF patrol 2", style=dashed, color=blue, fontcolor=blue];
+}
+`,
+		},
+		{
+			name:   "mermaid",
+			format: Mermaid,
+			expect: `flowchart LR
+	HALL["HALL"]
+	KITCHEN[["KITCHEN<br/>the kitchen"]]
+	HALL -->|"KITCHEN"| KITCHEN
+	KITCHEN -->|"HALL, DOOR<br/>if $DOOR_NAME == #quot;big \#quot;door\#quot;#quot;"| HALL
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			actual := Draw(testWorld(), tc.format, tc.opts)
+
+			assert.Equal(tc.expect, actual)
+		})
+	}
+}
+
+func Test_DrawDialog(t *testing.T) {
+	assert := assert.New(t)
+
+	world := tqw.WorldData{
+		Flags: map[string]string{"PLAYER_NAME": "Rose"},
+		Rooms: map[string]*game.Room{
+			"KITCHEN": {
+				Label: "KITCHEN",
+				NPCs: map[string]*game.NPC{
+					"CHEF": {
+						Label: "CHEF",
+						Dialog: []*game.DialogStep{
+							{Action: game.DialogLine, Content: "Hello.", Response: "Hi."},
+							{Action: game.DialogChoice, Label: "ASK", Content: "Hungry?", Choices: [][2]string{
+								{"$PLAYER_NAME is starving", "FEED"},
+								{"No", "ASK"},
+							}},
+							{Action: game.DialogEnd},
+							{Action: game.DialogLine, Label: "FEED", Content: "Eat up."},
+							{Action: game.DialogPause, ResumeAt: "ASK"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	expect := `flowchart LR
+	subgraph group_CHEF["CHEF"]
+		CHEF(["CHEF"])
+		CHEF__0["0<br/>Hello."]
+		CHEF__1{"1 ASK<br/>Hungry?"}
+		CHEF__2((("2<br/>END")))
+		CHEF__3["3 FEED<br/>Eat up."]
+		CHEF__4{{"4<br/>PAUSE"}}
+	end
+	CHEF -.-> CHEF__0
+	CHEF__0 -->|"Hi."| CHEF__1
+	CHEF__1 -->|"1) Rose is starving"| CHEF__3
+	CHEF__1 -->|"2) No"| CHEF__1
+	CHEF__3 --> CHEF__4
+	CHEF__4 -.->|"resumes"| CHEF__1
+`
+
+	actual, err := DrawDialog(world, "chef", Mermaid)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(expect, actual)
+
+	_, err = DrawDialog(world, "WAITER", Mermaid)
+	assert.Error(err)
+}
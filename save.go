@@ -0,0 +1,307 @@
+package tunaq
+
+// File save.go contains the functions for managing the saved games that an
+// Engine writes to and reads from disk.
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dekarrin/rezi"
+	"github.com/dekarrin/rosed"
+	"github.com/dekarrin/tunaq/internal/command"
+	"github.com/dekarrin/tunaq/internal/game"
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+)
+
+const (
+	// saveFileExt is the extension given to every save file.
+	saveFileExt = ".tqsav"
+
+	// saveFileMagic is written at the start of every save file to identify it
+	// as one.
+	saveFileMagic = "TQSAV"
+
+	// saveFileVersion is the version of the save file container format. This
+	// is separate from the version of the game state within it.
+	saveFileVersion = 1
+
+	// defaultSaveName is the name of the save used when SAVE or LOAD is given
+	// without one.
+	defaultSaveName = "QUICKSAVE"
+)
+
+// saveFile is a single saved game as it is written to disk.
+type saveFile struct {
+	// fingerprint is the fingerprint of the world the game was saved in.
+	fingerprint string
+
+	// savedAt is the time that the game was saved.
+	savedAt time.Time
+
+	// roomName is the name of the room the player was in when the game was
+	// saved. It is only used for display.
+	roomName string
+
+	// state is the saved game. When decoded from a file it is not attached to
+	// any world and must be applied to a running game with State.Resume.
+	state *game.State
+}
+
+func (sf saveFile) MarshalBinary() ([]byte, error) {
+	var data []byte
+
+	data = append(data, rezi.EncString(saveFileMagic)...)
+	data = append(data, rezi.EncInt(saveFileVersion)...)
+	data = append(data, rezi.EncString(sf.fingerprint)...)
+	data = append(data, rezi.EncInt(int(sf.savedAt.Unix()))...)
+	data = append(data, rezi.EncString(sf.roomName)...)
+	data = append(data, rezi.EncBinary(sf.state)...)
+
+	return data, nil
+}
+
+func (sf *saveFile) UnmarshalBinary(data []byte) error {
+	var decoded saveFile
+	var n int
+	var err error
+
+	magic, n, err := rezi.DecString(data)
+	if err != nil || magic != saveFileMagic {
+		return fmt.Errorf("not a TunaQuest save file")
+	}
+	data = data[n:]
+
+	version, n, err := rezi.DecInt(data)
+	if err != nil {
+		return fmt.Errorf("version: %w", err)
+	}
+	if version < 1 || version > saveFileVersion {
+		return fmt.Errorf("unsupported save file version %d", version)
+	}
+	data = data[n:]
+
+	decoded.fingerprint, n, err = rezi.DecString(data)
+	if err != nil {
+		return fmt.Errorf("fingerprint: %w", err)
+	}
+	data = data[n:]
+
+	savedAt, n, err := rezi.DecInt(data)
+	if err != nil {
+		return fmt.Errorf("save time: %w", err)
+	}
+	decoded.savedAt = time.Unix(int64(savedAt), 0)
+	data = data[n:]
+
+	decoded.roomName, n, err = rezi.DecString(data)
+	if err != nil {
+		return fmt.Errorf("room name: %w", err)
+	}
+	data = data[n:]
+
+	decoded.state = &game.State{}
+	n, err = rezi.DecBinary(data, decoded.state)
+	if err != nil {
+		return fmt.Errorf("state: %w", err)
+	}
+	data = data[n:]
+
+	if len(data) > 0 {
+		return fmt.Errorf("%d extra bytes at end of save file", len(data))
+	}
+
+	*sf = decoded
+	return nil
+}
+
+// executeSaveCommand executes one of the commands for managing saved games.
+// These are handled by the Engine and not the game.State because they deal
+// with the world file and the filesystem.
+func (eng *Engine) executeSaveCommand(cmd command.Command) error {
+	switch cmd.Verb {
+	case "SAVE":
+		return eng.saveGame(cmd.Recipient)
+	case "LOAD":
+		return eng.loadGame(cmd.Recipient)
+	case "SAVES":
+		return eng.listSaves()
+	case "RESTART":
+		return eng.restartGame()
+	default:
+		return tqerrors.Interpreterf("I don't know how to %q", cmd.Verb)
+	}
+}
+
+// saveGame writes the current game to the save with the given name. If name
+// is empty, the default save name is used.
+func (eng *Engine) saveGame(name string) error {
+	path, err := eng.savePath(name)
+	if err != nil {
+		return err
+	}
+
+	sf := saveFile{
+		fingerprint: eng.fingerprint,
+		savedAt:     time.Now(),
+		roomName:    eng.state.CurrentRoom.Name,
+		state:       eng.state,
+	}
+
+	if err := os.MkdirAll(eng.saveDir, 0755); err != nil {
+		return tqerrors.WrapInterpreterf(err, "I couldn't create the save directory: %s", err.Error())
+	}
+	if err := os.WriteFile(path, rezi.EncBinary(sf), 0644); err != nil {
+		return tqerrors.WrapInterpreterf(err, "I couldn't write the save file: %s", err.Error())
+	}
+
+	return eng.term.Output("\nGame saved to %s.\n\n", saveNameFromPath(path))
+}
+
+// loadGame replaces the current game with the one in the save with the given
+// name. If name is empty, the default save name is used. Saves made in a
+// different world than the one that is loaded are refused.
+func (eng *Engine) loadGame(name string) error {
+	path, err := eng.savePath(name)
+	if err != nil {
+		return err
+	}
+
+	sf, err := readSaveFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return tqerrors.Interpreterf("There's no save called %s", saveNameFromPath(path))
+		}
+		return tqerrors.WrapInterpreterf(err, "I couldn't read the save file: %s", err.Error())
+	}
+
+	if sf.fingerprint != eng.fingerprint {
+		msg := "The save %s was made in a different world (or a different version of this one), so it can't be loaded"
+		return tqerrors.Interpreterf(msg, saveNameFromPath(path))
+	}
+
+	if err := eng.state.Resume(sf.state); err != nil {
+		return tqerrors.WrapInterpreterf(err, "I couldn't load the save %s: %s", saveNameFromPath(path), err.Error())
+	}
+	eng.history.clear()
+
+	return eng.term.Output("\nGame loaded from %s.\n\nYou are in %s\n\n", saveNameFromPath(path), eng.state.CurrentRoom.Name)
+}
+
+// listSaves shows every save in the save directory, newest first.
+func (eng *Engine) listSaves() error {
+	entries, err := os.ReadDir(eng.saveDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return tqerrors.WrapInterpreterf(err, "I couldn't read the save directory: %s", err.Error())
+	}
+
+	type listedSave struct {
+		name string
+		file saveFile
+	}
+
+	var saves []listedSave
+	for _, ent := range entries {
+		if ent.IsDir() || filepath.Ext(ent.Name()) != saveFileExt {
+			continue
+		}
+
+		path := filepath.Join(eng.saveDir, ent.Name())
+		sf, err := readSaveFile(path)
+		if err != nil {
+			// not readable as a save; don't show it as one
+			continue
+		}
+		saves = append(saves, listedSave{name: saveNameFromPath(path), file: sf})
+	}
+
+	if len(saves) < 1 {
+		return eng.term.Output("\nThere are no saved games.\n\n")
+	}
+
+	sort.Slice(saves, func(i, j int) bool {
+		return saves[i].file.savedAt.After(saves[j].file.savedAt)
+	})
+
+	data := [][]string{{"Save", "Saved At", "Room"}}
+	for _, s := range saves {
+		roomName := s.file.roomName
+		if s.file.fingerprint != eng.fingerprint {
+			roomName += " (different world)"
+		}
+		data = append(data, []string{s.name, s.file.savedAt.Format("2006-01-02 15:04:05"), roomName})
+	}
+
+	tableOpts := rosed.Options{
+		TableHeaders:             true,
+		NoTrailingLineSeparators: true,
+	}
+
+	output := rosed.Edit("").
+		InsertTableOpts(0, data, eng.term.Width(), tableOpts).
+		String()
+
+	return eng.term.Output("\n" + output + "\n\n")
+}
+
+// restartGame confirms with the player and then starts the game over from the
+// beginning of the world.
+func (eng *Engine) restartGame() error {
+	answer, err := eng.term.Input("Are you sure you want to start over? Unsaved progress will be lost. (Y/N) ")
+	if err != nil {
+		return fmt.Errorf("get confirmation: %w", err)
+	}
+	answer = strings.ToUpper(strings.TrimSpace(answer))
+	if answer != "Y" && answer != "YES" {
+		return eng.term.Output("\nOkay, carrying on.\n\n")
+	}
+
+	if err := eng.startNewGame(); err != nil {
+		return tqerrors.WrapInterpreterf(err, "I couldn't reload the world: %s", err.Error())
+	}
+
+	return eng.term.Output("\nStarting over...\n\nYou are in %s\n\n", eng.state.CurrentRoom.Name)
+}
+
+// savePath gives the path to the file for the save with the given name. If
+// name is empty, the default save name is used.
+func (eng *Engine) savePath(name string) (string, error) {
+	if name == "" {
+		name = defaultSaveName
+	}
+
+	for _, ch := range name {
+		if !('A' <= ch && ch <= 'Z') && !('a' <= ch && ch <= 'z') && !('0' <= ch && ch <= '9') && ch != '_' && ch != '-' {
+			return "", tqerrors.Interpreterf("Save names can only have letters, numbers, dashes, and underscores in them")
+		}
+	}
+
+	return filepath.Join(eng.saveDir, strings.ToLower(name)+saveFileExt), nil
+}
+
+// saveNameFromPath gives the name of the save stored at the given path, in the
+// form the player would type it.
+func saveNameFromPath(path string) string {
+	return strings.ToUpper(strings.TrimSuffix(filepath.Base(path), saveFileExt))
+}
+
+// readSaveFile reads and decodes the save file at the given path.
+func readSaveFile(path string) (saveFile, error) {
+	var sf saveFile
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return sf, err
+	}
+
+	if _, err := rezi.DecBinary(data, &sf); err != nil {
+		return sf, err
+	}
+
+	return sf, nil
+}
@@ -0,0 +1,309 @@
+package tunaq
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dekarrin/rezi"
+	"github.com/dekarrin/tunaq/internal/command"
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+// testWorldData is a small world with two rooms and an item, used for
+// creating Engines in tests.
+const testWorldData = `format = "tuna"
+type = "data"
+
+[world]
+start = "KITCHEN"
+
+[[room]]
+label = "KITCHEN"
+name = "the kitchen"
+description = "A kitchen."
+
+  [[room.exit]]
+  aliases = ["HALL"]
+  dest = "HALL"
+  description = "A door."
+  message = "You walk to the hall."
+
+[[room]]
+label = "HALL"
+name = "the hall"
+description = "A hall."
+
+  [[room.exit]]
+  aliases = ["KITCHEN"]
+  dest = "KITCHEN"
+  description = "A door."
+  message = "You walk to the kitchen."
+
+[[item]]
+label = "SPOON"
+name = "spoon"
+aliases = ["SPOON"]
+description = "A spoon."
+start = "KITCHEN"
+`
+
+// newTestEngine writes world to a world file in a new temporary directory and
+// returns an Engine that plays it and keeps its saves in that directory, along
+// with the buffer that its output is written to. input is given to the Engine
+// as what the player types.
+func newTestEngine(t *testing.T, world string, input string, undoDepth int) (*Engine, *bytes.Buffer) {
+	dir := t.TempDir()
+	worldFile := filepath.Join(dir, "world.tqw")
+	if err := os.WriteFile(worldFile, []byte(world), 0644); err != nil {
+		t.Fatalf("write world file: %v", err)
+	}
+
+	out := &bytes.Buffer{}
+	eng, err := New(strings.NewReader(input), out, Config{WorldFile: worldFile, ForceDirect: true, UndoDepth: undoDepth})
+	if err != nil {
+		t.Fatalf("create engine: %v", err)
+	}
+	t.Cleanup(func() { eng.Close() })
+
+	return eng, out
+}
+
+// mustRun runs each command through eng the way RunUntilQuit would and fails
+// the test if any of them can't be parsed.
+func mustRun(t *testing.T, eng *Engine, commands ...string) error {
+	var cmds []command.Command
+	for _, c := range commands {
+		parsed, err := eng.vocab.ParseAll(c)
+		if err != nil {
+			t.Fatalf("parse %q: %v", c, err)
+		}
+		cmds = append(cmds, parsed...)
+	}
+	return eng.executeCommands(cmds)
+}
+
+func Test_Engine_saveAndLoad(t *testing.T) {
+	testCases := []struct {
+		name         string
+		save         string
+		load         string
+		expectFile   string
+		expectErr    string
+		expectInRoom string
+	}{
+		{
+			name:         "default save",
+			save:         "save",
+			load:         "load",
+			expectFile:   "quicksave.tqsav",
+			expectInRoom: "KITCHEN",
+		},
+		{
+			name:         "named save",
+			save:         "save Slot_1",
+			load:         "load slot_1",
+			expectFile:   "slot_1.tqsav",
+			expectInRoom: "KITCHEN",
+		},
+		{
+			name:       "missing save",
+			save:       "save one",
+			load:       "load two",
+			expectFile: "one.tqsav",
+			expectErr:  "There's no save called TWO",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			eng, _ := newTestEngine(t, testWorldData, "", 0)
+
+			if !assert.NoError(mustRun(t, eng, "take spoon", tc.save, "drop spoon", "go hall")) {
+				return
+			}
+
+			// the file starts with the magic header, after the length of the
+			// encoded data
+			path := filepath.Join(eng.saveDir, tc.expectFile)
+			data, err := os.ReadFile(path)
+			if !assert.NoError(err) {
+				return
+			}
+			_, n, err := rezi.DecInt(data)
+			if !assert.NoError(err) {
+				return
+			}
+			magic, _, err := rezi.DecString(data[n:])
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(saveFileMagic, magic)
+
+			sf, err := readSaveFile(path)
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(eng.fingerprint, sf.fingerprint)
+			assert.Equal("the kitchen", sf.roomName)
+
+			err = mustRun(t, eng, tc.load)
+			if tc.expectErr != "" {
+				assert.Equal(tc.expectErr, tqerrors.GameMessage(err))
+				assert.Equal("HALL", eng.state.CurrentRoom.Label)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal(tc.expectInRoom, eng.state.CurrentRoom.Label)
+			assert.Contains(eng.state.Inventory, "SPOON")
+		})
+	}
+}
+
+func Test_Engine_loadGame_changedWorld(t *testing.T) {
+	testCases := []struct {
+		name      string
+		changed   string
+		expectErr string
+	}{
+		{
+			name:    "description change can still be loaded",
+			changed: strings.Replace(testWorldData, "A kitchen.", "A big kitchen.", 1),
+		},
+		{
+			name:      "added room is rejected",
+			changed:   testWorldData + "\n[[room]]\nlabel = \"YARD\"\nname = \"the yard\"\ndescription = \"A yard.\"\n",
+			expectErr: "The save QUICKSAVE was made in a different world (or a different version of this one), so it can't be loaded",
+		},
+		{
+			name:      "removed item is rejected",
+			changed:   testWorldData[:strings.Index(testWorldData, "[[item]]")],
+			expectErr: "The save QUICKSAVE was made in a different world (or a different version of this one), so it can't be loaded",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			eng, _ := newTestEngine(t, testWorldData, "", 0)
+			if !assert.NoError(mustRun(t, eng, "go hall", "save")) {
+				return
+			}
+
+			// load the changed world with the same save directory
+			changedEng, _ := newTestEngine(t, tc.changed, "", 0)
+			changedEng.saveDir = eng.saveDir
+
+			err := mustRun(t, changedEng, "load")
+			if tc.expectErr != "" {
+				assert.Equal(tc.expectErr, tqerrors.GameMessage(err))
+				assert.Equal("KITCHEN", changedEng.state.CurrentRoom.Label)
+				return
+			}
+			if !assert.NoError(err) {
+				return
+			}
+			assert.Equal("HALL", changedEng.state.CurrentRoom.Label)
+		})
+	}
+}
+
+func Test_Engine_listSaves(t *testing.T) {
+	testCases := []struct {
+		name         string
+		saves        []string
+		otherWorld   bool
+		expect       []string
+		expectAbsent []string
+	}{
+		{
+			name:   "no saves",
+			expect: []string{"There are no saved games."},
+		},
+		{
+			name:         "saves in this world",
+			saves:        []string{"save first", "go hall", "save second"},
+			expect:       []string{"FIRST", "SECOND", "the kitchen", "the hall"},
+			expectAbsent: []string{"different world"},
+		},
+		{
+			name:       "saves in another world",
+			saves:      []string{"save first"},
+			otherWorld: true,
+			expect:     []string{"FIRST", "the kitchen (different world)"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			eng, out := newTestEngine(t, testWorldData, "", 0)
+			if !assert.NoError(mustRun(t, eng, tc.saves...)) {
+				return
+			}
+
+			if tc.otherWorld {
+				other := testWorldData + "\n[[room]]\nlabel = \"YARD\"\nname = \"the yard\"\ndescription = \"A yard.\"\n"
+				otherEng, otherOut := newTestEngine(t, other, "", 0)
+				otherEng.saveDir = eng.saveDir
+				eng, out = otherEng, otherOut
+			}
+
+			out.Reset()
+			if !assert.NoError(mustRun(t, eng, "saves")) {
+				return
+			}
+			for _, s := range tc.expect {
+				assert.Contains(out.String(), s)
+			}
+			for _, s := range tc.expectAbsent {
+				assert.NotContains(out.String(), s)
+			}
+		})
+	}
+}
+
+func Test_Engine_restartGame(t *testing.T) {
+	testCases := []struct {
+		name         string
+		answer       string
+		expectRoom   string
+		expectOutput string
+	}{
+		{
+			name:         "confirmed",
+			answer:       "y\n",
+			expectRoom:   "KITCHEN",
+			expectOutput: "Starting over...",
+		},
+		{
+			name:         "refused",
+			answer:       "n\n",
+			expectRoom:   "HALL",
+			expectOutput: "Okay, carrying on.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			eng, out := newTestEngine(t, testWorldData, tc.answer, 0)
+			if !assert.NoError(mustRun(t, eng, "take spoon", "go hall", "restart")) {
+				return
+			}
+
+			assert.Contains(out.String(), tc.expectOutput)
+			assert.Equal(tc.expectRoom, eng.state.CurrentRoom.Label)
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package tunascript
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dekarrin/tunaq/tunascript/syntax"
+)
+
+// This file contains functions for statically examining tunascript without
+// executing it.
+
+// FlagUsage is the flags that a piece of tunascript refers to, as can be
+// determined without executing it.
+type FlagUsage struct {
+	// Read is the labels of all flags whose values are used, sorted.
+	Read []string
+
+	// Written is the labels of all flags whose values are changed, sorted.
+	Written []string
+
+	// QueriesWorld is whether the tunascript asks about the state of the world
+	// outside of flags, such as with $IN_INVEN(). If so, its result can
+	// change even if no flags do.
+	QueriesWorld bool
+}
+
+// builtInFlagReaders is the built-in functions whose first argument is the
+// label of a flag that they read.
+var builtInFlagReaders = map[string]bool{
+	"FLAG_ENABLED":      true,
+	"FLAG_DISABLED":     true,
+	"FLAG_IS":           true,
+	"FLAG_LESS_THAN":    true,
+	"FLAG_GREATER_THAN": true,
+	"TOGGLE":            true,
+	"INC":               true,
+	"DEC":               true,
+}
+
+// builtInFlagWriters is the built-in functions whose first argument is the
+// label of a flag that they change.
+var builtInFlagWriters = map[string]bool{
+	"ENABLE":  true,
+	"DISABLE": true,
+	"TOGGLE":  true,
+	"INC":     true,
+	"DEC":     true,
+	"SET":     true,
+}
+
+// builtInWorldQueries is the built-in functions that ask about the state of
+// the world.
+var builtInWorldQueries = map[string]bool{
+	"IN_INVEN":  true,
+	"VISITED":   true,
+	"IS_LOCKED": true,
+	"IS_OPEN":   true,
+	"NPC_HAS":   true,
+	"TURN":      true,
+}
+
+// Flags gives the flags that the given tunascript reads and writes. Flags
+// passed to built-in functions are only included if they are given as literal
+// text; those given with an expression cannot be known without executing it.
+func Flags(ast AST) FlagUsage {
+	read := map[string]bool{}
+	written := map[string]bool{}
+	var queries bool
+
+	for _, n := range ast.Nodes {
+		if findFlagUsage(n, read, written) {
+			queries = true
+		}
+	}
+
+	return newFlagUsage(read, written, queries)
+}
+
+// TemplateFlags gives the flags that the given template reads, both to be
+// included in text and within flow-control conditions. As templates cannot
+// have side effects, Written will always be empty.
+func TemplateFlags(tmpl Template) FlagUsage {
+	read := map[string]bool{}
+	written := map[string]bool{}
+	var queries bool
+
+	for _, b := range tmpl.Blocks {
+		if findTemplateFlagUsage(b, read, written) {
+			queries = true
+		}
+	}
+
+	return newFlagUsage(read, written, queries)
+}
+
+func newFlagUsage(read, written map[string]bool, queries bool) FlagUsage {
+	fu := FlagUsage{QueriesWorld: queries}
+
+	for k := range read {
+		fu.Read = append(fu.Read, k)
+	}
+	for k := range written {
+		fu.Written = append(fu.Written, k)
+	}
+	sort.Strings(fu.Read)
+	sort.Strings(fu.Written)
+
+	return fu
+}
+
+// findTemplateFlagUsage adds all flags used in b to read and written, and
+// returns whether b queries the world.
+func findTemplateFlagUsage(b syntax.Block, read, written map[string]bool) bool {
+	var queries bool
+
+	switch b.Type() {
+	case syntax.TmplText:
+		// nothing to find
+	case syntax.TmplFlag:
+		read[strings.ToUpper(b.AsFlag().Flag)] = true
+	case syntax.TmplBranch:
+		br := b.AsBranch()
+		conds := append([]syntax.CondBlock{br.If}, br.ElseIf...)
+		for _, c := range conds {
+			for _, n := range c.Cond.Nodes {
+				if findFlagUsage(n, read, written) {
+					queries = true
+				}
+			}
+			for _, sub := range c.Content {
+				if findTemplateFlagUsage(sub, read, written) {
+					queries = true
+				}
+			}
+		}
+		for _, sub := range br.Else {
+			if findTemplateFlagUsage(sub, read, written) {
+				queries = true
+			}
+		}
+	case syntax.TmplCond:
+		c := b.AsCond()
+		for _, n := range c.Cond.Nodes {
+			if findFlagUsage(n, read, written) {
+				queries = true
+			}
+		}
+		for _, sub := range c.Content {
+			if findTemplateFlagUsage(sub, read, written) {
+				queries = true
+			}
+		}
+	default:
+		panic(fmt.Sprintf("unknown template block type: %v", b.Type()))
+	}
+
+	return queries
+}
+
+// findFlagUsage adds all flags used in n to read and written, and returns
+// whether n queries the world.
+func findFlagUsage(n syntax.ASTNode, read, written map[string]bool) bool {
+	var queries bool
+
+	switch n.Type() {
+	case syntax.ASTLiteral:
+		// nothing to find
+	case syntax.ASTFlag:
+		read[strings.ToUpper(n.AsFlagNode().Flag)] = true
+	case syntax.ASTGroup:
+		queries = findFlagUsage(n.AsGroupNode().Expr, read, written)
+	case syntax.ASTBinaryOp:
+		bn := n.AsBinaryOpNode()
+		leftQueries := findFlagUsage(bn.Left, read, written)
+		rightQueries := findFlagUsage(bn.Right, read, written)
+		queries = leftQueries || rightQueries
+	case syntax.ASTUnaryOp:
+		queries = findFlagUsage(n.AsUnaryOpNode().Operand, read, written)
+	case syntax.ASTAssignment:
+		an := n.AsAssignmentNode()
+		written[strings.ToUpper(an.Flag)] = true
+		if an.Value != nil {
+			queries = findFlagUsage(an.Value, read, written)
+		}
+	case syntax.ASTFunc:
+		fn := n.AsFuncNode()
+		funcName := strings.ToUpper(fn.Func)
+
+		if builtInWorldQueries[funcName] {
+			queries = true
+		}
+
+		if len(fn.Args) > 0 && fn.Args[0].Type() == syntax.ASTLiteral {
+			label := strings.ToUpper(fn.Args[0].AsLiteralNode().Value.String())
+			if builtInFlagReaders[funcName] {
+				read[label] = true
+			}
+			if builtInFlagWriters[funcName] {
+				written[label] = true
+			}
+		}
+
+		for _, arg := range fn.Args {
+			if findFlagUsage(arg, read, written) {
+				queries = true
+			}
+		}
+	default:
+		panic(fmt.Sprintf("unknown AST node type: %v", n.Type()))
+	}
+
+	return queries
+}
@@ -13,6 +13,15 @@ type funcInfo struct {
 	call funcImpl
 }
 
+func nullaryImpl(fname string, impl func() Value) funcInfo {
+	return funcInfo{
+		def: syntax.BuiltInFunctions[fname],
+		call: func(args []Value) Value {
+			return impl()
+		},
+	}
+}
+
 func unaryImpl(fname string, impl func(v Value) Value) funcInfo {
 	return funcInfo{
 		def: syntax.BuiltInFunctions[fname],
@@ -52,8 +61,14 @@ func (interp *Interpreter) initFuncs() {
 	interp.fn["DISABLE"] = unaryImpl("DISABLE", interp.disable)
 	interp.fn["TOGGLE"] = unaryImpl("TOGGLE", interp.toggle)
 	interp.fn["IN_INVEN"] = unaryImpl("IN_INVEN", interp.inInven)
+	interp.fn["VISITED"] = unaryImpl("VISITED", interp.visited)
+	interp.fn["IS_LOCKED"] = unaryImpl("IS_LOCKED", interp.isLocked)
+	interp.fn["IS_OPEN"] = unaryImpl("IS_OPEN", interp.isOpen)
+	interp.fn["NPC_HAS"] = binaryImpl("NPC_HAS", interp.npcHas)
+	interp.fn["TURN"] = nullaryImpl("TURN", interp.turn)
 	interp.fn["SET"] = binaryImpl("SET", interp.set)
 	interp.fn["MOVE"] = binaryImpl("MOVE", interp.move)
+	interp.fn["SEND"] = binaryImpl("SEND", interp.send)
 	interp.fn["OUTPUT"] = unaryImpl("OUTPUT", interp.output)
 
 	// and the two variable-arity functions
@@ -103,6 +118,35 @@ func (interp *Interpreter) inInven(v Value) Value {
 	return syntax.ValueOf(interp.Target.InInventory(itemLabelName))
 }
 
+func (interp *Interpreter) visited(v Value) Value {
+	roomLabelName := strings.ToUpper(v.String())
+
+	return syntax.ValueOf(interp.Target.Visited(roomLabelName))
+}
+
+func (interp *Interpreter) isLocked(v Value) Value {
+	labelName := strings.ToUpper(v.String())
+
+	return syntax.ValueOf(interp.Target.IsLocked(labelName))
+}
+
+func (interp *Interpreter) isOpen(v Value) Value {
+	labelName := strings.ToUpper(v.String())
+
+	return syntax.ValueOf(interp.Target.IsOpen(labelName))
+}
+
+func (interp *Interpreter) npcHas(npc, item Value) Value {
+	npcLabelName := strings.ToUpper(npc.String())
+	itemLabelName := strings.ToUpper(item.String())
+
+	return syntax.ValueOf(interp.Target.NPCHas(npcLabelName, itemLabelName))
+}
+
+func (interp *Interpreter) turn() Value {
+	return syntax.ValueOf(interp.Target.Turn())
+}
+
 func (interp *Interpreter) move(target, dest Value) Value {
 	targetStr := strings.ToUpper(target.String())
 	destStr := strings.ToUpper(dest.String())
@@ -110,6 +154,13 @@ func (interp *Interpreter) move(target, dest Value) Value {
 	return syntax.ValueOf(interp.Target.Move(targetStr, destStr))
 }
 
+func (interp *Interpreter) send(npc, dest Value) Value {
+	npcStr := strings.ToUpper(npc.String())
+	destStr := strings.ToUpper(dest.String())
+
+	return syntax.ValueOf(interp.Target.Send(npcStr, destStr))
+}
+
 func (interp *Interpreter) output(msg Value) Value {
 	return syntax.ValueOf(interp.Target.Output(msg.String()))
 }
@@ -47,7 +47,13 @@ var (
 		"DEC":               {Name: "DEC", RequiredArgs: 1, OptionalArgs: 1, SideEffects: true},
 		"SET":               {Name: "SET", RequiredArgs: 2, SideEffects: true},
 		"IN_INVEN":          {Name: "IN_INVEN", RequiredArgs: 1},
+		"VISITED":           {Name: "VISITED", RequiredArgs: 1},
+		"IS_LOCKED":         {Name: "IS_LOCKED", RequiredArgs: 1},
+		"IS_OPEN":           {Name: "IS_OPEN", RequiredArgs: 1},
+		"NPC_HAS":           {Name: "NPC_HAS", RequiredArgs: 2},
+		"TURN":              {Name: "TURN"},
 		"MOVE":              {Name: "MOVE", RequiredArgs: 2, SideEffects: true},
+		"SEND":              {Name: "SEND", RequiredArgs: 2, SideEffects: true},
 		"OUTPUT":            {Name: "OUTPUT", RequiredArgs: 1, SideEffects: true},
 	}
 )
@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 	"unicode"
+
+	"github.com/dekarrin/rezi"
 )
 
 type ValueType int
@@ -428,3 +430,75 @@ func (v Value) Bool() bool {
 		panic("unrecognized TSValue type")
 	}
 }
+
+// MarshalBinary converts v into a slice of bytes that can be decoded with
+// UnmarshalBinary. The type of v is preserved in the encoding.
+func (v Value) MarshalBinary() ([]byte, error) {
+	data := rezi.EncInt(int(v.vType))
+
+	switch v.vType {
+	case Float:
+		data = append(data, rezi.EncString(strconv.FormatFloat(v.f, 'g', -1, 64))...)
+	case Int:
+		data = append(data, rezi.EncInt(v.i)...)
+	case String:
+		data = append(data, rezi.EncString(v.s)...)
+	case Bool:
+		data = append(data, rezi.EncBool(v.b)...)
+	default:
+		return nil, fmt.Errorf("unrecognized TSValue type")
+	}
+
+	return data, nil
+}
+
+// UnmarshalBinary decodes a slice of bytes produced by MarshalBinary into v.
+// All data in the slice must be consumed by the decode or it will be considered
+// an error.
+func (v *Value) UnmarshalBinary(data []byte) error {
+	var decoded Value
+
+	vType, n, err := rezi.DecInt(data)
+	if err != nil {
+		return fmt.Errorf("type: %w", err)
+	}
+	data = data[n:]
+	decoded.vType = ValueType(vType)
+
+	switch decoded.vType {
+	case Float:
+		var fStr string
+		fStr, n, err = rezi.DecString(data)
+		if err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+		decoded.f, err = strconv.ParseFloat(fStr, 64)
+		if err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+	case Int:
+		decoded.i, n, err = rezi.DecInt(data)
+		if err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+	case String:
+		decoded.s, n, err = rezi.DecString(data)
+		if err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+	case Bool:
+		decoded.b, n, err = rezi.DecBool(data)
+		if err != nil {
+			return fmt.Errorf("value: %w", err)
+		}
+	default:
+		return fmt.Errorf("type: unrecognized TSValue type %d", vType)
+	}
+
+	if n != len(data) {
+		return fmt.Errorf("value: %d extra bytes after value", len(data)-n)
+	}
+
+	*v = decoded
+	return nil
+}
@@ -172,11 +172,37 @@ type WorldInterface interface {
 	// inventory.
 	InInventory(label string) bool
 
+	// Visited returns whether the player has ever been in the room with the
+	// given label.
+	Visited(label string) bool
+
+	// IsLocked returns whether the lock with the given label, or the lock of
+	// the exit, detail, or item with the given label, is locked.
+	IsLocked(label string) bool
+
+	// IsOpen returns whether the lock with the given label, or the lock of the
+	// exit, detail, or item with the given label, is open.
+	IsOpen(label string) bool
+
+	// NPCHas returns whether the NPC with the given label is holding the item
+	// with the given label. The item may instead be given as a tag, in which
+	// case it returns whether the NPC is holding any item with that tag.
+	NPCHas(npc string, item string) bool
+
+	// Turn returns the number of turns that have passed in the game.
+	Turn() int
+
 	// Move moves the label to the dest. The label can be an NPC or an Item. If
-	// label is "@PLAYER", the player will be moved. Returns whether the thing
-	// moved.
+	// label is "@PLAYER", the player will be moved. An Item can also be moved
+	// to "@INVEN", into something with a container, or into the inventory of
+	// an NPC. Returns whether the thing moved.
 	Move(label string, dest string) bool
 
+	// Send has the NPC with the given label start walking toward the room with
+	// the label dest, one room at a time as NPCs move, and then stay there.
+	// Returns whether the NPC was sent.
+	Send(npc string, dest string) bool
+
 	// Output prints the given string. Returns whether it did successfully.
 	Output(s string) bool
 }
@@ -498,6 +524,26 @@ func (interp *Interpreter) GetFlag(label string) string {
 	return flag.String()
 }
 
+// FlagValue gets the given flag's value with its type preserved. If the flag
+// is not defined, the zero Value and false are returned.
+func (interp *Interpreter) FlagValue(label string) (Value, bool) {
+	label = strings.ToUpper(label)
+
+	flag, ok := interp.flags[label]
+	return flag, ok
+}
+
+// SetFlagValue sets the given flag to the given value exactly, without any
+// interpretation of its type. If the flag is not yet defined, it is added.
+func (interp *Interpreter) SetFlagValue(label string, v Value) {
+	if interp.flags == nil {
+		interp.flags = make(map[string]Value)
+	}
+
+	label = strings.ToUpper(label)
+	interp.flags[label] = v
+}
+
 // templateExecNode executes a single template node and converts it to the
 // completed text.
 func (interp *Interpreter) templateExecNode(n syntax.Block) string {
@@ -0,0 +1,150 @@
+package tunaq
+
+// File undo.go contains the functions for taking back turns that the player
+// has taken and for redoing turns that were taken back.
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/dekarrin/tunaq/internal/command"
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+)
+
+// turnHistory holds snapshots of the game taken before each turn so that
+// turns can be taken back. Snapshots are the output of State.MarshalBinary.
+type turnHistory struct {
+	// depth is the maximum number of turns that can be taken back. If it is
+	// less than 1, no history is kept.
+	depth int
+
+	// undo is the snapshots that UNDO goes back to, oldest first.
+	undo [][]byte
+
+	// redo is the snapshots that REDO goes forward to, most recently taken
+	// back last.
+	redo [][]byte
+}
+
+// record adds a snapshot of the game before a turn to the history. If the
+// history is full, the oldest snapshot is discarded. Recording a turn discards
+// any turns that could have been redone.
+func (th *turnHistory) record(before []byte) {
+	if th.depth < 1 {
+		return
+	}
+
+	th.undo = append(th.undo, before)
+	if len(th.undo) > th.depth {
+		th.undo = th.undo[len(th.undo)-th.depth:]
+	}
+	th.redo = nil
+}
+
+// back gives the snapshot to go back to from current and makes current
+// available to redo. If there is nothing to go back to, ok will be false.
+func (th *turnHistory) back(current []byte) (snapshot []byte, ok bool) {
+	if len(th.undo) < 1 {
+		return nil, false
+	}
+
+	snapshot = th.undo[len(th.undo)-1]
+	th.undo = th.undo[:len(th.undo)-1]
+	th.redo = append(th.redo, current)
+	return snapshot, true
+}
+
+// forward gives the snapshot to go forward to from current and makes current
+// available to undo. If there is nothing to go forward to, ok will be false.
+func (th *turnHistory) forward(current []byte) (snapshot []byte, ok bool) {
+	if len(th.redo) < 1 {
+		return nil, false
+	}
+
+	snapshot = th.redo[len(th.redo)-1]
+	th.redo = th.redo[:len(th.redo)-1]
+	th.undo = append(th.undo, current)
+	return snapshot, true
+}
+
+// clear removes all turns from the history.
+func (th *turnHistory) clear() {
+	th.undo = nil
+	th.redo = nil
+}
+
+// lookingVerbs are the verbs of commands that only look at the game. Time
+// still passes during them, but they aren't worth taking back, so they are
+// never recorded in the history.
+var lookingVerbs = map[string]bool{
+	"LOOK":      true,
+	"EXITS":     true,
+	"INVENTORY": true,
+}
+
+// advanceRecorded advances the game with the given command and records the
+// turn in the history if it did something to the game.
+func (eng *Engine) advanceRecorded(cmd command.Command) error {
+	if lookingVerbs[cmd.Verb] {
+		return eng.state.Advance(cmd)
+	}
+
+	before, err := eng.state.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("snapshot game state: %w", err)
+	}
+
+	advanceErr := eng.state.Advance(cmd)
+
+	after, err := eng.state.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("snapshot game state: %w", err)
+	}
+
+	// turns that fail or that are outside of game time, such as HELP, do not
+	// change anything, so there is nothing to take back.
+	if !bytes.Equal(before, after) {
+		eng.history.record(before)
+	}
+
+	return advanceErr
+}
+
+// executeHistoryCommand executes UNDO or REDO.
+func (eng *Engine) executeHistoryCommand(cmd command.Command) error {
+	if eng.history.depth < 1 {
+		return tqerrors.Interpreterf("Taking back turns is turned off")
+	}
+
+	current, err := eng.state.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("snapshot game state: %w", err)
+	}
+
+	var snapshot []byte
+	var ok bool
+	var msg string
+
+	switch cmd.Verb {
+	case "UNDO":
+		snapshot, ok = eng.history.back(current)
+		if !ok {
+			return tqerrors.Interpreterf("There's nothing to undo")
+		}
+		msg = "You take back your last turn."
+	case "REDO":
+		snapshot, ok = eng.history.forward(current)
+		if !ok {
+			return tqerrors.Interpreterf("There's nothing to redo")
+		}
+		msg = "You redo the turn you took back."
+	default:
+		return tqerrors.Interpreterf("I don't know how to %q", cmd.Verb)
+	}
+
+	if err := eng.state.UnmarshalBinary(snapshot); err != nil {
+		return fmt.Errorf("restore game state: %w", err)
+	}
+
+	return eng.term.Output("\n%s\n\nYou are in %s\n\n", msg, eng.state.CurrentRoom.Name)
+}
@@ -0,0 +1,188 @@
+package tunaq
+
+import (
+	"testing"
+
+	"github.com/dekarrin/tunaq/internal/tqerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_turnHistory(t *testing.T) {
+	type step struct {
+		op       string // "record", "back", or "forward"
+		snapshot string // recorded, or the current state for back and forward
+		expect   string // the snapshot back or forward gives
+		expectOK bool
+	}
+
+	testCases := []struct {
+		name       string
+		depth      int
+		steps      []step
+		expectUndo []string
+		expectRedo []string
+	}{
+		{
+			name:  "no history is kept with no depth",
+			depth: 0,
+			steps: []step{
+				{op: "record", snapshot: "A"},
+				{op: "back", snapshot: "B", expectOK: false},
+			},
+		},
+		{
+			name:  "back and forward",
+			depth: 5,
+			steps: []step{
+				{op: "record", snapshot: "A"},
+				{op: "record", snapshot: "B"},
+				{op: "back", snapshot: "C", expect: "B", expectOK: true},
+				{op: "back", snapshot: "B", expect: "A", expectOK: true},
+				{op: "back", snapshot: "A", expectOK: false},
+				{op: "forward", snapshot: "A", expect: "B", expectOK: true},
+			},
+			expectUndo: []string{"A"},
+			expectRedo: []string{"C"},
+		},
+		{
+			name:  "oldest snapshot is discarded past depth",
+			depth: 2,
+			steps: []step{
+				{op: "record", snapshot: "A"},
+				{op: "record", snapshot: "B"},
+				{op: "record", snapshot: "C"},
+				{op: "back", snapshot: "D", expect: "C", expectOK: true},
+				{op: "back", snapshot: "C", expect: "B", expectOK: true},
+				{op: "back", snapshot: "B", expectOK: false},
+			},
+			expectRedo: []string{"D", "C"},
+		},
+		{
+			name:  "new turn discards redo",
+			depth: 5,
+			steps: []step{
+				{op: "record", snapshot: "A"},
+				{op: "back", snapshot: "B", expect: "A", expectOK: true},
+				{op: "record", snapshot: "A"},
+				{op: "forward", snapshot: "C", expectOK: false},
+			},
+			expectUndo: []string{"A"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			th := turnHistory{depth: tc.depth}
+			for i, st := range tc.steps {
+				var actual []byte
+				var ok bool
+
+				switch st.op {
+				case "record":
+					th.record([]byte(st.snapshot))
+					continue
+				case "back":
+					actual, ok = th.back([]byte(st.snapshot))
+				case "forward":
+					actual, ok = th.forward([]byte(st.snapshot))
+				}
+
+				if !assert.Equal(st.expectOK, ok, "step %d", i) {
+					return
+				}
+				if ok {
+					assert.Equal(st.expect, string(actual), "step %d", i)
+				}
+			}
+
+			assert.Equal(tc.expectUndo, snapshotStrings(th.undo))
+			assert.Equal(tc.expectRedo, snapshotStrings(th.redo))
+		})
+	}
+}
+
+func Test_Engine_undoRedo(t *testing.T) {
+	testCases := []struct {
+		name        string
+		depth       int
+		commands    []string
+		expectErr   string
+		expectRoom  string
+		expectSpoon bool
+	}{
+		{
+			name:       "undo a turn",
+			commands:   []string{"go hall", "undo"},
+			expectRoom: "KITCHEN",
+		},
+		{
+			name:       "redo a turn taken back",
+			commands:   []string{"go hall", "undo", "redo"},
+			expectRoom: "HALL",
+		},
+		{
+			name:       "turns that do nothing are not recorded",
+			commands:   []string{"take spoon", "look", "inventory", "exits", "help", "debug flags", "undo"},
+			expectRoom: "KITCHEN",
+		},
+		{
+			name:        "new turn discards redo",
+			commands:    []string{"go hall", "undo", "take spoon", "redo"},
+			expectErr:   "There's nothing to redo",
+			expectRoom:  "KITCHEN",
+			expectSpoon: true,
+		},
+		{
+			name:       "only depth turns can be taken back",
+			depth:      1,
+			commands:   []string{"go hall", "go kitchen", "undo", "undo"},
+			expectErr:  "There's nothing to undo",
+			expectRoom: "HALL",
+		},
+		{
+			name:       "turned off",
+			depth:      -1,
+			commands:   []string{"go hall", "undo"},
+			expectErr:  "Taking back turns is turned off",
+			expectRoom: "HALL",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			eng, _ := newTestEngine(t, testWorldData, "", tc.depth)
+
+			last := len(tc.commands) - 1
+			if !assert.NoError(mustRun(t, eng, tc.commands[:last]...)) {
+				return
+			}
+			err := mustRun(t, eng, tc.commands[last])
+			if tc.expectErr != "" {
+				assert.Equal(tc.expectErr, tqerrors.GameMessage(err))
+			} else {
+				assert.NoError(err)
+			}
+
+			assert.Equal(tc.expectRoom, eng.state.CurrentRoom.Label)
+			_, hasSpoon := eng.state.Inventory["SPOON"]
+			assert.Equal(tc.expectSpoon, hasSpoon)
+		})
+	}
+}
+
+// snapshotStrings converts snapshots to strings so they can be compared
+// easily. If there are none, nil is returned.
+func snapshotStrings(snapshots [][]byte) []string {
+	if len(snapshots) < 1 {
+		return nil
+	}
+	strs := make([]string, len(snapshots))
+	for i := range snapshots {
+		strs[i] = string(snapshots[i])
+	}
+	return strs
+}